@@ -0,0 +1,212 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+type autosaveTick struct{}
+
+const defaultAutosaveInterval = 30 * time.Second
+
+// autosaveInterval returns how often unsaved changes are written to the
+// recovery file, or 0 if autosave is off.
+func (m *model) autosaveInterval() time.Duration {
+	switch {
+	case m.config.AutosaveInterval < 0:
+		return 0
+	case m.config.AutosaveInterval == 0:
+		return defaultAutosaveInterval
+	}
+	return time.Duration(m.config.AutosaveInterval) * time.Second
+}
+
+func (m *model) autosaveCmd() tea.Cmd {
+	interval := m.autosaveInterval()
+	if interval == 0 {
+		return nil
+	}
+	return tea.Tick(interval, func(time.Time) tea.Msg {
+		return autosaveTick{}
+	})
+}
+
+// recoveryDir returns the directory recovery files are kept in,
+// $XDG_STATE_HOME/pixl or ~/.local/state/pixl.
+func recoveryDir() string {
+	if dir := os.Getenv("XDG_STATE_HOME"); dir != "" {
+		return filepath.Join(dir, "pixl")
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".local", "state", "pixl")
+}
+
+// recoveryPrefix returns the start of the names of a document's recovery
+// files. Files are told apart by a hash of their path, and documents without
+// a file share "unnamed-".
+func recoveryPrefix(filePath string) string {
+	dir := recoveryDir()
+	if dir == "" {
+		return ""
+	}
+	name := "unnamed"
+	if filePath != "" {
+		sum := sha256.Sum256([]byte(filePath))
+		base := filepath.Base(filePath)
+		name = strings.TrimSuffix(base, filepath.Ext(base)) + "-" + hex.EncodeToString(sum[:4])
+	}
+	return filepath.Join(dir, name+"-")
+}
+
+// recoveryPath returns this session's recovery file for a document. The name
+// ends with the session's pid, so that concurrent sessions on the same
+// document don't overwrite or remove each other's.
+func recoveryPath(filePath string) string {
+	prefix := recoveryPrefix(filePath)
+	if prefix == "" {
+		return ""
+	}
+	return prefix + strconv.Itoa(os.Getpid()) + nativeExt
+}
+
+// autosave writes the document to its recovery file if it has unsaved
+// changes, and removes the recovery file once there are none. Only the first
+// of a run of failures is reported.
+func (m *model) autosave() {
+	if !m.isModified() {
+		m.removeRecovery()
+		return
+	}
+	path := recoveryPath(m.filePath)
+	if path == "" {
+		return
+	}
+	data, err := encodeDocument(m.document())
+	if err == nil {
+		err = os.MkdirAll(filepath.Dir(path), 0700)
+	}
+	if err == nil {
+		err = saveFileMode(path, string(data), 0600)
+	}
+	if err != nil {
+		if !m.autosaveFailed {
+			m.alertMessage = fmt.Sprintf("Autosave failed: %v", err)
+		}
+		m.autosaveFailed = true
+		return
+	}
+	m.autosaveFailed = false
+	if m.recoveryFile != path {
+		m.removeRecovery()
+	}
+	m.recoveryFile = path
+}
+
+// removeRecovery deletes the recovery file written by this session, if any.
+func (m *model) removeRecovery() {
+	if m.recoveryFile != "" {
+		os.Remove(m.recoveryFile)
+		m.recoveryFile = ""
+	}
+}
+
+// findRecovery looks for a recovery file left behind for the document by a
+// session that didn't exit cleanly, so the user can be offered to restore it.
+// Files of sessions still running are theirs; of the rest, the newest is
+// offered.
+func (m *model) findRecovery() {
+	prefix := recoveryPrefix(m.filePath)
+	if prefix == "" {
+		return
+	}
+	entries, _ := os.ReadDir(filepath.Dir(prefix))
+	for _, e := range entries {
+		pid, ok := strings.CutPrefix(e.Name(), filepath.Base(prefix))
+		if !ok || !strings.HasSuffix(pid, nativeExt) {
+			continue
+		}
+		n, err := strconv.Atoi(strings.TrimSuffix(pid, nativeExt))
+		if err != nil || processRunning(n) {
+			continue
+		}
+		path := filepath.Join(filepath.Dir(prefix), e.Name())
+		if info, err := os.Stat(path); err == nil && info.ModTime().After(m.recoveryTime) {
+			m.pendingRecovery = path
+			m.recoveryTime = info.ModTime()
+		}
+	}
+}
+
+// processRunning reports whether a process with the given pid is running,
+// including this one.
+func processRunning(pid int) bool {
+	p, err := os.FindProcess(pid)
+	return err == nil && p.Signal(syscall.Signal(0)) == nil
+}
+
+// restoreRecovery replaces the document with the pending recovery file. The
+// restored content is a new history state, so it still counts as unsaved.
+func (m *model) restoreRecovery() bool {
+	path := m.pendingRecovery
+	m.pendingRecovery = ""
+	data, err := os.ReadFile(path)
+	var doc document
+	if err == nil {
+		doc, err = decodeDocument(data)
+	}
+	if err != nil {
+		m.alertMessage = fmt.Sprintf("Restore failed: %v", err)
+		return false
+	}
+	m.applyDocument(doc)
+	m.canvasInitialized = true
+	m.selection.active = false
+	m.textInsertActive = false
+	m.saveToHistoryAs("Restore")
+	m.recoveryFile = path
+	return true
+}
+
+// handleRecoveryKey handles the restore prompt shown on launch. Discarding
+// deletes the recovery file; ctrl+c quits and leaves it for the next launch.
+// Other keys are ignored until the user chooses.
+func (m *model) handleRecoveryKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "ctrl+c":
+		return m, tea.Quit
+	case "r":
+		m.restoreRecovery()
+	case "d":
+		os.Remove(m.pendingRecovery)
+		m.pendingRecovery = ""
+	}
+	return m, nil
+}
+
+func (m *model) renderRecoveryPrompt() string {
+	dialogStyle := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(themeColor(m.config.Theme.MenuBorder)).
+		Padding(0, 1)
+	accentStyle := lipgloss.NewStyle().Foreground(themeColor(m.config.Theme.ToolbarHighlightBg))
+	name := "canvas"
+	if m.filePath != "" {
+		name = shortPath(m.filePath)
+	}
+	return dialogStyle.Render("Unsaved changes to " + name + " were recovered from " +
+		m.recoveryTime.Format("Jan 2 15:04") + ".\n" +
+		accentStyle.Render("r") + " restore  " + accentStyle.Render("d") + " discard")
+}
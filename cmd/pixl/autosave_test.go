@@ -0,0 +1,181 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newAutosaveModel(t *testing.T) *model {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	m := newLayerModel()
+	m.filePath = filepath.Join(t.TempDir(), "art.txt")
+	return m
+}
+
+// crash autosaves a model's unsaved changes and leaves the recovery file as
+// a session that is no longer running would.
+func crash(t *testing.T, m *model) string {
+	t.Helper()
+	m.autosave()
+	crashed := strings.TrimSuffix(m.recoveryFile, fmt.Sprintf("%d.pixl", os.Getpid())) + "999999999.pixl"
+	if err := os.Rename(m.recoveryFile, crashed); err != nil {
+		t.Fatal(err)
+	}
+	return crashed
+}
+
+func TestAutosaveInterval(t *testing.T) {
+	m := &model{}
+	if got := m.autosaveInterval(); got != defaultAutosaveInterval {
+		t.Errorf("default interval = %v", got)
+	}
+	m.config.AutosaveInterval = 5
+	if got := m.autosaveInterval(); got != 5*time.Second {
+		t.Errorf("interval = %v, want 5s", got)
+	}
+	m.config.AutosaveInterval = -1
+	if m.autosaveCmd() != nil {
+		t.Error("autosave should be off")
+	}
+}
+
+func TestRecoveryPath(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", "/state")
+	a, b := recoveryPath("/a/art.txt"), recoveryPath("/b/art.txt")
+	if a == b {
+		t.Error("files with the same name in different directories should not share a recovery file")
+	}
+	if filepath.Dir(a) != "/state/pixl" || !strings.HasPrefix(filepath.Base(a), "art-") || !strings.HasSuffix(a, fmt.Sprintf("-%d.pixl", os.Getpid())) {
+		t.Errorf("recovery path = %q", a)
+	}
+	if got, want := recoveryPath(""), fmt.Sprintf("/state/pixl/unnamed-%d.pixl", os.Getpid()); got != want {
+		t.Errorf("unnamed recovery path = %q, want %q", got, want)
+	}
+}
+
+func TestNamedRecoveryPerSession(t *testing.T) {
+	running := newAutosaveModel(t)
+	running.canvas.Set(0, 0, "A", "white", "transparent")
+	running.saveToHistory()
+	running.autosave()
+
+	m := newLayerModel()
+	m.filePath = running.filePath
+	m.findRecovery()
+	if m.pendingRecovery != "" {
+		t.Errorf("a running session's autosave %q shouldn't be offered", m.pendingRecovery)
+	}
+	m.canvas.Set(0, 1, "B", "white", "transparent")
+	m.saveToHistory()
+	m.save()
+	if _, err := os.Stat(running.recoveryFile); err != nil {
+		t.Error("saving in one session shouldn't remove another's recovery file")
+	}
+}
+
+func TestUnnamedRecoveryPerSession(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "pixl")
+	t.Setenv("XDG_STATE_HOME", filepath.Dir(dir))
+	os.MkdirAll(dir, 0700)
+
+	// A running session's recovery file is its own; a finished one's is
+	// left behind for the next launch
+	running := filepath.Join(dir, fmt.Sprintf("unnamed-%d.pixl", os.Getppid()))
+	crashed := filepath.Join(dir, "unnamed-999999999.pixl")
+	os.WriteFile(running, nil, 0600)
+	os.WriteFile(crashed, nil, 0600)
+	os.Chtimes(running, time.Now(), time.Now().Add(time.Hour))
+
+	m := newLayerModel()
+	m.findRecovery()
+	if m.pendingRecovery != crashed {
+		t.Errorf("pendingRecovery = %q, want the crashed session's %q", m.pendingRecovery, crashed)
+	}
+}
+
+func TestAutosaveWritesOnlyUnsavedChanges(t *testing.T) {
+	m := newAutosaveModel(t)
+	path := recoveryPath(m.filePath)
+	m.Update(autosaveTick{})
+	if _, err := os.Stat(path); err == nil {
+		t.Fatal("an unmodified document should not be autosaved")
+	}
+
+	m.canvas.Set(0, 0, "A", "white", "transparent")
+	m.saveToHistory()
+	m.Update(autosaveTick{})
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("autosave should write the recovery file: %v", err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("recovery file mode = %v, want 0600", info.Mode().Perm())
+	}
+	if entries, _ := os.ReadDir(filepath.Dir(path)); len(entries) != 1 {
+		t.Errorf("recovery directory has %d entries, want no temporary files left", len(entries))
+	}
+
+	m.save()
+	if _, err := os.Stat(path); err == nil {
+		t.Error("saving should remove the recovery file")
+	}
+}
+
+func TestRestoreRecovery(t *testing.T) {
+	crashed := newAutosaveModel(t)
+	crashed.canvas.Set(1, 2, "R", "red", "transparent")
+	crashed.saveToHistory()
+	path := crash(t, crashed)
+
+	m := newLayerModel()
+	m.filePath = crashed.filePath
+	m.findRecovery()
+	if m.pendingRecovery == "" {
+		t.Fatal("the recovery file should be found on launch")
+	}
+	m.ready = true
+	if !strings.Contains(m.View(), "were recovered from") {
+		t.Error("view should offer to restore")
+	}
+	m.handleKey(keyMsg("x"))
+	if m.pendingRecovery == "" {
+		t.Fatal("other keys should not dismiss the prompt")
+	}
+	m.handleKey(keyMsg("r"))
+	if m.pendingRecovery != "" || m.canvas.Get(1, 2).char != "R" {
+		t.Fatal("r should restore the recovered content")
+	}
+	if !m.isModified() {
+		t.Error("restored content should count as unsaved")
+	}
+
+	m.removeRecovery()
+	if _, err := os.Stat(path); err == nil {
+		t.Error("a clean exit should remove the restored recovery file")
+	}
+}
+
+func TestDiscardRecovery(t *testing.T) {
+	crashed := newAutosaveModel(t)
+	crashed.canvas.Set(0, 0, "A", "white", "transparent")
+	crashed.saveToHistory()
+	path := crash(t, crashed)
+
+	m := newLayerModel()
+	m.filePath = crashed.filePath
+	m.findRecovery()
+	if m.pendingRecovery != path {
+		t.Fatalf("pendingRecovery = %q, want %q", m.pendingRecovery, path)
+	}
+	m.handleKey(keyMsg("d"))
+	if m.pendingRecovery != "" || m.canvas.Get(0, 0).char == "A" {
+		t.Fatal("d should dismiss the prompt without restoring")
+	}
+	if _, err := os.Stat(path); err == nil {
+		t.Error("d should delete the recovery file")
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Backup modes for the backup config key.
+const (
+	backupSimple   = "simple"
+	backupNumbered = "numbered"
+)
+
+// backupDirName is the directory, next to the file, that numbered backups
+// are kept in.
+const backupDirName = ".pixl-backups"
+
+// backupFile keeps the current contents of path before it is overwritten:
+// as path~ in simple mode, or as name.~N~ in the numbered backups directory,
+// counting up from 1. Nothing is done if path doesn't exist yet.
+func backupFile(path, mode string) error {
+	if mode == "" {
+		return nil
+	}
+	if target, err := filepath.EvalSymlinks(path); err == nil {
+		path = target
+	}
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return nil
+	}
+
+	backup := path + "~"
+	if mode == backupNumbered {
+		dir := filepath.Join(filepath.Dir(path), backupDirName)
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+		backup = filepath.Join(dir, nextBackupName(dir, filepath.Base(path)))
+	}
+	return linkOrCopy(path, backup)
+}
+
+// nextBackupName returns name.~N~ with N one above the highest numbered
+// backup of name in dir.
+func nextBackupName(dir, name string) string {
+	last := 0
+	entries, _ := os.ReadDir(dir)
+	for _, e := range entries {
+		rest, ok := strings.CutPrefix(e.Name(), name+".~")
+		if !ok {
+			continue
+		}
+		if n, err := strconv.Atoi(strings.TrimSuffix(rest, "~")); err == nil && strings.HasSuffix(rest, "~") {
+			last = max(last, n)
+		}
+	}
+	return name + ".~" + strconv.Itoa(last+1) + "~"
+}
+
+// linkOrCopy makes dst a hard link to src, replacing dst. Saving renames a
+// new file over src, so the link keeps the old contents. Filesystems without
+// hard links get a copy instead.
+func linkOrCopy(src, dst string) error {
+	os.Remove(dst)
+	if os.Link(src, dst) == nil {
+		return nil
+	}
+	return copyFile(src, dst)
+}
+
+// copyFile copies src to a new file dst with the same permissions, so a
+// private file doesn't get a readable backup.
+func copyFile(src, dst string) error {
+	info, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(src)
+	if err != nil {
+		return err
+	}
+	return saveFileMode(dst, string(data), info.Mode().Perm())
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func readTestFile(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestBackupFileSimple(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "art.txt")
+	if err := backupFile(path, backupSimple); err != nil {
+		t.Fatalf("backing up a missing file: %v", err)
+	}
+	if _, err := os.Stat(path + "~"); err == nil {
+		t.Error("a missing file should not be backed up")
+	}
+
+	os.WriteFile(path, []byte("v1"), 0644)
+	if err := backupFile(path, backupSimple); err != nil {
+		t.Fatal(err)
+	}
+	if err := saveFile(path, "v2"); err != nil {
+		t.Fatal(err)
+	}
+	if got := readTestFile(t, path+"~"); got != "v1" {
+		t.Errorf("backup = %q, want v1", got)
+	}
+	if got := readTestFile(t, path); got != "v2" {
+		t.Errorf("file = %q, want v2", got)
+	}
+}
+
+func TestBackupFileNumbered(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "art.txt")
+	for _, v := range []string{"v1", "v2", "v3"} {
+		if err := saveFile(path, v); err != nil {
+			t.Fatal(err)
+		}
+		if err := backupFile(path, backupNumbered); err != nil {
+			t.Fatal(err)
+		}
+	}
+	backups := filepath.Join(dir, backupDirName)
+	for i, want := range []string{"v1", "v2", "v3"} {
+		name := filepath.Join(backups, "art.txt.~"+string(rune('1'+i))+"~")
+		if got := readTestFile(t, name); got != want {
+			t.Errorf("%s = %q, want %q", filepath.Base(name), got, want)
+		}
+	}
+}
+
+func TestCopyFileKeepsPermissions(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "art.txt")
+	os.WriteFile(src, []byte("v1"), 0600)
+	if err := copyFile(src, src+"~"); err != nil {
+		t.Fatal(err)
+	}
+	if got := readTestFile(t, src+"~"); got != "v1" {
+		t.Errorf("copy = %q, want v1", got)
+	}
+	info, err := os.Stat(src + "~")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("copy mode = %v, want 0600", info.Mode().Perm())
+	}
+}
+
+func TestNextBackupName(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"art.txt.~2~", "art.txt.~10~", "art.txt.~x~", "other.txt.~40~"} {
+		os.WriteFile(filepath.Join(dir, name), nil, 0644)
+	}
+	if got := nextBackupName(dir, "art.txt"); got != "art.txt.~11~" {
+		t.Errorf("nextBackupName = %q, want art.txt.~11~", got)
+	}
+}
+
+func TestSaveBacksUpOncePerSession(t *testing.T) {
+	m := newLayerModel()
+	m.config.Backup = backupSimple
+	m.filePath = filepath.Join(t.TempDir(), "art.txt")
+	os.WriteFile(m.filePath, []byte("original"), 0644)
+
+	m.canvas.Set(0, 0, "A", "white", "transparent")
+	m.saveToHistory()
+	m.save()
+	m.canvas.Set(0, 1, "B", "white", "transparent")
+	m.saveToHistory()
+	m.save()
+
+	if got := readTestFile(t, m.filePath+"~"); got != "original" {
+		t.Errorf("backup = %q, want the version from before the session", got)
+	}
+}
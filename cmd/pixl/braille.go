@@ -0,0 +1,89 @@
+package main
+
+// brailleBlank is the braille pattern with no dots, U+2800. Each of the 256
+// patterns after it sets one bit per dot of a 2x4 grid.
+const brailleBlank = 0x2800
+
+// brailleBits maps a dot's row and column within a cell to its bit.
+var brailleBits = [4][2]rune{
+	{0x01, 0x08},
+	{0x02, 0x10},
+	{0x04, 0x20},
+	{0x40, 0x80},
+}
+
+// brailleDots returns the dots of a cell's braille pattern, or none if it
+// holds some other glyph.
+func brailleDots(c Cell) rune {
+	r := []rune(c.char)
+	if len(r) != 1 || r[0] < brailleBlank || r[0] > brailleBlank+0xff {
+		return 0
+	}
+	return r[0] - brailleBlank
+}
+
+// setDot draws a braille dot in a color on the active layer. The dot is
+// added to the cell's pattern, which takes the color over the current
+// background, and the transparent color clears it instead. A cell left
+// without dots is blank.
+func (m *model) setDot(y, x int, color string) {
+	if y < 0 || x < 0 {
+		return
+	}
+	row, col := y/4, x/2
+	cell := m.canvas.Get(row, col)
+	if cell == nil {
+		return
+	}
+	dots, bit := brailleDots(*cell), brailleBits[y%4][x%2]
+	if color != "transparent" {
+		c := m.brushCell(string(brailleBlank + (dots | bit)))
+		c.foregroundColor = color
+		m.canvas.SetCell(row, col, c)
+		return
+	}
+	if dots&bit == 0 {
+		return
+	}
+	if dots &^= bit; dots == 0 {
+		m.canvas.SetCell(row, col, layerBlank(m.activeLayer))
+		return
+	}
+	c := *cell
+	c.char = string(brailleBlank + dots)
+	m.canvas.SetCell(row, col, c)
+}
+
+// renderBraille renders a cell in braille mode with the stroke preview and
+// the cursor drawn on its dots. ok is false if neither is on the cell.
+func (m *model) renderBraille(row, col int) (string, bool) {
+	cell := m.compositeCell(row, col)
+	if cell == nil {
+		return "", false
+	}
+	dots := brailleDots(*cell)
+	style := cellStyle(cell.foregroundColor, cell.backgroundColor, cell.attrs)
+	drawn := false
+	for dy := range brailleBits {
+		for dx, bit := range brailleBits[dy] {
+			color, ok := m.dotColor(row*4+dy, col*2+dx)
+			switch {
+			case !ok:
+			case color != "transparent":
+				dots |= bit
+				style = cellStyle(color, m.backgroundColor, m.textAttrs)
+				drawn = true
+			case dots&bit != 0:
+				dots &^= bit
+				drawn = true
+			}
+		}
+	}
+	if !drawn {
+		return "", false
+	}
+	if dots == 0 {
+		return " ", true
+	}
+	return style.Render(string(brailleBlank + dots)), true
+}
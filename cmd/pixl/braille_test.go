@@ -0,0 +1,73 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestBrailleLine(t *testing.T) {
+	m := newDrawModel("Line", 4, 3)
+	m.grid = brailleGrid
+	drag(m, 0, 0, 0, 3)
+
+	// Dot columns 0 to 6 along the top row of dots
+	checkCells(t, m, 4, 3, map[[2]int]string{{0, 0}: "⠉", {0, 1}: "⠉", {0, 2}: "⠉", {0, 3}: "⠁"})
+	if cell := m.canvas.Get(0, 0); cell.foregroundColor != "red" {
+		t.Errorf("dots are %s, want red", cell.foregroundColor)
+	}
+}
+
+func TestBrailleDotsCombine(t *testing.T) {
+	m := newDrawModel("Line", 4, 3)
+	m.grid = brailleGrid
+	m.toggleKeyboardCursor()
+	drag(m, 0, 0, 0, 1)
+	pressKeys(m, "j", "j", "j", "l", " ", "K", "K", "K", " ")
+
+	// The vertical line down the right of the first cell keeps the
+	// horizontal one's dots
+	checkCells(t, m, 4, 3, map[[2]int]string{{0, 0}: "⢹", {0, 1}: "⠁"})
+}
+
+func TestBrailleErasesSingleDots(t *testing.T) {
+	m := newDrawModel("Point", 4, 3)
+	m.grid = brailleGrid
+	m.toggleKeyboardCursor()
+	drag(m, 0, 0, 0, 1)
+	m.canvas.Set(1, 0, "A", "red", "transparent")
+	m.foregroundColor = "transparent"
+
+	pressKeys(m, "l", " ", " ")
+	checkCells(t, m, 4, 3, map[[2]int]string{{0, 0}: "⠁", {0, 1}: "⠁"})
+
+	pressKeys(m, "h", " ", " ")
+	checkCells(t, m, 4, 3, map[[2]int]string{{0, 0}: " "})
+
+	m.tool().OnDrag(m, 4, 0)
+	checkCells(t, m, 4, 3, map[[2]int]string{{1, 0}: "A"})
+}
+
+func TestBrailleCursorStepsByDot(t *testing.T) {
+	m := newDrawModel("Point", 4, 3)
+	m.grid = brailleGrid
+	m.toggleKeyboardCursor()
+	pressKeys(m, "l", "j", "j", "j", "j")
+	if m.hoverRow != 1 || m.hoverCol != 0 || m.hoverDotY != 0 || m.hoverDotX != 1 {
+		t.Errorf("cursor at cell (%d,%d) dot (%d,%d), want cell (1,0) dot (0,1)",
+			m.hoverRow, m.hoverCol, m.hoverDotY, m.hoverDotX)
+	}
+	if y, x := m.strokePoint(m.hoverRow, m.hoverCol); y != 4 || x != 1 {
+		t.Errorf("cursor is on dot (%d,%d), want (4,1)", y, x)
+	}
+}
+
+func TestBraillePreview(t *testing.T) {
+	m := newDrawModel("Ellipse", 4, 3)
+	m.grid = brailleGrid
+	m.canvas.Set(0, 1, "⠈", "red", "transparent")
+	m.beginStroke(0, 0)
+	m.dragStroke(0, 1)
+	if got := m.renderCellAt(0, 1); !strings.Contains(got, "⠉") {
+		t.Errorf("preview at (0,1) = %q, want the existing dot combined with the outline", got)
+	}
+}
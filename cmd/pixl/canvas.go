@@ -2,6 +2,7 @@ package main
 
 import (
 	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
 )
@@ -18,6 +19,38 @@ type Cell struct {
 	char            string
 	foregroundColor string
 	backgroundColor string
+	attrs           cellAttrs
+}
+
+// cellAttrs is a bitset of SGR text attributes.
+type cellAttrs uint8
+
+const (
+	attrBold cellAttrs = 1 << iota
+	attrDim
+	attrItalic
+	attrUnderline
+	attrReverse
+	attrStrikethrough
+
+	attrAll = attrBold | attrDim | attrItalic | attrUnderline | attrReverse | attrStrikethrough
+)
+
+// textAttributes lists each attribute with its SGR on/off codes, its name
+// and the letter shown in the toolbar, in toolbar order.
+var textAttributes = []struct {
+	attr   cellAttrs
+	on     int
+	off    int
+	name   string
+	letter string
+}{
+	{attrBold, 1, 22, "Bold", "B"},
+	{attrDim, 2, 22, "Dim", "D"},
+	{attrItalic, 3, 23, "Italic", "I"},
+	{attrUnderline, 4, 24, "Underline", "U"},
+	{attrReverse, 7, 27, "Reverse", "R"},
+	{attrStrikethrough, 9, 29, "Strikethrough", "S"},
 }
 
 // NewCanvas creates a new canvas
@@ -42,6 +75,7 @@ func (c *Canvas) LoadText(text string) {
 
 		fg := "white"
 		bg := "transparent"
+		var attrs cellAttrs
 		col := 0
 		i := 0
 		runes := []rune(line)
@@ -59,7 +93,7 @@ func (c *Canvas) LoadText(text string) {
 				}
 				if j < len(runes) {
 					params := string(runes[i+2 : j])
-					fg, bg = applyANSIParams(params, fg, bg)
+					fg, bg, attrs = applyANSIParams(params, fg, bg, attrs)
 				}
 				// Skip past 'm' if found, or past entire malformed sequence
 				i = j + 1
@@ -67,8 +101,8 @@ func (c *Canvas) LoadText(text string) {
 			}
 
 			r := runes[i]
-			if r != ' ' {
-				c.cells[row][col] = Cell{char: string(r), foregroundColor: fg, backgroundColor: bg}
+			if r != ' ' || bg != "transparent" || attrs != 0 {
+				c.cells[row][col] = Cell{char: string(r), foregroundColor: fg, backgroundColor: bg, attrs: attrs}
 			}
 			col++
 			i++
@@ -76,27 +110,96 @@ func (c *Canvas) LoadText(text string) {
 	}
 }
 
-func applyANSIParams(params, fg, bg string) (string, string) {
+func applyANSIParams(params, fg, bg string, attrs cellAttrs) (string, string, cellAttrs) {
 	if params == "" || params == "0" {
-		return "white", "transparent"
+		return "white", "transparent", 0
 	}
-	for _, p := range strings.Split(params, ";") {
-		code, err := strconv.Atoi(p)
+	parts := strings.Split(params, ";")
+	for i := 0; i < len(parts); i++ {
+		code, err := strconv.Atoi(parts[i])
 		if err != nil {
 			continue
 		}
+		switch code {
+		case 0:
+			fg = "white"
+			bg = "transparent"
+			attrs = 0
+		case 39:
+			fg = "white"
+		case 49:
+			bg = "transparent"
+		case 38, 48:
+			color, n := parseExtendedColor(parts[i+1:])
+			i += n
+			if color == "" {
+				continue
+			}
+			if code == 38 {
+				fg = color
+			} else {
+				bg = color
+			}
+		}
 		if name, ok := ansiFgToName[code]; ok {
 			fg = name
 		}
 		if name, ok := ansiBgToName[code]; ok {
 			bg = name
 		}
-		if code == 0 {
-			fg = "white"
-			bg = "transparent"
+		for _, a := range textAttributes {
+			if code == a.on {
+				attrs |= a.attr
+			} else if code == a.off {
+				attrs &^= a.attr
+			}
+		}
+	}
+	return fg, bg, attrs
+}
+
+// sgrCodes returns the SGR parameters that turn on the given attributes.
+func (a cellAttrs) sgrCodes() []string {
+	var codes []string
+	for _, ta := range textAttributes {
+		if a&ta.attr != 0 {
+			codes = append(codes, strconv.Itoa(ta.on))
+		}
+	}
+	return codes
+}
+
+// parseExtendedColor parses the arguments following a 38 or 48 code:
+// "5;n" for a 256-color index or "2;r;g;b" for 24-bit color. It returns the
+// color name ("" if malformed) and how many parameters it consumed.
+func parseExtendedColor(args []string) (string, int) {
+	if len(args) == 0 {
+		return "", 0
+	}
+	switch args[0] {
+	case "5":
+		if len(args) < 2 {
+			return "", len(args)
+		}
+		if n, ok := ansi256Index(args[1]); ok {
+			return strconv.Itoa(n), 2
+		}
+		return "", 2
+	case "2":
+		if len(args) < 4 {
+			return "", len(args)
 		}
+		var rgb [3]int
+		for i := range rgb {
+			v, err := strconv.Atoi(args[1+i])
+			if err != nil || v < 0 || v > 255 {
+				return "", 4
+			}
+			rgb[i] = v
+		}
+		return hexColor(rgb[0], rgb[1], rgb[2]), 4
 	}
-	return fg, bg
+	return "", 1
 }
 
 func visibleWidth(line string) int {
@@ -132,12 +235,49 @@ var ansiBgToName = map[int]string{
 	104: "bright_blue", 105: "bright_magenta", 106: "bright_cyan", 107: "bright_white",
 }
 
+// saveFile writes content to path atomically: it goes to a temporary file in
+// the same directory, which is synced and then renamed over the target, so a
+// failed write never truncates the existing file. Permissions of an existing
+// file are kept; new files get 0666 less the umask. Symlinks are followed.
 func saveFile(path, content string) error {
-	perm := os.FileMode(0666)
-	if info, err := os.Stat(path); err == nil {
+	return saveFileMode(path, content, 0666)
+}
+
+// saveFileMode is saveFile giving new files the permissions perm.
+func saveFileMode(path, content string, perm os.FileMode) error {
+	if target, err := filepath.EvalSymlinks(path); err == nil {
+		path = target
+	}
+	info, statErr := os.Stat(path)
+	if statErr == nil {
 		perm = info.Mode().Perm()
 	}
-	return os.WriteFile(path, []byte(content), perm)
+
+	// The temporary name is unique to this process, so anything already
+	// there was left by an earlier process with the same pid.
+	tmp := filepath.Join(filepath.Dir(path), "."+filepath.Base(path)+".tmp"+strconv.Itoa(os.Getpid()))
+	os.Remove(tmp)
+	f, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+	if err != nil {
+		return err
+	}
+	_, err = f.WriteString(content)
+	if err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil && statErr == nil {
+		err = os.Chmod(tmp, perm)
+	}
+	if err == nil {
+		err = os.Rename(tmp, path)
+	}
+	if err != nil {
+		os.Remove(tmp)
+	}
+	return err
 }
 
 // Set sets a character and colors at the given position
@@ -147,6 +287,13 @@ func (c *Canvas) Set(row, col int, char, fgColor, bgColor string) {
 	}
 }
 
+// SetCell sets the cell at the given position
+func (c *Canvas) SetCell(row, col int, cell Cell) {
+	if row >= 0 && row < c.height && col >= 0 && col < c.width {
+		c.cells[row][col] = cell
+	}
+}
+
 // Get gets the cell at the given position
 func (c *Canvas) Get(row, col int) *Cell {
 	if row >= 0 && row < c.height && col >= 0 && col < c.width {
@@ -186,7 +333,7 @@ func (c Canvas) Equals(other Canvas) bool {
 			if cell1 == nil || cell2 == nil {
 				return false
 			}
-			if cell1.char != cell2.char || cell1.foregroundColor != cell2.foregroundColor || cell1.backgroundColor != cell2.backgroundColor {
+			if *cell1 != *cell2 {
 				return false
 			}
 		}
@@ -0,0 +1,255 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// maxCanvasSize bounds each dimension set by the canvas size commands.
+const maxCanvasSize = 4096
+
+// canvasAnchor is where the existing content stays when the canvas is
+// resized. y and x count halves of the added or removed space that go
+// before the content: 0 keeps it at the top/left, 2 at the bottom/right.
+type canvasAnchor struct {
+	name string
+	y, x int
+}
+
+var canvasAnchors = []canvasAnchor{
+	{"top-left", 0, 0}, {"top", 0, 1}, {"top-right", 0, 2},
+	{"left", 1, 0}, {"center", 1, 1}, {"right", 1, 2},
+	{"bottom-left", 2, 0}, {"bottom", 2, 1}, {"bottom-right", 2, 2},
+}
+
+type sizePromptMode int
+
+const (
+	promptResizeCanvas sizePromptMode = iota
+	promptExpandCanvas
+)
+
+// reframeLayers gives every layer a new size, moving its content by offY
+// rows and offX columns. Content moved outside the canvas is dropped.
+func (m *model) reframeLayers(width, height, offY, offX int) {
+	m.syncActiveLayer()
+	for i := range m.layers {
+		old := m.layers[i].canvas
+		c := newLayerCanvas(i, width, height)
+		for row := max(0, -offY); row < min(old.height, height-offY); row++ {
+			for col := max(0, -offX); col < min(old.width, width-offX); col++ {
+				c.cells[row+offY][col+offX] = old.cells[row][col]
+			}
+		}
+		m.layers[i].canvas = c
+	}
+	m.canvas = m.layers[m.activeLayer].canvas
+}
+
+// setCanvasSize reframes the canvas as a single undoable step. The new size
+// becomes fixed, so it no longer follows the terminal.
+func (m *model) setCanvasSize(label string, width, height, offY, offX int) bool {
+	if width < 1 || height < 1 || width > maxCanvasSize || height > maxCanvasSize {
+		m.alertMessage = fmt.Sprintf("Canvas size must be between 1x1 and %dx%d", maxCanvasSize, maxCanvasSize)
+		return false
+	}
+	if width == m.canvas.width && height == m.canvas.height && offY == 0 && offX == 0 {
+		return false
+	}
+	m.reframeLayers(width, height, offY, offX)
+	m.fixedWidth, m.fixedHeight = width, height
+	m.canvasInitialized = true
+	m.selection.active = false
+	m.textInsertActive = false
+	m.saveToHistoryAs(label)
+	return true
+}
+
+// resizeCanvas changes the canvas size, keeping the content at the anchor.
+func (m *model) resizeCanvas(width, height int, anchor canvasAnchor) bool {
+	offY := (height - m.canvas.height) * anchor.y / 2
+	offX := (width - m.canvas.width) * anchor.x / 2
+	return m.setCanvasSize("Resize Canvas", width, height, offY, offX)
+}
+
+// expandCanvas adds cells on each side of the canvas.
+func (m *model) expandCanvas(top, right, bottom, left int) bool {
+	return m.setCanvasSize("Expand Canvas",
+		m.canvas.width+left+right, m.canvas.height+top+bottom, top, left)
+}
+
+// cropToSelection crops the canvas to the inside of the selection.
+func (m *model) cropToSelection() bool {
+	if !m.selection.active {
+		m.alertMessage = "Crop to Selection needs a selection"
+		return false
+	}
+	minY, minX, maxY, maxX, ok := m.selectionBounds()
+	if !ok {
+		m.alertMessage = "The selection is empty"
+		return false
+	}
+	return m.setCanvasSize("Crop to Selection", maxX-minX+1, maxY-minY+1, -minY, -minX)
+}
+
+// trimCanvas removes the blank margins around the content of all layers.
+func (m *model) trimCanvas() bool {
+	top, left, bottom, right, ok := m.contentBounds()
+	if !ok {
+		m.alertMessage = "The canvas is empty"
+		return false
+	}
+	return m.setCanvasSize("Trim Canvas", right-left+1, bottom-top+1, -top, -left)
+}
+
+// parseCanvasSize parses "WIDTHxHEIGHT [anchor]". The anchor defaults to the
+// top-left corner.
+func parseCanvasSize(input string) (width, height int, anchor canvasAnchor, err error) {
+	fields := strings.Fields(input)
+	if len(fields) == 0 || len(fields) > 2 {
+		return 0, 0, anchor, fmt.Errorf("expected WIDTHxHEIGHT [anchor]")
+	}
+	w, h, ok := strings.Cut(strings.ToLower(fields[0]), "x")
+	width, werr := strconv.Atoi(w)
+	height, herr := strconv.Atoi(h)
+	if !ok || werr != nil || herr != nil {
+		return 0, 0, anchor, fmt.Errorf("invalid size %q", fields[0])
+	}
+	anchor = canvasAnchors[0]
+	if len(fields) == 2 {
+		found := false
+		for _, a := range canvasAnchors {
+			if a.name == strings.ToLower(fields[1]) {
+				anchor, found = a, true
+			}
+		}
+		if !found {
+			return 0, 0, anchor, fmt.Errorf("unknown anchor %q", fields[1])
+		}
+	}
+	return width, height, anchor, nil
+}
+
+// parseCanvasExpand parses "N [side...]", the cells to add on each named
+// side, or on every side if none are named.
+func parseCanvasExpand(input string) (top, right, bottom, left int, err error) {
+	fields := strings.Fields(input)
+	if len(fields) == 0 {
+		return 0, 0, 0, 0, fmt.Errorf("expected N [top|right|bottom|left...]")
+	}
+	n, err := strconv.Atoi(fields[0])
+	if err != nil || n < 1 {
+		return 0, 0, 0, 0, fmt.Errorf("invalid number of cells %q", fields[0])
+	}
+	if len(fields) == 1 {
+		return n, n, n, n, nil
+	}
+	for _, side := range fields[1:] {
+		switch strings.ToLower(side) {
+		case "top":
+			top = n
+		case "right":
+			right = n
+		case "bottom":
+			bottom = n
+		case "left":
+			left = n
+		default:
+			return 0, 0, 0, 0, fmt.Errorf("unknown side %q", side)
+		}
+	}
+	return top, right, bottom, left, nil
+}
+
+func (m *model) openSizePrompt(mode sizePromptMode) {
+	m.closeMenus()
+	m.showSizePrompt = true
+	m.sizePromptMode = mode
+	m.sizePromptError = ""
+	m.sizeInput = ""
+	if mode == promptResizeCanvas {
+		m.sizeInput = fmt.Sprintf("%dx%d", m.canvas.width, m.canvas.height)
+	}
+}
+
+// applySizePrompt runs the prompt's command, returning an error for input
+// that doesn't parse.
+func (m *model) applySizePrompt() error {
+	if m.sizePromptMode == promptExpandCanvas {
+		top, right, bottom, left, err := parseCanvasExpand(m.sizeInput)
+		if err == nil {
+			m.expandCanvas(top, right, bottom, left)
+		}
+		return err
+	}
+	width, height, anchor, err := parseCanvasSize(m.sizeInput)
+	if err == nil {
+		m.resizeCanvas(width, height, anchor)
+	}
+	return err
+}
+
+// handleSizePromptKey handles keys while the canvas size prompt is open.
+// Input that doesn't parse keeps the prompt open with an error.
+func (m *model) handleSizePromptKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.Type {
+	case tea.KeyEscape:
+		m.showSizePrompt = false
+	case tea.KeyEnter:
+		if err := m.applySizePrompt(); err != nil {
+			m.sizePromptError = err.Error()
+			return m, nil
+		}
+		m.showSizePrompt = false
+	case tea.KeyBackspace:
+		if msg.Alt {
+			m.sizeInput = deleteWord(m.sizeInput)
+		} else if len(m.sizeInput) > 0 {
+			m.sizeInput = m.sizeInput[:len(m.sizeInput)-1]
+		}
+		m.sizePromptError = ""
+	case tea.KeySpace:
+		m.sizeInput += " "
+	case tea.KeyRunes:
+		m.sizeInput += string(msg.Runes)
+		m.sizePromptError = ""
+	}
+	return m, nil
+}
+
+func (m *model) renderSizePrompt() string {
+	dimStyle := lipgloss.NewStyle().Faint(true)
+	errorStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("1"))
+
+	title, hint := "Resize Canvas", "WIDTHxHEIGHT [anchor], e.g. 80x24 center"
+	if m.sizePromptMode == promptExpandCanvas {
+		title, hint = "Expand Canvas", "N [top|right|bottom|left...], e.g. 2 left right"
+	}
+	lines := []string{title, "", "> " + m.sizeInput + "_"}
+	if m.sizePromptError != "" {
+		lines = append(lines, errorStyle.Render(m.sizePromptError))
+	} else {
+		lines = append(lines, dimStyle.Render(hint))
+	}
+
+	width := 40
+	for _, line := range lines {
+		width = max(width, lipgloss.Width(line))
+	}
+	lines[1] = strings.Repeat("─", width)
+	for i, line := range lines {
+		if pad := width - lipgloss.Width(line); pad > 0 {
+			lines[i] = line + strings.Repeat(" ", pad)
+		}
+	}
+
+	dialogStyle := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(themeColor(m.config.Theme.MenuBorder)).
+		Padding(0, 1)
+	return dialogStyle.Render(strings.Join(lines, "\n"))
+}
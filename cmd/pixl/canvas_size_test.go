@@ -0,0 +1,175 @@
+package main
+
+import (
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// newSizeModel returns a 4x2 canvas with A in the top-left corner and B in
+// the bottom-right.
+func newSizeModel() *model {
+	m := newLayerModel()
+	m.fixedWidth, m.fixedHeight = 4, 2
+	m.canvas.Set(0, 0, "A", "white", "transparent")
+	m.canvas.Set(1, 3, "B", "white", "transparent")
+	m.saveToHistory()
+	return m
+}
+
+func TestResizeCanvasAnchors(t *testing.T) {
+	tests := []struct {
+		anchor string
+		a, b   [2]int
+	}{
+		{"top-left", [2]int{0, 0}, [2]int{1, 3}},
+		{"center", [2]int{1, 2}, [2]int{2, 5}},
+		{"bottom-right", [2]int{2, 4}, [2]int{3, 7}},
+		{"right", [2]int{1, 4}, [2]int{2, 7}},
+	}
+	for _, tt := range tests {
+		m := newSizeModel()
+		_, _, anchor, err := parseCanvasSize("8x4 " + tt.anchor)
+		if err != nil {
+			t.Fatal(err)
+		}
+		m.resizeCanvas(8, 4, anchor)
+		t.Run(tt.anchor, func(t *testing.T) {
+			checkCells(t, m, 8, 4, map[[2]int]string{tt.a: "A", tt.b: "B"})
+		})
+	}
+}
+
+func TestResizeCanvasShrinks(t *testing.T) {
+	m := newSizeModel()
+	m.resizeCanvas(2, 1, canvasAnchors[0])
+	checkCells(t, m, 2, 1, map[[2]int]string{{0, 0}: "A"})
+	if m.fixedWidth != 2 || m.fixedHeight != 1 {
+		t.Errorf("fixed size = %dx%d, want 2x1", m.fixedWidth, m.fixedHeight)
+	}
+}
+
+func TestCanvasSizeIsOneUndoStep(t *testing.T) {
+	m := newSizeModel()
+	m.addLayer()
+	m.canvas.Set(0, 1, "L", "white", "transparent")
+	m.saveToHistory()
+	before := len(m.history)
+
+	m.expandCanvas(1, 0, 0, 2)
+	if len(m.history) != before+1 {
+		t.Fatalf("history grew by %d, want 1", len(m.history)-before)
+	}
+	checkCells(t, m, 6, 3, map[[2]int]string{{1, 3}: "L"})
+	if c := m.layerCanvas(0); c.width != 6 || c.Get(1, 2).char != "A" {
+		t.Error("every layer should be expanded")
+	}
+
+	m.undo()
+	checkCells(t, m, 4, 2, map[[2]int]string{{0, 1}: "L"})
+	if m.fixedWidth != 4 || m.fixedHeight != 2 {
+		t.Errorf("undo should restore the fixed size, got %dx%d", m.fixedWidth, m.fixedHeight)
+	}
+}
+
+func TestCropToSelection(t *testing.T) {
+	m := newSizeModel()
+	if m.cropToSelection() || m.alertMessage == "" {
+		t.Fatal("cropping without a selection should alert")
+	}
+	m.alertMessage = ""
+	// The selection border is drawn around the cells it selects
+	m.selection = selectionState{active: true, startY: -1, startX: 1, endY: 2, endX: 4}
+	m.cropToSelection()
+	checkCells(t, m, 2, 2, map[[2]int]string{{1, 1}: "B", {0, 0}: " "})
+	if m.selection.active {
+		t.Error("cropping should clear the selection")
+	}
+}
+
+func TestTrimCanvas(t *testing.T) {
+	m := newLayerModel()
+	m.canvas = NewCanvas(10, 6)
+	m.canvas.Set(2, 3, "A", "white", "transparent")
+	m.canvas.Set(4, 5, " ", "white", "red")
+	m.saveToHistory()
+	m.trimCanvas()
+	checkCells(t, m, 3, 3, map[[2]int]string{{0, 0}: "A"})
+	if m.canvas.Get(2, 2).backgroundColor != "red" {
+		t.Error("a space with a background color is content")
+	}
+
+	empty := newLayerModel()
+	if empty.trimCanvas() || empty.alertMessage == "" {
+		t.Error("trimming an empty canvas should alert")
+	}
+}
+
+func TestTrimKeepsCoveringSpaces(t *testing.T) {
+	m := newLayerModel()
+	m.canvas = NewCanvas(10, 6)
+	m.canvas.Set(4, 5, "A", "white", "transparent")
+	m.addLayer()
+
+	// A white space on an upper layer hides the cells beneath it
+	m.canvas.Set(1, 2, " ", "white", "transparent")
+	m.saveToHistory()
+	m.trimCanvas()
+	if m.canvas.width != 4 || m.canvas.height != 4 {
+		t.Errorf("canvas is %dx%d, want the covering space kept at 4x4", m.canvas.width, m.canvas.height)
+	}
+}
+
+func TestTrimAfterCut(t *testing.T) {
+	m := newSizeModel()
+	m.selection = selectionState{active: true, startY: -1, startX: -1, endY: 1, endX: 1}
+	m.cutSelection()
+	m.trimCanvas()
+	checkCells(t, m, 1, 1, map[[2]int]string{{0, 0}: "B"})
+}
+
+func TestParseCanvasSize(t *testing.T) {
+	w, h, a, err := parseCanvasSize("80X24 Bottom")
+	if err != nil || w != 80 || h != 24 || a.name != "bottom" {
+		t.Errorf("got %dx%d %q, %v", w, h, a.name, err)
+	}
+	for _, bad := range []string{"", "80", "80x", "ax2", "80x24 middle", "1x2 top left"} {
+		if _, _, _, err := parseCanvasSize(bad); err == nil {
+			t.Errorf("parseCanvasSize(%q) should fail", bad)
+		}
+	}
+}
+
+func TestParseCanvasExpand(t *testing.T) {
+	if top, right, bottom, left, err := parseCanvasExpand("2"); err != nil || top != 2 || right != 2 || bottom != 2 || left != 2 {
+		t.Errorf("2 should expand every side, got %d %d %d %d %v", top, right, bottom, left, err)
+	}
+	if top, right, bottom, left, err := parseCanvasExpand("3 left bottom"); err != nil || top != 0 || right != 0 || bottom != 3 || left != 3 {
+		t.Errorf("got %d %d %d %d %v", top, right, bottom, left, err)
+	}
+	for _, bad := range []string{"", "0", "-1", "two", "2 up"} {
+		if _, _, _, _, err := parseCanvasExpand(bad); err == nil {
+			t.Errorf("parseCanvasExpand(%q) should fail", bad)
+		}
+	}
+}
+
+func TestSizePrompt(t *testing.T) {
+	m := newSizeModel()
+	m.openSizePrompt(promptResizeCanvas)
+	if m.sizeInput != "4x2" {
+		t.Errorf("prompt should start with the current size, got %q", m.sizeInput)
+	}
+	m.sizeInput = "6x3 nowhere"
+	m.handleKey(tea.KeyMsg{Type: tea.KeyEnter})
+	if !m.showSizePrompt || m.sizePromptError == "" {
+		t.Fatal("invalid input should keep the prompt open with an error")
+	}
+	m.sizeInput = ""
+	typeText(m, "6x3 center")
+	m.handleKey(tea.KeyMsg{Type: tea.KeyEnter})
+	if m.showSizePrompt {
+		t.Fatal("valid input should close the prompt")
+	}
+	checkCells(t, m, 6, 3, map[[2]int]string{{0, 1}: "A"})
+}
@@ -184,6 +184,116 @@ func TestLoadTextANSIBackground(t *testing.T) {
 	}
 }
 
+func TestLoadTextANSI256Color(t *testing.T) {
+	c := NewCanvas(2, 1)
+	c.LoadText("\x1b[38;5;196;48;5;21mX\x1b[0m")
+
+	cell := c.Get(0, 0)
+	if cell.foregroundColor != "196" {
+		t.Errorf("(0,0).fg = %q, want 196", cell.foregroundColor)
+	}
+	if cell.backgroundColor != "21" {
+		t.Errorf("(0,0).bg = %q, want 21", cell.backgroundColor)
+	}
+}
+
+func TestLoadTextANSITruecolor(t *testing.T) {
+	c := NewCanvas(2, 1)
+	c.LoadText("\x1b[1;38;2;255;136;0;48;2;0;0;16mX\x1b[0m")
+
+	cell := c.Get(0, 0)
+	if cell.foregroundColor != "#ff8800" {
+		t.Errorf("(0,0).fg = %q, want #ff8800", cell.foregroundColor)
+	}
+	if cell.backgroundColor != "#000010" {
+		t.Errorf("(0,0).bg = %q, want #000010", cell.backgroundColor)
+	}
+}
+
+func TestLoadTextANSIDefaultColorCodes(t *testing.T) {
+	c := NewCanvas(2, 1)
+	c.LoadText("\x1b[31;44mX\x1b[39;49mY")
+
+	if cell := c.Get(0, 1); cell.foregroundColor != "white" || cell.backgroundColor != "transparent" {
+		t.Errorf("(0,1) = %+v, want white/transparent after 39;49", cell)
+	}
+}
+
+func TestLoadTextANSIMalformedExtendedColor(t *testing.T) {
+	c := NewCanvas(3, 1)
+	c.LoadText("\x1b[38;5;999mA\x1b[38;2;1;2mB\x1b[38;5;34;32mC")
+
+	if cell := c.Get(0, 0); cell.foregroundColor != "white" {
+		t.Errorf("(0,0).fg = %q, want white for out-of-range index", cell.foregroundColor)
+	}
+	if cell := c.Get(0, 1); cell.foregroundColor != "white" {
+		t.Errorf("(0,1).fg = %q, want white for truncated truecolor", cell.foregroundColor)
+	}
+	// The 256-color index is consumed, so the following 32 still applies
+	if cell := c.Get(0, 2); cell.foregroundColor != "green" {
+		t.Errorf("(0,2).fg = %q, want green", cell.foregroundColor)
+	}
+}
+
+func TestExtendedColorsRoundTrip(t *testing.T) {
+	m := &model{canvas: NewCanvas(3, 1)}
+	m.canvas.Set(0, 0, "A", "208", "transparent")
+	m.canvas.Set(0, 1, "B", "#0891b2", "17")
+	m.canvas.Set(0, 2, "C", "red", "#ffffff")
+
+	loaded := NewCanvas(3, 1)
+	loaded.LoadText(m.renderCanvasPlain())
+	if !loaded.Equals(m.canvas) {
+		t.Errorf("round trip lost colors: got %+v, want %+v", loaded.cells[0], m.canvas.cells[0])
+	}
+}
+
+func TestLoadTextAttributes(t *testing.T) {
+	c := NewCanvas(5, 1)
+	c.LoadText("\x1b[1;31mA\x1b[3;4mB\x1b[22;24mC\x1b[0;7;9mD\x1b[2mE")
+
+	want := []cellAttrs{
+		attrBold,
+		attrBold | attrItalic | attrUnderline,
+		attrItalic,
+		attrReverse | attrStrikethrough,
+		attrReverse | attrStrikethrough | attrDim,
+	}
+	for col, attrs := range want {
+		if got := c.Get(0, col).attrs; got != attrs {
+			t.Errorf("(0,%d).attrs = %06b, want %06b", col, got, attrs)
+		}
+	}
+	if cell := c.Get(0, 2); cell.foregroundColor != "red" {
+		t.Errorf("(0,2).foregroundColor = %q, attribute resets should keep color", cell.foregroundColor)
+	}
+}
+
+func TestLoadTextKeepsStyledSpaces(t *testing.T) {
+	c := NewCanvas(2, 1)
+	c.LoadText("\x1b[4m \x1b[0m\x1b[44m \x1b[0m")
+
+	if cell := c.Get(0, 0); cell.attrs != attrUnderline {
+		t.Errorf("(0,0).attrs = %06b, want underline", cell.attrs)
+	}
+	if cell := c.Get(0, 1); cell.backgroundColor != "blue" {
+		t.Errorf("(0,1).backgroundColor = %q, want blue", cell.backgroundColor)
+	}
+}
+
+func TestAttributesRoundTrip(t *testing.T) {
+	m := &model{canvas: NewCanvas(3, 1)}
+	m.canvas.SetCell(0, 0, Cell{char: "A", foregroundColor: "red", backgroundColor: "transparent", attrs: attrBold | attrUnderline})
+	m.canvas.SetCell(0, 1, Cell{char: "B", foregroundColor: "white", backgroundColor: "transparent", attrs: attrAll})
+	m.canvas.SetCell(0, 2, Cell{char: " ", foregroundColor: "white", backgroundColor: "transparent", attrs: attrStrikethrough})
+
+	loaded := NewCanvas(3, 1)
+	loaded.LoadText(m.renderCanvasPlain())
+	if !loaded.Equals(m.canvas) {
+		t.Errorf("round trip lost attributes: got %+v, want %+v", loaded.cells[0], m.canvas.cells[0])
+	}
+}
+
 func TestLoadTextANSIDoesNotCountAsColumns(t *testing.T) {
 	c := NewCanvas(3, 1)
 	c.LoadText("\x1b[31mA\x1b[0m \x1b[34mB\x1b[0m")
@@ -376,3 +486,56 @@ func TestSaveFileNewFileUses0666(t *testing.T) {
 		t.Errorf("new file permissions = %o, want at least 0644", perm)
 	}
 }
+
+func TestSaveFileLeavesNoTempFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "art.txt")
+	if err := saveFile(path, "one"); err != nil {
+		t.Fatal(err)
+	}
+	if err := saveFile(path, "two"); err != nil {
+		t.Fatal(err)
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 {
+		t.Errorf("directory has %d entries, want only the saved file", len(entries))
+	}
+}
+
+func TestSaveFileFailureKeepsTarget(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "art.txt")
+	if err := os.Mkdir(path, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := saveFile(path, "content"); err == nil {
+		t.Fatal("saving over a directory should fail")
+	}
+	if info, err := os.Stat(path); err != nil || !info.IsDir() {
+		t.Error("a failed save should leave the target alone")
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Error("a failed save should remove its temporary file")
+	}
+}
+
+func TestSaveFileFollowsSymlink(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "target.txt")
+	link := filepath.Join(dir, "link.txt")
+	if err := os.WriteFile(target, []byte("old"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(target, link); err != nil {
+		t.Skip("symlinks not supported")
+	}
+	if err := saveFile(link, "new"); err != nil {
+		t.Fatal(err)
+	}
+	if info, err := os.Lstat(link); err != nil || info.Mode()&os.ModeSymlink == 0 {
+		t.Error("saving through a symlink should keep the link")
+	}
+	if data, _ := os.ReadFile(target); string(data) != "new" {
+		t.Errorf("target content = %q, want new", data)
+	}
+}
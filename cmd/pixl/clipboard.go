@@ -0,0 +1,126 @@
+package main
+
+import (
+	"io"
+	"os"
+	"strings"
+
+	"github.com/aymanbagabas/go-osc52/v2"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Formats for the clipboard config key, which sets what yanking sends to the
+// system clipboard.
+const (
+	clipboardText = "text"
+	clipboardANSI = "ansi"
+)
+
+// clipboardOutput opens where OSC 52 sequences are written: the terminal
+// itself, which gets them however stdout and stderr are redirected.
+var clipboardOutput = func() (io.WriteCloser, error) {
+	return os.OpenFile("/dev/tty", os.O_WRONLY, 0)
+}
+
+// pasteTabWidth is the number of spaces a pasted tab becomes.
+const pasteTabWidth = 4
+
+// cellsText renders cells as text, one line per row. With ansi, colors and
+// attributes are kept as SGR sequences; otherwise only the glyphs are kept
+// and trailing spaces are trimmed.
+func cellsText(cells [][]Cell, ansi bool) string {
+	var b strings.Builder
+	for _, row := range cells {
+		var line strings.Builder
+		for _, cell := range row {
+			if cell.foregroundColor == "transparent" {
+				line.WriteString(" ")
+				continue
+			}
+			if !ansi {
+				line.WriteString(cell.char)
+				continue
+			}
+
+			var params []string
+			if fg := colorToANSI(cell.foregroundColor); fg != "" {
+				params = append(params, fg)
+			}
+			if bg := colorToANSIBg(cell.backgroundColor); bg != "" {
+				params = append(params, bg)
+			}
+			params = append(params, cell.attrs.sgrCodes()...)
+
+			if len(params) == 0 {
+				line.WriteString(cell.char)
+				continue
+			}
+
+			line.WriteString("\x1b[")
+			line.WriteString(strings.Join(params, ";"))
+			line.WriteString("m")
+			line.WriteString(cell.char)
+			line.WriteString("\x1b[0m")
+		}
+		if ansi {
+			b.WriteString(line.String())
+		} else {
+			b.WriteString(strings.TrimRight(line.String(), " "))
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// exportClipboard returns a command sending the clipboard to the system
+// clipboard with an OSC 52 sequence, in the configured format. Terminals
+// without OSC 52 support ignore it.
+func (m *model) exportClipboard() tea.Cmd {
+	if m.clipboard.cells == nil || (m.config.Clipboard != clipboardText && m.config.Clipboard != clipboardANSI) {
+		return nil
+	}
+	seq := osc52.New(cellsText(m.clipboard.cells, m.config.Clipboard == clipboardANSI))
+	if os.Getenv("TMUX") == "" && strings.HasPrefix(os.Getenv("TERM"), "screen") {
+		seq = seq.Screen()
+	}
+	return func() tea.Msg {
+		out, err := clipboardOutput()
+		if err != nil {
+			return nil
+		}
+		defer out.Close()
+		seq.WriteTo(out)
+		return nil
+	}
+}
+
+// pasteText floats text pasted from the terminal at the cursor, reading ANSI
+// colors the same way as opening a text file. It replaces the clipboard, so
+// it can be pasted again with p.
+func (m *model) pasteText(text string) {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	text = strings.ReplaceAll(text, "\r", "\n")
+	text = strings.ReplaceAll(text, "\t", strings.Repeat(" ", pasteTabWidth))
+	width, height := textCanvasSize(text)
+	if width == 0 || !m.checkLayerEditable() {
+		return
+	}
+
+	c := NewCanvas(width, height)
+	c.LoadText(text)
+	for row := range c.cells {
+		for col, cell := range c.cells[row] {
+			if isEmptyCell(cell) {
+				c.cells[row][col] = transparentCell
+			}
+		}
+	}
+	m.clipboard = clipboardData{cells: c.cells, width: width, height: height}
+
+	y, x := m.scrollY, m.scrollX
+	if m.cursorVisible {
+		y, x = m.hoverRow, m.hoverCol
+	}
+	m.selection.active = false
+	m.floatClipboard(y, x)
+}
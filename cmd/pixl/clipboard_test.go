@@ -0,0 +1,125 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"io"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestCellsText(t *testing.T) {
+	cells := [][]Cell{
+		{{char: "A", foregroundColor: "red", backgroundColor: "transparent", attrs: attrBold}, transparentCell, blankCell},
+		{blankCell, {char: "B", foregroundColor: "white", backgroundColor: "blue"}, blankCell},
+	}
+	if got, want := cellsText(cells, false), "A\n B\n"; got != want {
+		t.Errorf("plain text = %q, want %q", got, want)
+	}
+	ansi := cellsText(cells, true)
+	if !strings.Contains(ansi, "\x1b[31;1mA\x1b[0m") || !strings.HasSuffix(ansi, "m \n") {
+		t.Errorf("ANSI text = %q, want styled cells and full rows", ansi)
+	}
+}
+
+type nopWriteCloser struct{ io.Writer }
+
+func (nopWriteCloser) Close() error { return nil }
+
+// captureClipboard collects what is sent to the system clipboard, returning
+// the decoded contents of each OSC 52 sequence.
+func captureClipboard(t *testing.T) func() []string {
+	var buf bytes.Buffer
+	old := clipboardOutput
+	clipboardOutput = func() (io.WriteCloser, error) { return nopWriteCloser{&buf}, nil }
+	t.Cleanup(func() { clipboardOutput = old })
+	t.Setenv("TERM", "xterm-256color")
+	return func() []string {
+		var sent []string
+		for _, seq := range strings.Split(buf.String(), "\x07") {
+			if data, ok := strings.CutPrefix(seq, "\x1b]52;c;"); ok {
+				text, err := base64.StdEncoding.DecodeString(data)
+				if err != nil {
+					t.Fatalf("invalid base64 in %q", seq)
+				}
+				sent = append(sent, string(text))
+			}
+		}
+		return sent
+	}
+}
+
+func TestYankSendsToSystemClipboard(t *testing.T) {
+	tests := []struct {
+		format string
+		want   []string
+	}{
+		{clipboardText, []string{"AB\n"}},
+		{clipboardANSI, []string{"\x1b[31mA\x1b[0mB\n"}},
+		{"", nil},
+	}
+	for _, tt := range tests {
+		sent := captureClipboard(t)
+		m := newFloatingModel()
+		m.canvas.Set(1, 1, "A", "red", "transparent")
+		m.config.Clipboard = tt.format
+		if cmd := m.runAction("copy"); cmd != nil {
+			cmd()
+		}
+		if got := sent(); strings.Join(got, "|") != strings.Join(tt.want, "|") {
+			t.Errorf("clipboard = %q: sent %q, want %q", tt.format, got, tt.want)
+		}
+	}
+}
+
+func TestCutSendsToSystemClipboard(t *testing.T) {
+	sent := captureClipboard(t)
+	m := newFloatingModel()
+	m.config.Clipboard = clipboardText
+	m.runAction("cut")()
+	if got := sent(); len(got) != 1 || got[0] != "AB\n" {
+		t.Errorf("cut sent %q, want AB", got)
+	}
+}
+
+func TestBracketedPasteFloatsText(t *testing.T) {
+	m := newFloatingModel()
+	m.selection.active = false
+	m.hoverRow, m.hoverCol, m.cursorVisible = 2, 1, true
+	steps := len(m.history)
+
+	m.handleKey(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("x\x1b[34mYZ\r\n\tw"), Paste: true})
+	if !m.floating.active || len(m.history) != steps {
+		t.Fatal("pasted text should float without recording a step")
+	}
+	// Pasted spaces are transparent, so the A underneath stays
+	checkCells(t, m, 6, 4, map[[2]int]string{{1, 1}: "A", {2, 1}: "x", {2, 2}: "Y", {2, 3}: "Z", {3, 1}: " ", {3, 5}: "w"})
+	if c := m.canvas.Get(2, 2); c.foregroundColor != "blue" {
+		t.Errorf("pasted color = %q, want blue", c.foregroundColor)
+	}
+
+	pressKeys(m, "enter")
+	if len(m.history) != steps+1 || m.history[m.historyIndex].label != "Paste" {
+		t.Error("putting pasted text down should record one Paste step")
+	}
+	if m.clipboard.width != 5 || m.clipboard.height != 2 {
+		t.Errorf("clipboard is %dx%d, want 5x2", m.clipboard.width, m.clipboard.height)
+	}
+}
+
+func TestPasteIntoColorInput(t *testing.T) {
+	m := newColorPickerModel()
+	m.openMenu(menuForeground)
+	m.openExtendedColorPicker()
+	m.handleKey(keyMsg("tab"))
+
+	m.handleKey(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("#ff8800"), Paste: true})
+	if m.floating.active {
+		t.Error("a paste into the color input shouldn't float on the canvas")
+	}
+	if m.colorInput != "#ff8800" {
+		t.Errorf("colorInput = %q, want the pasted hex value", m.colorInput)
+	}
+}
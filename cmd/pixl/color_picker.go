@@ -0,0 +1,455 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+const (
+	maxRecentColors   = 9
+	colorCubeColumns  = 18 // Three 6x6 cube blocks side by side
+	colorGrayColumns  = 12
+	colorSwatchWidth  = 2
+	colorInputLabel   = " Hex/RGB/HSV: "
+	colorPickerMore   = "More colors"
+	colorPickerMoreID = -2 // colorPickerClickIndex result for the "More colors" row
+)
+
+// xtermSystemColors approximates the 16 system colors for RGB/HSV display.
+var xtermSystemColors = [16][3]int{
+	{0, 0, 0}, {205, 0, 0}, {0, 205, 0}, {205, 205, 0},
+	{0, 0, 238}, {205, 0, 205}, {0, 205, 205}, {229, 229, 229},
+	{127, 127, 127}, {255, 0, 0}, {0, 255, 0}, {255, 255, 0},
+	{92, 92, 255}, {255, 0, 255}, {0, 255, 255}, {255, 255, 255},
+}
+
+var colorCubeLevels = [6]int{0, 95, 135, 175, 215, 255}
+
+// colorRGB returns the RGB value of a canvas color. Named and 256-color
+// values use the standard xterm palette.
+func colorRGB(name string) (r, g, b int, ok bool) {
+	if r, g, b, ok := hexRGB(name); ok {
+		return r, g, b, true
+	}
+	n, ok := ansi256Index(name)
+	if !ok {
+		idx := -1
+		for i, c := range colors[1:] {
+			if c.name == normalizeColorName(name) {
+				idx = i
+				break
+			}
+		}
+		if idx < 0 {
+			return 0, 0, 0, false
+		}
+		n = idx
+	}
+	switch {
+	case n < 16:
+		c := xtermSystemColors[n]
+		return c[0], c[1], c[2], true
+	case n < 232:
+		n -= 16
+		return colorCubeLevels[n/36], colorCubeLevels[n/6%6], colorCubeLevels[n%6], true
+	default:
+		v := 8 + (n-232)*10
+		return v, v, v, true
+	}
+}
+
+func rgbToHSV(r, g, b int) (h, s, v int) {
+	rf, gf, bf := float64(r)/255, float64(g)/255, float64(b)/255
+	maxC := math.Max(rf, math.Max(gf, bf))
+	minC := math.Min(rf, math.Min(gf, bf))
+	d := maxC - minC
+
+	var hf float64
+	switch {
+	case d == 0:
+		hf = 0
+	case maxC == rf:
+		hf = math.Mod((gf-bf)/d, 6)
+	case maxC == gf:
+		hf = (bf-rf)/d + 2
+	default:
+		hf = (rf-gf)/d + 4
+	}
+	hf *= 60
+	if hf < 0 {
+		hf += 360
+	}
+	sf := 0.0
+	if maxC > 0 {
+		sf = d / maxC
+	}
+	return int(math.Round(hf)) % 360, int(math.Round(sf * 100)), int(math.Round(maxC * 100))
+}
+
+func hsvToRGB(h, s, v float64) (r, g, b int) {
+	h = math.Mod(h, 360)
+	s /= 100
+	v /= 100
+	c := v * s
+	x := c * (1 - math.Abs(math.Mod(h/60, 2)-1))
+	m := v - c
+
+	var rf, gf, bf float64
+	switch {
+	case h < 60:
+		rf, gf, bf = c, x, 0
+	case h < 120:
+		rf, gf, bf = x, c, 0
+	case h < 180:
+		rf, gf, bf = 0, c, x
+	case h < 240:
+		rf, gf, bf = 0, x, c
+	case h < 300:
+		rf, gf, bf = x, 0, c
+	default:
+		rf, gf, bf = c, 0, x
+	}
+	to8 := func(f float64) int { return int(math.Round((f + m) * 255)) }
+	return to8(rf), to8(gf), to8(bf)
+}
+
+// parseColorInput parses the extended picker's input field. It accepts
+// "#rrggbb", "#rgb", "rgb(r,g,b)", "r,g,b", "hsv(h,s,v)" with s and v in
+// percent, and anything isValidCanvasColor accepts.
+func parseColorInput(s string) (string, bool) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if s == "" {
+		return "", false
+	}
+	if isValidCanvasColor(s) {
+		return canonicalColor(s), true
+	}
+
+	hex := strings.TrimPrefix(s, "#")
+	if len(hex) == 3 {
+		hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+	}
+	if r, g, b, ok := hexRGB("#" + hex); ok {
+		return hexColor(r, g, b), true
+	}
+
+	isHSV := strings.HasPrefix(s, "hsv")
+	s = strings.TrimPrefix(strings.TrimPrefix(s, "hsv"), "rgb")
+	s = strings.TrimSuffix(strings.TrimPrefix(strings.TrimSpace(s), "("), ")")
+	fields := strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == ' ' })
+	if len(fields) != 3 {
+		return "", false
+	}
+	var vals [3]float64
+	for i, f := range fields {
+		v, err := strconv.ParseFloat(strings.TrimSuffix(f, "%"), 64)
+		if err != nil || v < 0 {
+			return "", false
+		}
+		vals[i] = v
+	}
+
+	if isHSV {
+		if vals[0] > 360 || vals[1] > 100 || vals[2] > 100 {
+			return "", false
+		}
+		return hexColor(hsvToRGB(vals[0], vals[1], vals[2])), true
+	}
+	for _, v := range vals {
+		if v > 255 || v != math.Trunc(v) {
+			return "", false
+		}
+	}
+	return hexColor(int(vals[0]), int(vals[1]), int(vals[2])), true
+}
+
+// colorPickerGrid returns the swatch rows of the extended picker: recent
+// colors (when there are any), the 16 named colors, the 6x6x6 color cube as
+// two rows of three 6x6 blocks, and the 24-step grayscale ramp.
+func (m *model) colorPickerGrid() [][]string {
+	var grid [][]string
+	if len(m.recentColors) > 0 {
+		grid = append(grid, m.recentColors)
+	}
+
+	named := make([]string, 0, 16)
+	for _, c := range colors[1:] {
+		named = append(named, c.name)
+	}
+	grid = append(grid, named)
+
+	for y := 0; y < 12; y++ {
+		row := make([]string, colorCubeColumns)
+		for x := range row {
+			r := (y/6)*3 + x/6
+			row[x] = strconv.Itoa(16 + 36*r + 6*(y%6) + x%6)
+		}
+		grid = append(grid, row)
+	}
+
+	for y := 0; y < 2; y++ {
+		row := make([]string, colorGrayColumns)
+		for x := range row {
+			row[x] = strconv.Itoa(232 + y*colorGrayColumns + x)
+		}
+		grid = append(grid, row)
+	}
+	return grid
+}
+
+// colorPickerLines maps each content line of the extended picker to a grid
+// row, or -1 for spacer lines. Two info lines and the input line follow.
+func (m *model) colorPickerLines() []int {
+	var lines []int
+	row := 0
+	if len(m.recentColors) > 0 {
+		lines = append(lines, row, -1)
+		row++
+	}
+	lines = append(lines, row, -1) // named colors
+	row++
+	for i := 0; i < 12; i++ {
+		lines = append(lines, row)
+		row++
+	}
+	lines = append(lines, -1, row, row+1)
+	return lines
+}
+
+func (m *model) pickerColor() string {
+	if m.showBgPicker {
+		return m.backgroundColor
+	}
+	return m.foregroundColor
+}
+
+func (m *model) setPickerColor(name string) {
+	if m.showBgPicker {
+		m.backgroundColor = name
+	} else {
+		m.foregroundColor = name
+	}
+}
+
+func (m *model) addRecentColor(name string) {
+	if name == "" || name == "transparent" {
+		return
+	}
+	recent := []string{name}
+	for _, c := range m.recentColors {
+		if c != name && len(recent) < maxRecentColors {
+			recent = append(recent, c)
+		}
+	}
+	m.recentColors = recent
+}
+
+// openExtendedColorPicker switches the open color menu to the extended
+// picker with the cursor on the current color.
+func (m *model) openExtendedColorPicker() {
+	m.colorPickerExtended = true
+	m.colorInputFocused = false
+	m.colorInput = ""
+	m.colorInputInvalid = false
+	m.locateColorCursor(m.pickerColor())
+}
+
+// locateColorCursor moves the grid cursor onto name, preferring its fixed
+// position over the recent row, which reorders as colors are picked.
+func (m *model) locateColorCursor(name string) {
+	grid := m.colorPickerGrid()
+	recentRows := 0
+	if len(m.recentColors) > 0 {
+		recentRows = 1
+	}
+	for y := recentRows; y < len(grid); y++ {
+		for x, c := range grid[y] {
+			if c == name {
+				m.colorGridRow, m.colorGridCol = y, x
+				return
+			}
+		}
+	}
+	for x, c := range m.recentColors {
+		if c == name {
+			m.colorGridRow, m.colorGridCol = 0, x
+			return
+		}
+	}
+	m.colorGridRow, m.colorGridCol = recentRows, 0
+}
+
+func (m *model) moveColorCursor(dy, dx int) {
+	grid := m.colorPickerGrid()
+	m.colorGridRow = max(0, min(len(grid)-1, m.colorGridRow+dy))
+	row := grid[m.colorGridRow]
+	m.colorGridCol = max(0, min(len(row)-1, m.colorGridCol+dx))
+	m.setPickerColor(row[m.colorGridCol])
+}
+
+func (m *model) commitPickerColor(name string) {
+	m.setPickerColor(name)
+	m.addRecentColor(name)
+	m.locateColorCursor(name)
+}
+
+// handleColorPickerKey handles keys while the extended picker is open. It
+// returns false for keys the picker doesn't use so normal handling applies.
+func (m *model) handleColorPickerKey(msg tea.KeyMsg) bool {
+	if m.colorInputFocused {
+		switch msg.Type {
+		case tea.KeyCtrlC:
+			return false
+		case tea.KeyTab, tea.KeyEscape:
+			m.colorInputFocused = false
+		case tea.KeyEnter:
+			name, ok := parseColorInput(m.colorInput)
+			if !ok {
+				m.colorInputInvalid = true
+				return true
+			}
+			m.commitPickerColor(name)
+			m.closeMenus()
+		case tea.KeyBackspace:
+			if len(m.colorInput) > 0 {
+				runes := []rune(m.colorInput)
+				m.colorInput = string(runes[:len(runes)-1])
+			}
+			m.colorInputInvalid = false
+		case tea.KeySpace:
+			m.colorInput += " "
+			m.colorInputInvalid = false
+		case tea.KeyRunes:
+			m.colorInput += string(msg.Runes)
+			m.colorInputInvalid = false
+		}
+		return true
+	}
+
+	switch msg.String() {
+	case "tab", "#":
+		m.colorInputFocused = true
+		if msg.String() == "#" {
+			m.colorInput = "#"
+		}
+	case "esc":
+		m.colorPickerExtended = false
+	case "enter", " ":
+		grid := m.colorPickerGrid()
+		m.commitPickerColor(grid[m.colorGridRow][m.colorGridCol])
+		m.closeMenus()
+	case "up":
+		m.moveColorCursor(-1, 0)
+	case "down":
+		m.moveColorCursor(1, 0)
+	case "left":
+		m.moveColorCursor(0, -1)
+	case "right":
+		m.moveColorCursor(0, 1)
+	case "1", "2", "3", "4", "5", "6", "7", "8", "9":
+		idx := int(msg.String()[0] - '1')
+		if idx < len(m.recentColors) {
+			m.setPickerColor(m.recentColors[idx])
+			m.colorGridRow, m.colorGridCol = 0, idx
+		}
+	default:
+		return false
+	}
+	return true
+}
+
+// extendedColorPickerClick handles a click inside the extended picker and
+// reports whether the click landed on it.
+func (m *model) extendedColorPickerClick(msg tea.MouseMsg, itemX int) bool {
+	popupLines := strings.Split(m.renderExtendedColorPicker(), "\n")
+	left := itemX - pickerContentOffset
+	top := controlBarHeight
+	if msg.Y < top || msg.Y >= top+len(popupLines) || len(popupLines) == 0 ||
+		msg.X < left || msg.X >= left+lipgloss.Width(popupLines[0]) {
+		return false
+	}
+
+	lines := m.colorPickerLines()
+	line := msg.Y - top - 1
+	switch {
+	case line >= 0 && line < len(lines) && lines[line] >= 0:
+		grid := m.colorPickerGrid()
+		row := grid[lines[line]]
+		col := (msg.X - left - pickerContentOffset) / colorSwatchWidth
+		if msg.X-left-pickerContentOffset >= 0 && col < len(row) {
+			m.colorGridRow, m.colorGridCol = lines[line], col
+			m.colorInputFocused = false
+			m.commitPickerColor(row[col])
+		}
+	case line == len(lines)+2:
+		m.colorInputFocused = true
+	}
+	return true
+}
+
+func (m *model) renderExtendedColorPicker() string {
+	pickerStyle := lipgloss.NewStyle().
+		Border(lipgloss.NormalBorder()).
+		BorderForeground(themeColor(m.config.Theme.MenuBorder))
+
+	cursorBg := themeColor(m.config.Theme.MenuSelectedBg)
+	if m.colorInputFocused {
+		cursorBg = themeColor(m.config.Theme.MenuUnfocusedBg)
+	}
+
+	grid := m.colorPickerGrid()
+	var lines []string
+	for _, gridRow := range m.colorPickerLines() {
+		if gridRow < 0 {
+			lines = append(lines, "")
+			continue
+		}
+		var line strings.Builder
+		line.WriteString(" ")
+		for x, c := range grid[gridRow] {
+			style := colorStyleByName(c)
+			if gridRow == m.colorGridRow && x == m.colorGridCol {
+				line.WriteString(style.Background(cursorBg).Render("▐▌"))
+			} else {
+				line.WriteString(style.Render("██"))
+			}
+		}
+		lines = append(lines, line.String())
+	}
+
+	current := m.pickerColor()
+	info := " " + colorStyleByName(current).Render("██") + " " + colorDisplayName(current)
+	values := ""
+	if r, g, b, ok := colorRGB(current); ok && current != "transparent" {
+		h, s, v := rgbToHSV(r, g, b)
+		info += "  " + strings.ToUpper(hexColor(r, g, b))
+		values = fmt.Sprintf(" rgb(%d,%d,%d)  hsv(%d,%d,%d)", r, g, b, h, s, v)
+	}
+	lines = append(lines, info, values)
+
+	input := colorInputLabel + m.colorInput
+	if m.colorInputFocused {
+		input += "_"
+	}
+	if m.colorInputInvalid {
+		input = lipgloss.NewStyle().Foreground(lipgloss.Color("1")).Render(input)
+	} else if !m.colorInputFocused {
+		input = lipgloss.NewStyle().Faint(true).Render(input + "(tab)")
+	}
+	lines = append(lines, input)
+
+	width := 0
+	for _, l := range lines {
+		width = max(width, lipgloss.Width(l))
+	}
+	for i, l := range lines {
+		lines[i] = l + strings.Repeat(" ", width+1-lipgloss.Width(l))
+	}
+
+	return pickerStyle.Render(strings.Join(lines, "\n"))
+}
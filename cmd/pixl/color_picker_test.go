@@ -0,0 +1,260 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func newColorPickerModel() *model {
+	m := initialModel()
+	m.width = 120
+	m.height = 40
+	m.config.Theme = defaultTheme()
+	m.toolbar = toolbarLayout{foregroundItemX: 13, backgroundItemX: 40}
+	return m
+}
+
+func TestParseColorInput(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+		ok   bool
+	}{
+		{"#FF8800", "#ff8800", true},
+		{"ff8800", "#ff8800", true},
+		{"#f80", "#ff8800", true},
+		{"rgb(255, 136, 0)", "#ff8800", true},
+		{"255,136,0", "#ff8800", true},
+		{"255 136 0", "#ff8800", true},
+		{"hsv(0,100,100)", "#ff0000", true},
+		{"hsv(120, 100%, 50%)", "#008000", true},
+		{"208", "208", true},
+		{"bright-red", "bright-red", true},
+		{"rgb(256,0,0)", "", false},
+		{"hsv(400,0,0)", "", false},
+		{"1,2", "", false},
+		{"#12345", "", false},
+		{"", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := parseColorInput(tt.in)
+		if ok != tt.ok || got != tt.want {
+			t.Errorf("parseColorInput(%q) = %q, %v, want %q, %v", tt.in, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestColorRGB(t *testing.T) {
+	tests := []struct {
+		name    string
+		r, g, b int
+	}{
+		{"red", 205, 0, 0},
+		{"bright_white", 255, 255, 255},
+		{"16", 0, 0, 0},
+		{"196", 255, 0, 0},
+		{"232", 8, 8, 8},
+		{"255", 238, 238, 238},
+		{"#0891b2", 8, 145, 178},
+	}
+	for _, tt := range tests {
+		r, g, b, ok := colorRGB(tt.name)
+		if !ok || r != tt.r || g != tt.g || b != tt.b {
+			t.Errorf("colorRGB(%q) = %d,%d,%d,%v want %d,%d,%d", tt.name, r, g, b, ok, tt.r, tt.g, tt.b)
+		}
+	}
+	if _, _, _, ok := colorRGB("transparent"); ok {
+		t.Error("colorRGB(transparent) should not be ok")
+	}
+}
+
+func TestHSVRoundTrip(t *testing.T) {
+	h, s, v := rgbToHSV(255, 136, 0)
+	if h != 32 || s != 100 || v != 100 {
+		t.Errorf("rgbToHSV(255,136,0) = %d,%d,%d want 32,100,100", h, s, v)
+	}
+	r, g, b := hsvToRGB(float64(h), float64(s), float64(v))
+	if r != 255 || g != 136 || b != 0 {
+		t.Errorf("hsvToRGB(32,100,100) = %d,%d,%d want 255,136,0", r, g, b)
+	}
+}
+
+func TestColorPickerGridCoversAll256(t *testing.T) {
+	m := newColorPickerModel()
+	seen := map[string]bool{}
+	for _, row := range m.colorPickerGrid() {
+		for _, c := range row {
+			seen[c] = true
+		}
+	}
+	if len(seen) != 256 {
+		t.Errorf("grid has %d distinct colors, want 256", len(seen))
+	}
+	for i := 16; i < 256; i++ {
+		if !seen[strconv.Itoa(i)] {
+			t.Errorf("grid missing color %d", i)
+		}
+	}
+}
+
+func TestTabOpensExtendedPicker(t *testing.T) {
+	m := newColorPickerModel()
+	m.handleKey(keyMsg("f"))
+	m.handleKey(keyMsg("tab"))
+
+	if !m.colorPickerExtended {
+		t.Fatal("tab in the foreground menu should open the extended picker")
+	}
+	if !strings.Contains(m.renderColorPicker("Foreground"), "Hex/RGB/HSV") {
+		t.Error("extended picker should render the input field")
+	}
+
+	m.handleKey(keyMsg("esc"))
+	if m.colorPickerExtended || !m.showFgPicker {
+		t.Error("esc should return to the simple picker")
+	}
+}
+
+func TestExtendedPickerArrowsAndEnter(t *testing.T) {
+	m := newColorPickerModel()
+	m.handleKey(keyMsg("b"))
+	m.handleKey(keyMsg("tab"))
+
+	m.handleKey(keyMsg("down")) // into the color cube
+	m.handleKey(keyMsg("right"))
+	if m.backgroundColor != "17" {
+		t.Fatalf("backgroundColor = %q, want 17", m.backgroundColor)
+	}
+
+	m.handleKey(keyMsg("enter"))
+	if m.activeMenu() != -1 {
+		t.Error("enter should close the picker")
+	}
+	if len(m.recentColors) != 1 || m.recentColors[0] != "17" {
+		t.Errorf("recentColors = %v, want [17]", m.recentColors)
+	}
+	if m.foregroundColor != "white" {
+		t.Errorf("foregroundColor = %q, should be unchanged", m.foregroundColor)
+	}
+}
+
+func TestExtendedPickerInputField(t *testing.T) {
+	m := newColorPickerModel()
+	m.handleKey(keyMsg("f"))
+	m.handleKey(keyMsg("tab"))
+	m.handleKey(keyMsg("tab"))
+	if !m.colorInputFocused {
+		t.Fatal("second tab should focus the input field")
+	}
+
+	for _, k := range []string{"z", "z"} {
+		m.handleKey(keyMsg(k))
+	}
+	m.handleKey(keyMsg("enter"))
+	if !m.colorInputInvalid || m.foregroundColor != "white" {
+		t.Fatalf("invalid input should be flagged, fg = %q", m.foregroundColor)
+	}
+
+	m.handleKey(keyMsg("backspace"))
+	m.handleKey(keyMsg("backspace"))
+	for _, k := range []string{"#", "f", "0", "0"} {
+		m.handleKey(keyMsg(k))
+	}
+	m.handleKey(keyMsg("enter"))
+	if m.foregroundColor != "#ff0000" {
+		t.Errorf("foregroundColor = %q, want #ff0000", m.foregroundColor)
+	}
+	if m.activeMenu() != -1 {
+		t.Error("applying input should close the picker")
+	}
+}
+
+func TestExtendedPickerHashFocusesInput(t *testing.T) {
+	m := newColorPickerModel()
+	m.handleKey(keyMsg("f"))
+	m.handleKey(keyMsg("tab"))
+	m.handleKey(keyMsg("#"))
+	if !m.colorInputFocused || m.colorInput != "#" {
+		t.Errorf("# should focus input with %q, got focused=%v input=%q", "#", m.colorInputFocused, m.colorInput)
+	}
+}
+
+func TestExtendedPickerInputLetsCtrlCQuit(t *testing.T) {
+	m := newColorPickerModel()
+	m.handleKey(keyMsg("f"))
+	m.handleKey(keyMsg("tab"))
+	m.handleKey(keyMsg("#"))
+	if _, cmd := m.handleKey(tea.KeyMsg{Type: tea.KeyCtrlC}); !isQuit(cmd) {
+		t.Error("ctrl+c should quit while the color input is focused")
+	}
+}
+
+func TestExtendedPickerRecentNumberKeys(t *testing.T) {
+	m := newColorPickerModel()
+	m.recentColors = []string{"#123456", "42"}
+	m.handleKey(keyMsg("f"))
+	m.handleKey(keyMsg("tab"))
+	m.handleKey(keyMsg("2"))
+	if m.foregroundColor != "42" {
+		t.Errorf("foregroundColor = %q, want 42", m.foregroundColor)
+	}
+}
+
+func TestAddRecentColor(t *testing.T) {
+	m := newColorPickerModel()
+	for i := 0; i < maxRecentColors+2; i++ {
+		m.addRecentColor(strconv.Itoa(100 + i))
+	}
+	m.addRecentColor("transparent")
+	m.addRecentColor("102")
+
+	if len(m.recentColors) != maxRecentColors {
+		t.Fatalf("len(recentColors) = %d, want %d", len(m.recentColors), maxRecentColors)
+	}
+	if m.recentColors[0] != "102" || m.recentColors[1] != "110" {
+		t.Errorf("recentColors = %v, want 102 moved to front", m.recentColors)
+	}
+}
+
+func TestExtendedPickerClickSwatch(t *testing.T) {
+	m := newColorPickerModel()
+	m.openMenu(menuForeground)
+	m.openExtendedColorPicker()
+
+	left := m.toolbar.foregroundItemX - pickerContentOffset
+	// Line 0 holds the named colors; the second swatch is red
+	msg := tea.MouseMsg{X: left + pickerContentOffset + colorSwatchWidth, Y: controlBarHeight + 1, Type: tea.MouseLeft}
+	m.handleMouse(msg)
+
+	if m.foregroundColor != "red" {
+		t.Errorf("foregroundColor = %q, want red", m.foregroundColor)
+	}
+	if !m.showFgPicker || !m.colorPickerExtended {
+		t.Error("clicking a swatch should keep the picker open")
+	}
+	if len(m.recentColors) != 1 || m.recentColors[0] != "red" {
+		t.Errorf("recentColors = %v, want [red]", m.recentColors)
+	}
+	// The new recent row shifts the grid; the cursor should follow red
+	grid := m.colorPickerGrid()
+	if grid[m.colorGridRow][m.colorGridCol] != "red" || m.colorGridRow == 0 {
+		t.Errorf("cursor at (%d,%d), want red in the named row", m.colorGridRow, m.colorGridCol)
+	}
+}
+
+func TestSimplePickerMoreRowOpensExtended(t *testing.T) {
+	m := newColorPickerModel()
+	m.openMenu(menuBackground)
+
+	msg := tea.MouseMsg{X: m.toolbar.backgroundItemX, Y: controlBarHeight + 1 + len(colors), Type: tea.MouseLeft}
+	m.handleMouse(msg)
+	if !m.colorPickerExtended {
+		t.Error("clicking More colors should open the extended picker")
+	}
+	if m.backgroundColor != "transparent" {
+		t.Errorf("backgroundColor = %q, should be unchanged", m.backgroundColor)
+	}
+}
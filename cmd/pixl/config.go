@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 )
 
@@ -15,13 +16,21 @@ type Config struct {
 	DefaultBackground string
 	DefaultTool       string
 	DefaultBoxStyle   string
+	Author            string
+	UndoLimit         int
+	UndoMemoryMB      int
+	AutosaveInterval  int
+	Backup            string
+	Clipboard         string
 	Theme             Theme
+	Keymap            map[string]string
 	Warnings          []string
 }
 
 func loadConfig() Config {
 	c := Config{
 		MergeBoxBorders: true,
+		Clipboard:       clipboardText,
 		Theme:           defaultTheme(),
 	}
 
@@ -36,6 +45,7 @@ func loadConfig() Config {
 	}
 	defer f.Close()
 
+	var bindings []keyBinding
 	scanner := bufio.NewScanner(f)
 	for scanner.Scan() {
 		line := strings.TrimSpace(scanner.Text())
@@ -61,13 +71,13 @@ func loadConfig() Config {
 			}
 		case "default-foreground":
 			if isValidCanvasColor(val) {
-				c.DefaultForeground = val
+				c.DefaultForeground = canonicalColor(val)
 			} else {
 				c.Warnings = append(c.Warnings, fmt.Sprintf("invalid color %q for %s", val, key))
 			}
 		case "default-background":
 			if isValidCanvasColor(val) {
-				c.DefaultBackground = val
+				c.DefaultBackground = canonicalColor(val)
 			} else {
 				c.Warnings = append(c.Warnings, fmt.Sprintf("invalid color %q for %s", val, key))
 			}
@@ -83,7 +93,59 @@ func loadConfig() Config {
 			} else {
 				c.Warnings = append(c.Warnings, fmt.Sprintf("invalid box style %q for %s", val, key))
 			}
+		case "author":
+			c.Author = val
+		case "undo-limit":
+			if val == "unlimited" || val == "0" {
+				c.UndoLimit = -1
+			} else if n, err := strconv.Atoi(val); err == nil && n > 1 {
+				c.UndoLimit = n
+			} else {
+				c.Warnings = append(c.Warnings, fmt.Sprintf("%s must be a number above 1 or \"unlimited\", got %q", key, val))
+			}
+		case "undo-memory":
+			if n, err := strconv.Atoi(val); err == nil && n > 0 {
+				c.UndoMemoryMB = n
+			} else {
+				c.Warnings = append(c.Warnings, fmt.Sprintf("%s must be a positive number of megabytes, got %q", key, val))
+			}
+		case "autosave-interval":
+			if val == "off" || val == "0" {
+				c.AutosaveInterval = -1
+			} else if n, err := strconv.Atoi(val); err == nil && n > 0 {
+				c.AutosaveInterval = n
+			} else {
+				c.Warnings = append(c.Warnings, fmt.Sprintf("%s must be a number of seconds or \"off\", got %q", key, val))
+			}
+		case "backup":
+			switch val {
+			case "true", backupSimple:
+				c.Backup = backupSimple
+			case backupNumbered:
+				c.Backup = backupNumbered
+			case "false", "off":
+				c.Backup = ""
+			default:
+				c.Warnings = append(c.Warnings, fmt.Sprintf("%s must be true, false or numbered, got %q", key, val))
+			}
+		case "clipboard":
+			switch val {
+			case clipboardText, clipboardANSI:
+				c.Clipboard = val
+			case "false", "off":
+				c.Clipboard = ""
+			default:
+				c.Warnings = append(c.Warnings, fmt.Sprintf("%s must be text, ansi or off, got %q", key, val))
+			}
 		default:
+			if action, ok := strings.CutPrefix(key, "bind."); ok {
+				if isValidAction(action) {
+					bindings = append(bindings, keyBinding{action, parseBindingKeys(val)})
+				} else {
+					c.Warnings = append(c.Warnings, fmt.Sprintf("unknown action %q for %s", action, key))
+				}
+				continue
+			}
 			ptr := c.Theme.field(key)
 			if ptr == nil {
 				c.Warnings = append(c.Warnings, fmt.Sprintf("unknown config key %q", key))
@@ -95,6 +157,10 @@ func loadConfig() Config {
 		}
 	}
 
+	keymap, warnings := resolveKeymap(bindings)
+	c.Keymap = keymap
+	c.Warnings = append(c.Warnings, warnings...)
+
 	return c
 }
 
@@ -100,6 +100,54 @@ func TestLoadConfigDefaultBoxStyle(t *testing.T) {
 	}
 }
 
+func TestLoadConfigExtendedCanvasColors(t *testing.T) {
+	writeTestConfig(t, "default-foreground = #FF8800\ndefault-background = 017\n")
+
+	c := loadConfig()
+	if c.DefaultForeground != "#ff8800" {
+		t.Errorf("DefaultForeground = %q, want #ff8800", c.DefaultForeground)
+	}
+	if c.DefaultBackground != "17" {
+		t.Errorf("DefaultBackground = %q, want 17", c.DefaultBackground)
+	}
+	if len(c.Warnings) != 0 {
+		t.Errorf("unexpected warnings: %v", c.Warnings)
+	}
+}
+
+func TestIsValidCanvasColor(t *testing.T) {
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{"red", true},
+		{"bright-red", true},
+		{"transparent", true},
+		{"0", true},
+		{"255", true},
+		{"256", false},
+		{"-1", false},
+		{"#A1b2C3", true},
+		{"#abc", false},
+		{"#gggggg", false},
+		{"nonexistent", false},
+	}
+	for _, tt := range tests {
+		if got := isValidCanvasColor(tt.name); got != tt.want {
+			t.Errorf("isValidCanvasColor(%q) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestLoadConfigAuthor(t *testing.T) {
+	writeTestConfig(t, "author = Ann Example\n")
+
+	c := loadConfig()
+	if c.Author != "Ann Example" {
+		t.Errorf("Author = %q, want %q", c.Author, "Ann Example")
+	}
+}
+
 func TestConfigAppliedToModel(t *testing.T) {
 	m := initialModel()
 	m.config = Config{
@@ -358,3 +406,104 @@ func TestLoadConfigThemePartialOverride(t *testing.T) {
 		t.Errorf("MenuBorder = %q, want bright-blue (default)", c.Theme.MenuBorder)
 	}
 }
+
+func TestLoadConfigBindings(t *testing.T) {
+	writeTestConfig(t, "bind.undo = ctrl+z\nbind.toggle-bold = B\nbind.paste = none\n")
+
+	c := loadConfig()
+	if len(c.Warnings) != 0 {
+		t.Errorf("unexpected warnings: %v", c.Warnings)
+	}
+	if c.Keymap["ctrl+z"] != "undo" || c.Keymap["B"] != "toggle-bold" {
+		t.Errorf("keymap missing configured keys: %v", c.Keymap)
+	}
+	if _, ok := c.Keymap["u"]; ok {
+		t.Error("rebinding undo should drop its default key")
+	}
+	if _, ok := c.Keymap["p"]; ok {
+		t.Error("bind.paste = none should unbind p")
+	}
+	if c.Keymap["r"] != "redo" {
+		t.Error("unconfigured actions should keep their default keys")
+	}
+}
+
+func TestLoadConfigBindingWarnings(t *testing.T) {
+	writeTestConfig(t, "bind.launch-rockets = z\nbind.redo = u\nbind.copy = q\n")
+
+	c := loadConfig()
+	if len(c.Warnings) != 3 {
+		t.Fatalf("warnings = %v, want 3", c.Warnings)
+	}
+	if c.Keymap["u"] != "redo" {
+		t.Errorf("configured key should win over a default, u = %q", c.Keymap["u"])
+	}
+	if _, ok := c.Keymap["q"]; ok {
+		t.Error("reserved keys should not be bound")
+	}
+}
+
+func TestLoadConfigUndoLimit(t *testing.T) {
+	writeTestConfig(t, "undo-limit = 500\nundo-memory = 16\n")
+	c := loadConfig()
+	if c.UndoLimit != 500 || c.UndoMemoryMB != 16 {
+		t.Errorf("UndoLimit = %d UndoMemoryMB = %d, want 500 and 16", c.UndoLimit, c.UndoMemoryMB)
+	}
+
+	writeTestConfig(t, "undo-limit = unlimited\n")
+	if c := loadConfig(); c.UndoLimit != -1 {
+		t.Errorf("unlimited UndoLimit = %d, want -1", c.UndoLimit)
+	}
+
+	writeTestConfig(t, "undo-limit = lots\nundo-memory = -3\n")
+	if c := loadConfig(); len(c.Warnings) != 2 || c.UndoLimit != 0 || c.UndoMemoryMB != 0 {
+		t.Errorf("invalid values should warn and keep defaults, got %+v", c.Warnings)
+	}
+}
+
+func TestLoadConfigAutosaveInterval(t *testing.T) {
+	writeTestConfig(t, "autosave-interval = 90\n")
+	if c := loadConfig(); c.AutosaveInterval != 90 {
+		t.Errorf("AutosaveInterval = %d, want 90", c.AutosaveInterval)
+	}
+
+	writeTestConfig(t, "autosave-interval = off\n")
+	if c := loadConfig(); c.AutosaveInterval != -1 {
+		t.Errorf("off AutosaveInterval = %d, want -1", c.AutosaveInterval)
+	}
+
+	writeTestConfig(t, "autosave-interval = soon\n")
+	if c := loadConfig(); len(c.Warnings) != 1 || c.AutosaveInterval != 0 {
+		t.Errorf("invalid value should warn and keep the default, got %+v", c.Warnings)
+	}
+}
+
+func TestLoadConfigBackup(t *testing.T) {
+	for val, want := range map[string]string{"true": backupSimple, "numbered": backupNumbered, "false": ""} {
+		writeTestConfig(t, "backup = "+val+"\n")
+		if c := loadConfig(); c.Backup != want || len(c.Warnings) != 0 {
+			t.Errorf("backup = %s: Backup = %q, want %q", val, c.Backup, want)
+		}
+	}
+	writeTestConfig(t, "backup = always\n")
+	if c := loadConfig(); len(c.Warnings) != 1 || c.Backup != "" {
+		t.Errorf("invalid value should warn, got %+v", c.Warnings)
+	}
+}
+
+func TestLoadConfigClipboard(t *testing.T) {
+	writeTestConfig(t, "")
+	if c := loadConfig(); c.Clipboard != clipboardText {
+		t.Errorf("default Clipboard = %q, want %q", c.Clipboard, clipboardText)
+	}
+	for val, want := range map[string]string{"text": clipboardText, "ansi": clipboardANSI, "off": ""} {
+		writeTestConfig(t, "clipboard = "+val+"\n")
+		if c := loadConfig(); c.Clipboard != want || len(c.Warnings) != 0 {
+			t.Errorf("clipboard = %s: Clipboard = %q, want %q", val, c.Clipboard, want)
+		}
+	}
+	writeTestConfig(t, "clipboard = html\n")
+	if c := loadConfig(); len(c.Warnings) != 1 || c.Clipboard != clipboardText {
+		t.Errorf("invalid value should warn and keep the default, got %+v", c.Warnings)
+	}
+}
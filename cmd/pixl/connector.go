@@ -0,0 +1,64 @@
+package main
+
+// arrowheads maps the direction a connector ends in to the glyph pointing
+// that way.
+var arrowheads = map[[2]int]string{
+	{-1, 0}: "▲",
+	{1, 0}:  "▼",
+	{0, -1}: "◀",
+	{0, 1}:  "▶",
+}
+
+// connectorElbow returns the glyphs showing which way the Connector tool
+// turns: along and then down for horizontal first, or down and then along.
+func (m *model) connectorElbow() string {
+	s := boxStyles[m.boxStyle]
+	if m.connectorVertical {
+		return s.v + s.bl
+	}
+	return s.h + s.tr
+}
+
+// connectorGlyphs returns the glyph for each cell of a connector between
+// two cells, in the current box style and joined to the box lines it
+// crosses, with the arrowheads that are turned on.
+func (m *model) connectorGlyphs(y1, x1, y2, x2 int) map[[2]int]string {
+	path := elbowPath([2]int{y1, x1}, [2]int{y2, x2}, !m.connectorVertical)
+	glyphs := m.boxPathGlyphs(path)
+	if m.arrowEnd {
+		m.addArrowhead(glyphs, path)
+	}
+	if m.arrowStart {
+		reversed := make([][2]int, len(path))
+		for i, p := range path {
+			reversed[len(path)-1-i] = p
+		}
+		m.addArrowhead(glyphs, reversed)
+	}
+	return glyphs
+}
+
+// addArrowhead points the last cell of a path onwards. A path ending on a
+// box line keeps the junction it makes there and has the arrowhead in the
+// cell before, pointing at the line.
+func (m *model) addArrowhead(glyphs map[[2]int]string, path [][2]int) {
+	if len(path) < 2 {
+		return
+	}
+	end, prev := len(path)-1, len(path)-2
+	s := boxStyles[m.boxStyle]
+	if existing := m.canvas.Get(path[end][0], path[end][1]); existing != nil && len(path) > 2 {
+		if _, _, _, _, ok := s.dirs(existing.char); ok {
+			end, prev = prev, prev-1
+		}
+	}
+	dir := [2]int{path[end][0] - path[prev][0], path[end][1] - path[prev][1]}
+	glyphs[path[end]] = arrowheads[dir]
+}
+
+// drawConnector draws a connector between two cells.
+func (m *model) drawConnector(y1, x1, y2, x2 int) {
+	for p, g := range m.connectorGlyphs(y1, x1, y2, x2) {
+		m.canvas.SetCell(p[0], p[1], m.brushCell(g))
+	}
+}
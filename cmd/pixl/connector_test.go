@@ -0,0 +1,69 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestConnectorElbow(t *testing.T) {
+	m := newDrawModel("Connector", 9, 7)
+	m.drawBox(2, 4, 5, 8)
+	m.saveToHistory()
+	drag(m, 0, 0, 1, 3)
+	checkCells(t, m, 9, 7, map[[2]int]string{{0, 0}: "─", {0, 2}: "─", {0, 3}: "┐", {1, 3}: "│", {1, 0}: " "})
+
+	pressKeys(m, "enter")
+	drag(m, 4, 0, 6, 2)
+	checkCells(t, m, 9, 7, map[[2]int]string{{4, 0}: "│", {5, 0}: "│", {6, 0}: "└", {6, 1}: "─", {6, 2}: "─"})
+}
+
+func TestConnectorJoinsBoxes(t *testing.T) {
+	m := newDrawModel("Connector", 9, 7)
+	m.drawBox(2, 4, 5, 8)
+	m.saveToHistory()
+	drag(m, 3, 0, 3, 4)
+	drag(m, 0, 6, 2, 6)
+	checkCells(t, m, 9, 7, map[[2]int]string{{3, 4}: "┤", {2, 6}: "┴", {2, 5}: "─", {3, 1}: "─"})
+}
+
+func TestConnectorArrowheads(t *testing.T) {
+	m := newDrawModel("Connector", 9, 7)
+	m.drawBox(2, 4, 5, 8)
+	m.saveToHistory()
+	m.runAction("arrow-at-start")
+	m.runAction("arrow-at-end")
+	drag(m, 4, 0, 4, 4)
+
+	// The end on the box border keeps its junction, with the arrowhead
+	// just before it
+	checkCells(t, m, 9, 7, map[[2]int]string{{4, 0}: "◀", {4, 1}: "─", {4, 3}: "▶", {4, 4}: "┤"})
+
+	m.runAction("arrow-at-start")
+	drag(m, 6, 3, 0, 0)
+	checkCells(t, m, 9, 7, map[[2]int]string{{6, 3}: "─", {6, 0}: "└", {0, 0}: "▲"})
+}
+
+func TestConnectorPreview(t *testing.T) {
+	m := newDrawModel("Connector", 9, 7)
+	m.drawBox(2, 4, 5, 8)
+	m.saveToHistory()
+	m.width = 120
+	m.runAction("arrow-at-end")
+	if bar := m.renderControlBar(); !strings.Contains(bar, "Connector ─┐▶") {
+		t.Error("the toolbar should show the elbow and arrowheads")
+	}
+
+	m.beginStroke(0, 0)
+	m.dragStroke(0, 4)
+	if rendered, ok := m.tool().RenderPreview(m, 0, 4); !ok || !strings.Contains(rendered, "▶") {
+		t.Errorf("preview at (0,4) = %q, want the arrowhead", rendered)
+	}
+	pressKeys(m, "enter")
+	m.dragStroke(2, 2)
+	if _, ok := m.tool().RenderPreview(m, 2, 0); !ok {
+		t.Error("changing the elbow should update the preview")
+	}
+	if m.canvas.Get(0, 0).char != " " {
+		t.Error("the preview shouldn't draw")
+	}
+}
@@ -0,0 +1,113 @@
+package main
+
+import tea "github.com/charmbracelet/bubbletea"
+
+// cursorMoves maps cursor-mode keys to a row/column step. Shifted keys extend
+// a drag, starting one at the cursor if no stroke is in progress.
+var cursorMoves = map[string]struct {
+	dy, dx int
+	drag   bool
+}{
+	"h": {0, -1, false}, "left": {0, -1, false},
+	"j": {1, 0, false}, "down": {1, 0, false},
+	"k": {-1, 0, false}, "up": {-1, 0, false},
+	"l": {0, 1, false}, "right": {0, 1, false},
+	"H": {0, -1, true}, "shift+left": {0, -1, true},
+	"J": {1, 0, true}, "shift+down": {1, 0, true},
+	"K": {-1, 0, true}, "shift+up": {-1, 0, true},
+	"L": {0, 1, true}, "shift+right": {0, 1, true},
+}
+
+// isCursorKey reports whether keyboard cursor mode takes a key before the
+// keymap does.
+func isCursorKey(key string) bool {
+	_, ok := cursorMoves[key]
+	return ok || key == " "
+}
+
+// toggleKeyboardCursor switches keyboard cursor mode. The cursor starts at
+// the hovered cell, or the top-left of the viewport if nothing is hovered.
+func (m *model) toggleKeyboardCursor() {
+	if m.keyboardCursor && m.mouseDown {
+		m.endStroke(m.hoverRow, m.hoverCol)
+	}
+	m.keyboardCursor = !m.keyboardCursor
+	if m.keyboardCursor && !m.cursorVisible {
+		m.hoverRow, m.hoverCol = m.clampToCanvas(m.scrollY, m.scrollX)
+		m.cursorVisible = true
+	}
+}
+
+// moveKeyboardCursor moves the cursor by a step, keeping it on the canvas
+// and scrolling it into view. An active stroke is dragged along. Tools
+// drawing on a grid of dots step by a dot.
+func (m *model) moveKeyboardCursor(dy, dx int) {
+	if m.toolGrid() != cellGrid {
+		m.moveGridCursor(dy, dx)
+	} else {
+		m.hoverRow, m.hoverCol = m.clampToCanvas(m.hoverRow+dy, m.hoverCol+dx)
+	}
+	m.scrollToCell(m.hoverRow, m.hoverCol)
+	m.cursorVisible = true
+	if m.mouseDown {
+		m.dragStroke(m.hoverRow, m.hoverCol)
+	}
+	m.trackPolyline(m.hoverRow, m.hoverCol)
+}
+
+// pressKeyboardCursor presses at the cursor the way a mouse click does. The
+// Text tool gets an immediate release, since typing takes over the keyboard,
+// and so does the Polyline tool, which places a vertex per click.
+func (m *model) pressKeyboardCursor() tea.Cmd {
+	started, cmd := m.beginStroke(m.hoverRow, m.hoverCol)
+	if !started {
+		return nil
+	}
+	m.dragStroke(m.hoverRow, m.hoverCol)
+	if m.selectedTool == "Text" || m.selectedTool == "Polyline" {
+		m.endStroke(m.hoverRow, m.hoverCol)
+	}
+	return cmd
+}
+
+// cancelKeyboardStroke abandons the stroke in progress, restoring the canvas
+// as it was before the press.
+func (m *model) cancelKeyboardStroke() {
+	m.canvas = m.canvasBeforeStroke.Copy()
+	m.mouseDown = false
+	m.showPreview = false
+	m.previewPoints = nil
+	m.previewFill = nil
+	m.previewGlyphs = nil
+}
+
+// handleCursorKey handles keys while keyboard cursor mode is on and no menu
+// is open. It returns false for keys that should fall through to the regular
+// key handling.
+func (m *model) handleCursorKey(msg tea.KeyMsg) (bool, tea.Cmd) {
+	key := msg.String()
+	if move, ok := cursorMoves[key]; ok {
+		if move.drag && !m.mouseDown {
+			if cmd := m.pressKeyboardCursor(); !m.mouseDown {
+				return true, cmd
+			}
+		}
+		m.moveKeyboardCursor(move.dy, move.dx)
+		return true, nil
+	}
+
+	switch key {
+	case " ":
+		if m.mouseDown {
+			m.endStroke(m.hoverRow, m.hoverCol)
+			return true, nil
+		}
+		return true, m.pressKeyboardCursor()
+	case "esc":
+		if m.mouseDown {
+			m.cancelKeyboardStroke()
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestToggleKeyboardCursor(t *testing.T) {
+	m := newDrawModel("Point", 10, 10)
+	m.handleKey(keyMsg("m"))
+	if !m.keyboardCursor || !m.cursorVisible {
+		t.Fatal("m should enable a visible keyboard cursor")
+	}
+	if m.hoverRow != 0 || m.hoverCol != 0 {
+		t.Errorf("cursor starts at (%d,%d), want (0,0)", m.hoverRow, m.hoverCol)
+	}
+	m.handleKey(keyMsg("m"))
+	if m.keyboardCursor {
+		t.Error("m again should leave cursor mode")
+	}
+}
+
+func TestKeyboardCursorMovesAndClamps(t *testing.T) {
+	m := newDrawModel("Point", 10, 10)
+	m.toggleKeyboardCursor()
+	pressKeys(m, "l", "l", "j", "right", "down", "k")
+	if m.hoverRow != 1 || m.hoverCol != 3 {
+		t.Errorf("cursor at (%d,%d), want (1,3)", m.hoverRow, m.hoverCol)
+	}
+	pressKeys(m, "k", "k", "h", "h", "h", "h", "left")
+	if m.hoverRow != 0 || m.hoverCol != 0 {
+		t.Errorf("cursor at (%d,%d), want clamped to (0,0)", m.hoverRow, m.hoverCol)
+	}
+	if m.mouseDown || m.canvas.Get(0, 0).char != " " {
+		t.Error("moving without pressing should not draw")
+	}
+}
+
+func TestKeyboardCursorRectangleMatchesMouse(t *testing.T) {
+	mouse := newDrawModel("Rectangle", 10, 10)
+	mouse.handleMouse(tea.MouseMsg{X: 1, Y: controlBarHeight + 1, Type: tea.MouseLeft})
+	mouse.handleMouse(tea.MouseMsg{X: 4, Y: controlBarHeight + 3, Type: tea.MouseMotion})
+
+	m := newDrawModel("Rectangle", 10, 10)
+	m.toggleKeyboardCursor()
+	pressKeys(m, "l", "j", " ", "l", "l", "l", "j", "j")
+
+	if !m.mouseDown || !m.showPreview {
+		t.Fatal("space should press and movement should drag a preview")
+	}
+	if m.previewEndX != mouse.previewEndX || m.previewEndY != mouse.previewEndY ||
+		m.startX != mouse.startX || m.startY != mouse.startY {
+		t.Errorf("keyboard preview %d,%d-%d,%d, mouse %d,%d-%d,%d",
+			m.startX, m.startY, m.previewEndX, m.previewEndY,
+			mouse.startX, mouse.startY, mouse.previewEndX, mouse.previewEndY)
+	}
+	if got, want := m.View(), mouse.View(); got != want {
+		t.Error("keyboard drag should render the same preview as a mouse drag")
+	}
+
+	m.handleKey(keyMsg(" "))
+	if m.mouseDown {
+		t.Fatal("second space should release")
+	}
+	for _, pos := range [][2]int{{1, 1}, {1, 4}, {3, 1}, {3, 4}} {
+		if cell := m.canvas.Get(pos[0], pos[1]); cell.char != "#" {
+			t.Errorf("rectangle corner (%d,%d) = %q, want #", pos[0], pos[1], cell.char)
+		}
+	}
+	if len(m.history) != 2 {
+		t.Errorf("history length = %d, want 2", len(m.history))
+	}
+}
+
+func TestKeyboardCursorShiftExtendsDrag(t *testing.T) {
+	m := newDrawModel("Point", 10, 10)
+	m.toggleKeyboardCursor()
+	pressKeys(m, "L", "L")
+	m.handleKey(tea.KeyMsg{Type: tea.KeyShiftDown})
+	if !m.mouseDown {
+		t.Fatal("shift+movement should start a drag")
+	}
+	for _, pos := range [][2]int{{0, 0}, {0, 1}, {0, 2}, {1, 2}} {
+		if cell := m.canvas.Get(pos[0], pos[1]); cell.char != "#" {
+			t.Errorf("cell (%d,%d) = %q, want #", pos[0], pos[1], cell.char)
+		}
+	}
+	m.handleKey(keyMsg(" "))
+	if m.mouseDown {
+		t.Error("space should end the drag")
+	}
+}
+
+func TestKeyboardCursorFill(t *testing.T) {
+	m := newDrawModel("Fill", 10, 10)
+	m.toggleKeyboardCursor()
+	pressKeys(m, " ", " ")
+	if cell := m.canvas.Get(9, 9); cell.char != "#" {
+		t.Errorf("fill should reach (9,9), got %q", cell.char)
+	}
+}
+
+func TestKeyboardCursorText(t *testing.T) {
+	m := newDrawModel("Text", 10, 10)
+	m.toggleKeyboardCursor()
+	pressKeys(m, "j", " ")
+	if m.mouseDown || !m.textInsertActive {
+		t.Fatal("space with the Text tool should click and start typing")
+	}
+	pressKeys(m, "h", "i")
+	if got := m.canvas.Get(1, 0).char + m.canvas.Get(1, 1).char; got != "hi" {
+		t.Errorf("typed %q, want hi", got)
+	}
+}
+
+func TestKeyboardCursorEscCancelsStroke(t *testing.T) {
+	m := newDrawModel("Point", 10, 10)
+	m.toggleKeyboardCursor()
+	pressKeys(m, " ", "l", "l")
+	m.handleKey(keyMsg("esc"))
+	if m.mouseDown {
+		t.Fatal("esc should cancel the stroke")
+	}
+	if m.canvas.Get(0, 0).char != " " || m.canvas.Get(0, 2).char != " " {
+		t.Error("cancelling should restore the canvas")
+	}
+	if len(m.history) != 1 {
+		t.Errorf("history length = %d, want 1", len(m.history))
+	}
+}
+
+func TestKeyboardCursorLeavesMenusAlone(t *testing.T) {
+	m := newDrawModel("Point", 10, 10)
+	m.toggleKeyboardCursor()
+	m.handleKey(keyMsg("t"))
+	m.handleKey(keyMsg("down"))
+	if m.hoverRow != 0 {
+		t.Error("arrows should navigate an open menu, not move the cursor")
+	}
+}
+
+func TestKeyboardCursorScrollsIntoView(t *testing.T) {
+	m := newScrollModel()
+	m.handleKey(keyMsg("m"))
+	rows, _ := m.viewportSize()
+	for i := 0; i < rows; i++ {
+		m.handleKey(keyMsg("j"))
+	}
+	if m.scrollY != 1 || !m.viewportContains(m.hoverRow, m.hoverCol) {
+		t.Errorf("scrollY = %d, cursor row %d should stay in view", m.scrollY, m.hoverRow)
+	}
+}
@@ -0,0 +1,388 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// Native documents are JSON with a header followed by the layer stack, with
+// one line per canvas row. Version 1 files hold a single "cells" grid instead
+// of layers and are still readable.
+const (
+	documentFormat  = "pixl"
+	documentVersion = 2
+	nativeExt       = ".pixl"
+)
+
+// documentMeta holds descriptive metadata stored alongside the artwork.
+type documentMeta struct {
+	Title  string `json:"title,omitempty"`
+	Author string `json:"author,omitempty"`
+}
+
+// documentEditor holds the editor state restored when a document is opened.
+type documentEditor struct {
+	Tool          string   `json:"tool,omitempty"`
+	Glyph         string   `json:"glyph,omitempty"`
+	Foreground    string   `json:"foreground,omitempty"`
+	Background    string   `json:"background,omitempty"`
+	BoxStyle      string   `json:"boxStyle,omitempty"`
+	CircleMode    bool     `json:"circleMode,omitempty"`
+	FillMode      string   `json:"fillMode,omitempty"`
+	FillGlyph     string   `json:"fillGlyph,omitempty"`
+	FillColor     string   `json:"fillColor,omitempty"`
+	Grid          string   `json:"grid,omitempty"`
+	PixelMode     bool     `json:"pixelMode,omitempty"` // read only, from before grid
+	SelectMode    string   `json:"selectMode,omitempty"`
+	PolylineMode  string   `json:"polylineMode,omitempty"`
+	VerticalElbow bool     `json:"verticalElbow,omitempty"`
+	ArrowStart    bool     `json:"arrowStart,omitempty"`
+	ArrowEnd      bool     `json:"arrowEnd,omitempty"`
+	Attributes    []string `json:"attributes,omitempty"`
+	ActiveLayer   int      `json:"activeLayer,omitempty"`
+}
+
+// documentCell is the on-disk form of a Cell. Fields equal to the layer's
+// blank cell are omitted, so an untouched cell encodes as {}.
+type documentCell struct {
+	Char  string    `json:"c,omitempty"`
+	Fg    string    `json:"fg,omitempty"`
+	Bg    string    `json:"bg,omitempty"`
+	Attrs cellAttrs `json:"a,omitempty"`
+}
+
+type documentHeader struct {
+	Format  string         `json:"format"`
+	Version int            `json:"version"`
+	Width   int            `json:"width"`
+	Height  int            `json:"height"`
+	Meta    documentMeta   `json:"meta"`
+	Editor  documentEditor `json:"editor"`
+}
+
+type documentLayerHeader struct {
+	Name   string `json:"name"`
+	Hidden bool   `json:"hidden,omitempty"`
+	Locked bool   `json:"locked,omitempty"`
+}
+
+type documentLayer struct {
+	documentLayerHeader
+	Cells [][]documentCell `json:"cells"`
+}
+
+type documentFile struct {
+	documentHeader
+	Cells  [][]documentCell `json:"cells"` // version 1 only
+	Layers []documentLayer  `json:"layers"`
+}
+
+// document is a decoded native file. Layers are ordered bottom to top.
+type document struct {
+	layers []Layer
+	meta   documentMeta
+	editor documentEditor
+}
+
+func isNativePath(path string) bool {
+	return strings.EqualFold(filepath.Ext(path), nativeExt)
+}
+
+func encodeDocumentCell(c, blank Cell) documentCell {
+	var dc documentCell
+	if c.char != blank.char {
+		dc.Char = c.char
+	}
+	if c.foregroundColor != blank.foregroundColor {
+		dc.Fg = c.foregroundColor
+	}
+	if c.backgroundColor != blank.backgroundColor {
+		dc.Bg = c.backgroundColor
+	}
+	dc.Attrs = c.attrs
+	return dc
+}
+
+func decodeDocumentCell(dc documentCell, blank Cell) Cell {
+	c := blank
+	if dc.Char != "" {
+		c.char = dc.Char
+	}
+	if dc.Fg != "" {
+		c.foregroundColor = dc.Fg
+	}
+	if dc.Bg != "" {
+		c.backgroundColor = dc.Bg
+	}
+	c.attrs = dc.Attrs & attrAll
+	return c
+}
+
+// encodeDocument serializes a document. Headers are indented and each
+// canvas row is written on its own line so files diff cleanly.
+func encodeDocument(doc document) ([]byte, error) {
+	if len(doc.layers) == 0 {
+		return nil, fmt.Errorf("document has no layers")
+	}
+	width, height := doc.layers[0].canvas.width, doc.layers[0].canvas.height
+	header := documentHeader{
+		Format:  documentFormat,
+		Version: documentVersion,
+		Width:   width,
+		Height:  height,
+		Meta:    doc.meta,
+		Editor:  doc.editor,
+	}
+	head, err := json.MarshalIndent(header, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	var b bytes.Buffer
+	b.Write(bytes.TrimSuffix(head, []byte("\n}")))
+	b.WriteString(",\n  \"layers\": [")
+	for i, l := range doc.layers {
+		layerHead, err := json.MarshalIndent(documentLayerHeader{
+			Name:   l.name,
+			Hidden: !l.visible,
+			Locked: l.locked,
+		}, "    ", "  ")
+		if err != nil {
+			return nil, err
+		}
+		if i > 0 {
+			b.WriteString(",")
+		}
+		b.WriteString("\n    ")
+		b.Write(bytes.TrimSuffix(layerHead, []byte("\n    }")))
+		b.WriteString(",\n      \"cells\": [")
+		blank := layerBlank(i)
+		for row := 0; row < height; row++ {
+			cells := make([]documentCell, width)
+			for col := range cells {
+				cells[col] = encodeDocumentCell(l.canvas.cells[row][col], blank)
+			}
+			line, err := json.Marshal(cells)
+			if err != nil {
+				return nil, err
+			}
+			if row > 0 {
+				b.WriteString(",")
+			}
+			b.WriteString("\n        ")
+			b.Write(line)
+		}
+		b.WriteString("\n      ]\n    }")
+	}
+	b.WriteString("\n  ]\n}\n")
+	return b.Bytes(), nil
+}
+
+// decodeCells converts one grid of document cells into a canvas.
+func decodeCells(rows [][]documentCell, width, height int, blank Cell) (Canvas, error) {
+	if len(rows) != height {
+		return Canvas{}, fmt.Errorf("document has %d rows, want %d", len(rows), height)
+	}
+	canvas := NewCanvas(width, height)
+	for row, cells := range rows {
+		if len(cells) != width {
+			return Canvas{}, fmt.Errorf("row %d has %d cells, want %d", row, len(cells), width)
+		}
+		for col, dc := range cells {
+			canvas.cells[row][col] = decodeDocumentCell(dc, blank)
+		}
+	}
+	return canvas, nil
+}
+
+func decodeDocument(data []byte) (document, error) {
+	var f documentFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return document{}, fmt.Errorf("invalid pixl document: %w", err)
+	}
+	if f.Format != documentFormat {
+		return document{}, fmt.Errorf("not a pixl document (format %q)", f.Format)
+	}
+	if f.Version < 1 || f.Version > documentVersion {
+		return document{}, fmt.Errorf("unsupported pixl document version %d", f.Version)
+	}
+	if f.Width <= 0 || f.Height <= 0 {
+		return document{}, fmt.Errorf("invalid canvas size %dx%d", f.Width, f.Height)
+	}
+
+	doc := document{meta: f.Meta, editor: f.Editor}
+	if f.Version == 1 {
+		canvas, err := decodeCells(f.Cells, f.Width, f.Height, blankCell)
+		if err != nil {
+			return document{}, err
+		}
+		doc.layers = []Layer{{name: "Background", visible: true, canvas: canvas}}
+		return doc, nil
+	}
+
+	if len(f.Layers) == 0 {
+		return document{}, fmt.Errorf("document has no layers")
+	}
+	for i, dl := range f.Layers {
+		canvas, err := decodeCells(dl.Cells, f.Width, f.Height, layerBlank(i))
+		if err != nil {
+			return document{}, fmt.Errorf("layer %d: %w", i, err)
+		}
+		doc.layers = append(doc.layers, Layer{
+			name:    dl.Name,
+			visible: !dl.Hidden,
+			locked:  dl.Locked,
+			canvas:  canvas,
+		})
+	}
+	return doc, nil
+}
+
+// document captures the model's layers, metadata and editor state.
+func (m *model) document() document {
+	m.syncActiveLayer()
+	doc := document{
+		layers: m.layers,
+		meta:   m.meta,
+		editor: documentEditor{
+			Tool:          m.selectedTool,
+			Glyph:         m.selectedChar,
+			Foreground:    m.foregroundColor,
+			Background:    m.backgroundColor,
+			CircleMode:    m.circleMode,
+			VerticalElbow: m.connectorVertical,
+			ArrowStart:    m.arrowStart,
+			ArrowEnd:      m.arrowEnd,
+			FillGlyph:     m.fillChar,
+			FillColor:     m.fillColor,
+			ActiveLayer:   m.activeLayer,
+		},
+	}
+	if m.boxStyle >= 0 && m.boxStyle < len(boxStyles) {
+		doc.editor.BoxStyle = boxStyles[m.boxStyle].name
+	}
+	if m.selectModeIndex > 0 {
+		doc.editor.SelectMode = m.selectMode().name
+	}
+	if m.polylineModeIndex > 0 {
+		doc.editor.PolylineMode = m.polylineMode().name
+	}
+	if m.fillMode != fillNone {
+		doc.editor.FillMode = fillModeNames[m.fillMode]
+	}
+	if m.grid != cellGrid {
+		doc.editor.Grid = drawGrids[m.grid].name
+	}
+	for _, a := range textAttributes {
+		if m.textAttrs&a.attr != 0 {
+			doc.editor.Attributes = append(doc.editor.Attributes, strings.ToLower(a.name))
+		}
+	}
+	if doc.meta.Author == "" {
+		doc.meta.Author = m.config.Author
+	}
+	if doc.meta.Title == "" && m.filePath != "" {
+		base := filepath.Base(m.filePath)
+		doc.meta.Title = strings.TrimSuffix(base, filepath.Ext(base))
+	}
+	return doc
+}
+
+// applyDocument replaces the layers with the document's and restores the
+// saved editor state. Invalid editor values are ignored.
+func (m *model) applyDocument(doc document) {
+	e := doc.editor
+	m.cancelPolyline()
+	m.layers = doc.layers
+	m.activeLayer = 0
+	if e.ActiveLayer > 0 && e.ActiveLayer < len(m.layers) {
+		m.activeLayer = e.ActiveLayer
+	}
+	m.canvas = m.layers[m.activeLayer].canvas
+	m.meta = doc.meta
+	m.fixedWidth = m.canvas.width
+	m.fixedHeight = m.canvas.height
+
+	if e.Tool != "" && isValidTool(e.Tool) {
+		m.setTool(e.Tool)
+	}
+	if len([]rune(e.Glyph)) == 1 {
+		m.selectedChar = e.Glyph
+	}
+	if e.Foreground != "" && isValidCanvasColor(e.Foreground) {
+		m.foregroundColor = canonicalColor(e.Foreground)
+	}
+	if e.Background != "" && isValidCanvasColor(e.Background) {
+		m.backgroundColor = canonicalColor(e.Background)
+	}
+	for i, s := range boxStyles {
+		if s.name == e.BoxStyle {
+			m.boxStyle = i
+			break
+		}
+	}
+	m.circleMode = e.CircleMode
+	m.connectorVertical = e.VerticalElbow
+	m.arrowStart, m.arrowEnd = e.ArrowStart, e.ArrowEnd
+	m.fillMode = fillNone
+	for i, name := range fillModeNames {
+		if name == e.FillMode {
+			m.fillMode = fillMode(i)
+			break
+		}
+	}
+	if len([]rune(e.FillGlyph)) == 1 {
+		m.fillChar = e.FillGlyph
+	}
+	if e.FillColor != "" && isValidCanvasColor(e.FillColor) {
+		m.fillColor = canonicalColor(e.FillColor)
+	}
+	m.grid = cellGrid
+	if e.PixelMode {
+		m.grid = pixelGrid
+	}
+	for i, g := range drawGrids {
+		if g.name == e.Grid {
+			m.grid = drawGrid(i)
+			break
+		}
+	}
+	m.selectModeIndex = 0
+	for i, mode := range selectModes {
+		if mode.name == e.SelectMode {
+			m.selectModeIndex = i
+			break
+		}
+	}
+	m.polylineModeIndex = 0
+	for i, mode := range polylineModes {
+		if mode.name == e.PolylineMode {
+			m.polylineModeIndex = i
+			break
+		}
+	}
+	m.textAttrs = 0
+	for _, name := range e.Attributes {
+		for _, a := range textAttributes {
+			if strings.EqualFold(name, a.name) {
+				m.textAttrs |= a.attr
+			}
+		}
+	}
+}
+
+// fileContent returns what should be written to path: the native document
+// for .pixl files and ANSI text for everything else.
+func (m *model) fileContent(path string) (string, error) {
+	if !isNativePath(path) {
+		return m.renderCanvasPlain(), nil
+	}
+	data, err := encodeDocument(m.document())
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
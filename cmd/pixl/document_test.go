@@ -0,0 +1,244 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func singleLayer(c Canvas) []Layer {
+	return []Layer{{name: "Background", visible: true, canvas: c}}
+}
+
+func TestDocumentRoundTripPreservesCells(t *testing.T) {
+	c := NewCanvas(4, 2)
+	c.Set(0, 0, "X", "red", "blue")
+	c.Set(0, 1, " ", "transparent", "transparent")
+	c.Set(1, 3, "★", "bright_yellow", "transparent")
+	c.SetCell(1, 0, Cell{char: "B", foregroundColor: "white", backgroundColor: "transparent", attrs: attrBold | attrReverse})
+
+	data, err := encodeDocument(document{layers: singleLayer(c)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc, err := decodeDocument(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if doc.layers[0].canvas.width != 4 || doc.layers[0].canvas.height != 2 {
+		t.Fatalf("size = %dx%d, want 4x2", doc.layers[0].canvas.width, doc.layers[0].canvas.height)
+	}
+	if !doc.layers[0].canvas.Equals(c) {
+		t.Error("decoded canvas differs from original")
+	}
+	// A transparent cell must stay distinct from a blank space
+	if cell := doc.layers[0].canvas.Get(0, 1); cell.foregroundColor != "transparent" {
+		t.Errorf("cell(0,1).foregroundColor = %q, want transparent", cell.foregroundColor)
+	}
+	if cell := doc.layers[0].canvas.Get(0, 2); cell.foregroundColor != "white" {
+		t.Errorf("cell(0,2).foregroundColor = %q, want white", cell.foregroundColor)
+	}
+}
+
+func TestDocumentRoundTripMetaAndEditor(t *testing.T) {
+	doc := document{
+		layers: singleLayer(NewCanvas(2, 2)),
+		meta:   documentMeta{Title: "Cat", Author: "ann"},
+		editor: documentEditor{Tool: "Box", Glyph: "#", Foreground: "red", Background: "blue", BoxStyle: "Heavy", CircleMode: true},
+	}
+	data, err := encodeDocument(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := decodeDocument(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.meta != doc.meta {
+		t.Errorf("meta = %+v, want %+v", got.meta, doc.meta)
+	}
+	if !reflect.DeepEqual(got.editor, doc.editor) {
+		t.Errorf("editor = %+v, want %+v", got.editor, doc.editor)
+	}
+}
+
+func TestEncodeDocumentOneRowPerLine(t *testing.T) {
+	data, err := encodeDocument(document{layers: singleLayer(NewCanvas(3, 2))})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "\n        [{},{},{}],\n        [{},{},{}]\n") {
+		t.Errorf("rows not written one per line:\n%s", data)
+	}
+}
+
+func TestDecodeDocumentErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{"not json", "hello"},
+		{"wrong format", `{"format":"other","version":1,"width":1,"height":1,"cells":[[{}]]}`},
+		{"future version", `{"format":"pixl","version":99,"width":1,"height":1,"cells":[[{}]]}`},
+		{"zero size", `{"format":"pixl","version":1,"width":0,"height":0,"cells":[]}`},
+		{"missing rows", `{"format":"pixl","version":1,"width":1,"height":2,"cells":[[{}]]}`},
+		{"ragged row", `{"format":"pixl","version":1,"width":2,"height":1,"cells":[[{}]]}`},
+		{"no layers", `{"format":"pixl","version":2,"width":1,"height":1,"layers":[]}`},
+		{"ragged layer", `{"format":"pixl","version":2,"width":2,"height":1,"layers":[{"name":"a","cells":[[{},{}]]},{"name":"b","cells":[[{}]]}]}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := decodeDocument([]byte(tt.data)); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
+
+func TestApplyDocumentRestoresState(t *testing.T) {
+	m := initialModel()
+	canvas := NewCanvas(7, 3)
+	canvas.Set(2, 6, "Z", "green", "transparent")
+	m.applyDocument(document{
+		layers: singleLayer(canvas),
+		editor: documentEditor{Tool: "Line", Glyph: "*", Foreground: "red", Background: "nope", BoxStyle: "Double",
+			SelectMode: "Lasso", PolylineMode: "Box Polygon", ArrowEnd: true, Grid: "Braille", FillMode: "Filled", FillGlyph: "▒", Attributes: []string{"bold", "Underline", "blink"}},
+	})
+
+	if m.fixedWidth != 7 || m.fixedHeight != 3 {
+		t.Errorf("fixed size = %dx%d, want 7x3", m.fixedWidth, m.fixedHeight)
+	}
+	if cell := m.canvas.Get(2, 6); cell == nil || cell.char != "Z" {
+		t.Errorf("cell(2,6) = %+v, want Z", cell)
+	}
+	if m.selectedTool != "Line" || m.selectedChar != "*" || m.foregroundColor != "red" {
+		t.Errorf("editor state = %s %q %s, want Line * red", m.selectedTool, m.selectedChar, m.foregroundColor)
+	}
+	if m.backgroundColor != "transparent" {
+		t.Errorf("backgroundColor = %q, want invalid value ignored", m.backgroundColor)
+	}
+	if m.boxStyle != 1 {
+		t.Errorf("boxStyle = %d, want 1 (Double)", m.boxStyle)
+	}
+	if m.selectModeIndex != 1 {
+		t.Errorf("selectModeIndex = %d, want 1 (Lasso)", m.selectModeIndex)
+	}
+	if m.arrowStart || !m.arrowEnd {
+		t.Errorf("arrows = %v %v, want only the end arrow", m.arrowStart, m.arrowEnd)
+	}
+	if m.polylineModeIndex != 3 {
+		t.Errorf("polylineModeIndex = %d, want 3 (Box Polygon)", m.polylineModeIndex)
+	}
+	if m.fillMode != fillOnly || m.fillChar != "▒" {
+		t.Errorf("fill = %s %q, want Filled ▒", fillModeNames[m.fillMode], m.fillChar)
+	}
+	if m.grid != brailleGrid {
+		t.Errorf("grid = %d, want braille", m.grid)
+	}
+	if m.textAttrs != attrBold|attrUnderline {
+		t.Errorf("textAttrs = %06b, want bold+underline", m.textAttrs)
+	}
+}
+
+func TestFileContentPicksFormatByExtension(t *testing.T) {
+	m := &model{canvas: NewCanvas(2, 1), selectedChar: "●"}
+	m.canvas.Set(0, 0, "X", "red", "transparent")
+
+	native, err := m.fileContent("/tmp/art.pixl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(native, "{") {
+		t.Errorf(".pixl content should be a native document, got %q", native)
+	}
+
+	text, err := m.fileContent("/tmp/art.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text != m.renderCanvasPlain() {
+		t.Errorf(".txt content = %q, want ANSI text", text)
+	}
+}
+
+func TestDocumentTitleDefaultsToFileName(t *testing.T) {
+	m := &model{canvas: NewCanvas(1, 1), filePath: "/tmp/space cat.pixl", config: Config{Author: "ann"}}
+	doc := m.document()
+	if doc.meta.Title != "space cat" {
+		t.Errorf("title = %q, want %q", doc.meta.Title, "space cat")
+	}
+	if doc.meta.Author != "ann" {
+		t.Errorf("author = %q, want ann", doc.meta.Author)
+	}
+}
+
+func TestDocumentRoundTripPreservesLayers(t *testing.T) {
+	bottom := NewCanvas(2, 1)
+	bottom.Set(0, 0, "A", "red", "transparent")
+	top := newLayerCanvas(1, 2, 1)
+	top.Set(0, 1, "B", "white", "transparent")
+	layers := []Layer{
+		{name: "Background", visible: true, canvas: bottom},
+		{name: "Ink", visible: false, locked: true, canvas: top},
+	}
+
+	data, err := encodeDocument(document{layers: layers, editor: documentEditor{ActiveLayer: 1}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc, err := decodeDocument(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(doc.layers) != 2 {
+		t.Fatalf("decoded %d layers, want 2", len(doc.layers))
+	}
+	for i, want := range layers {
+		got := doc.layers[i]
+		if got.name != want.name || got.visible != want.visible || got.locked != want.locked {
+			t.Errorf("layer %d = %q visible=%v locked=%v, want %q visible=%v locked=%v",
+				i, got.name, got.visible, got.locked, want.name, want.visible, want.locked)
+		}
+		if !got.canvas.Equals(want.canvas) {
+			t.Errorf("layer %d cells differ", i)
+		}
+	}
+	// Transparent cells on upper layers encode as {}
+	if !strings.Contains(string(data), `[{},{"c":"B","fg":"white"}]`) {
+		t.Errorf("upper layer not encoded relative to transparency:\n%s", data)
+	}
+
+	m := initialModel()
+	m.applyDocument(doc)
+	if m.activeLayer != 1 || m.canvas.Get(0, 1).char != "B" {
+		t.Errorf("active layer = %d, want 1 with its canvas loaded", m.activeLayer)
+	}
+}
+
+func TestDecodeVersion1Document(t *testing.T) {
+	data := `{"format":"pixl","version":1,"width":2,"height":1,"cells":[[{"c":"X","fg":"red"},{}]]}`
+	doc, err := decodeDocument([]byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(doc.layers) != 1 || doc.layers[0].name != "Background" || !doc.layers[0].visible {
+		t.Fatalf("layers = %+v, want a single visible Background layer", doc.layers)
+	}
+	if cell := doc.layers[0].canvas.Get(0, 0); cell.char != "X" || cell.foregroundColor != "red" {
+		t.Errorf("cell(0,0) = %+v, want X/red", cell)
+	}
+}
+
+func TestDecodePixelModeDocument(t *testing.T) {
+	data := `{"format":"pixl","version":1,"width":1,"height":1,"cells":[[{}]],"editor":{"pixelMode":true}}`
+	doc, err := decodeDocument([]byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := initialModel()
+	m.applyDocument(doc)
+	if m.grid != pixelGrid {
+		t.Errorf("grid = %d, want pixels from pixelMode", m.grid)
+	}
+}
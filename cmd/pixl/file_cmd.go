@@ -0,0 +1,364 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+type pathPromptMode int
+
+const (
+	promptSaveAs pathPromptMode = iota
+	promptOpen
+)
+
+const pathPromptMaxMatches = 5
+
+// isModified reports whether the document changed since it was last saved
+// or opened, by comparing the history position with the saved one.
+func (m *model) isModified() bool {
+	return len(m.history) > 0 && m.historyIndex != m.savedHistoryIndex
+}
+
+// markSaved records the current state as saved. Its changes no longer need
+// recovering, so the autosave recovery file is removed.
+func (m *model) markSaved() {
+	m.savedHistoryIndex = m.historyIndex
+	m.removeRecovery()
+}
+
+// save writes the document to its file, asking for a path if it has none.
+func (m *model) save() {
+	if m.filePath == "" {
+		m.openPathPrompt(promptSaveAs)
+		return
+	}
+	m.saveAs(m.filePath)
+}
+
+// saveAs writes the document to path and makes it the document's file.
+// With backups on, the version that was on disk before the session's first
+// save to path is kept. Failures are reported through an alert.
+func (m *model) saveAs(path string) bool {
+	output, err := m.fileContent(path)
+	if err == nil && !m.backedUp[path] {
+		err = backupFile(path, m.config.Backup)
+	}
+	if err == nil {
+		if m.backedUp == nil {
+			m.backedUp = map[string]bool{}
+		}
+		m.backedUp[path] = true
+		err = saveFile(path, output)
+	}
+	if err != nil {
+		m.alertMessage = fmt.Sprintf("Save failed: %v", err)
+		return false
+	}
+	m.filePath = path
+	m.markSaved()
+	return true
+}
+
+// requestQuit quits, first asking whether to save if quitting would lose
+// changes. Documents without a file are printed on quit, so they don't ask,
+// and --no-save quits without writing anything.
+func (m *model) requestQuit() tea.Cmd {
+	if m.isModified() && m.filePath != "" && !m.noSave {
+		m.confirmQuit = true
+		return nil
+	}
+	return tea.Quit
+}
+
+// requestOpen opens path, first asking whether to save if replacing the
+// document would lose changes.
+func (m *model) requestOpen(path string) {
+	if m.isModified() {
+		m.confirmQuit = true
+		m.pendingOpen = path
+		return
+	}
+	m.openFile(path)
+}
+
+// handleQuitConfirmKey handles the save/discard/cancel choice on quit, or on
+// opening another file when pendingOpen is set. Any other key cancels.
+// Saving a document without a file asks for a path first and opens the
+// other file once it is saved.
+func (m *model) handleQuitConfirmKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	m.confirmQuit = false
+	open := m.pendingOpen
+	m.pendingOpen = ""
+	switch msg.String() {
+	case "s":
+		if m.filePath == "" {
+			m.openPathPrompt(promptSaveAs)
+			m.pendingOpen = open
+			return m, nil
+		}
+		if m.saveAs(m.filePath) {
+			return m, m.continueAfterConfirm(open)
+		}
+	case "d":
+		if open == "" {
+			m.discardOnQuit = true
+		}
+		return m, m.continueAfterConfirm(open)
+	}
+	return m, nil
+}
+
+// continueAfterConfirm opens the file the save/discard prompt was asked for,
+// or quits if it was asked on quit.
+func (m *model) continueAfterConfirm(open string) tea.Cmd {
+	if open != "" {
+		m.openFile(open)
+		return nil
+	}
+	return tea.Quit
+}
+
+// openFile replaces the document with the contents of path: a native
+// document for .pixl files and ANSI text for everything else.
+func (m *model) openFile(path string) bool {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		m.alertMessage = fmt.Sprintf("Open failed: %v", err)
+		return false
+	}
+
+	if isNativePath(path) {
+		doc, err := decodeDocument(data)
+		if err != nil {
+			m.alertMessage = fmt.Sprintf("Open failed: %v", err)
+			return false
+		}
+		m.applyDocument(doc)
+	} else {
+		text := string(data)
+		width, height := textCanvasSize(text)
+		c := NewCanvas(max(width, 1), max(height, 1))
+		c.LoadText(text)
+		m.layers = []Layer{{name: "Background", visible: true, canvas: c}}
+		m.activeLayer = 0
+		m.canvas = c
+		m.meta = documentMeta{}
+		m.fixedWidth = c.width
+		m.fixedHeight = c.height
+	}
+
+	m.filePath = path
+	m.canvasInitialized = true
+	m.scrollX, m.scrollY = 0, 0
+	m.selection.active = false
+	m.textInsertActive = false
+	m.history = nil
+	m.saveToHistory()
+	m.markSaved()
+	return true
+}
+
+// textCanvasSize returns the canvas size that fits ANSI text.
+func textCanvasSize(text string) (width, height int) {
+	lines := strings.Split(strings.TrimRight(text, "\n"), "\n")
+	for _, line := range lines {
+		width = max(width, visibleWidth(line))
+	}
+	if width == 0 {
+		return 0, 0
+	}
+	return width, len(lines)
+}
+
+func (m *model) openPathPrompt(mode pathPromptMode) {
+	m.closeMenus()
+	m.showPathPrompt = true
+	m.pathPromptMode = mode
+	m.pathInput = ""
+	if mode == promptSaveAs && m.filePath != "" {
+		m.pathInput = m.filePath
+	} else if wd, err := os.Getwd(); err == nil {
+		m.pathInput = wd + string(filepath.Separator)
+	}
+	m.updatePathMatches()
+}
+
+func (m *model) closePathPrompt() {
+	m.showPathPrompt = false
+	m.pathInput = ""
+	m.pathInputMatches = nil
+}
+
+// updatePathMatches lists the matches for the path input, which are kept
+// so that rendering the prompt doesn't read the directory.
+func (m *model) updatePathMatches() {
+	m.pathInputMatches = pathMatches(m.pathInput)
+}
+
+// expandPath resolves a leading ~ and makes the path absolute.
+func expandPath(path string) string {
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			path = filepath.Join(home, path[1:])
+		}
+	}
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return path
+}
+
+// pathMatches lists the entries of the input's directory that start with
+// its last element. Directories end with a separator.
+func pathMatches(input string) []string {
+	dir, prefix := filepath.Split(input)
+	readDir := dir
+	if readDir == "" {
+		readDir = "."
+	}
+	entries, err := os.ReadDir(expandPath(readDir))
+	if err != nil {
+		return nil
+	}
+	var matches []string
+	for _, e := range entries {
+		name := e.Name()
+		if !strings.HasPrefix(name, prefix) || (strings.HasPrefix(name, ".") && !strings.HasPrefix(prefix, ".")) {
+			continue
+		}
+		if e.IsDir() {
+			name += string(filepath.Separator)
+		}
+		matches = append(matches, dir+name)
+	}
+	sort.Strings(matches)
+	return matches
+}
+
+// completePath extends the input to the longest prefix shared by every
+// match, like shell tab completion.
+func completePath(input string) string {
+	matches := pathMatches(input)
+	if len(matches) == 0 {
+		return input
+	}
+	common := matches[0]
+	for _, match := range matches[1:] {
+		for !strings.HasPrefix(match, common) {
+			common = common[:len(common)-1]
+		}
+	}
+	for !utf8.ValidString(common) {
+		common = common[:len(common)-1]
+	}
+	if len(common) > len(input) {
+		return common
+	}
+	return input
+}
+
+// handlePathPromptKey handles keys while the path prompt is open.
+func (m *model) handlePathPromptKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.Type {
+	case tea.KeyEscape:
+		m.closePathPrompt()
+		m.pendingOpen = ""
+	case tea.KeyEnter:
+		if strings.TrimSpace(m.pathInput) == "" {
+			return m, nil
+		}
+		path := expandPath(m.pathInput)
+		if info, err := os.Stat(path); err == nil && info.IsDir() {
+			if !strings.HasSuffix(m.pathInput, string(filepath.Separator)) {
+				m.pathInput += string(filepath.Separator)
+				m.updatePathMatches()
+			}
+			return m, nil
+		}
+		mode := m.pathPromptMode
+		m.closePathPrompt()
+		if mode == promptOpen {
+			m.requestOpen(path)
+		} else if m.saveAs(path) && m.pendingOpen != "" {
+			m.openFile(m.pendingOpen)
+		}
+		m.pendingOpen = ""
+	case tea.KeyTab:
+		m.pathInput = completePath(m.pathInput)
+	case tea.KeyBackspace:
+		if msg.Alt {
+			m.pathInput = deletePathElement(m.pathInput)
+		} else if r := []rune(m.pathInput); len(r) > 0 {
+			m.pathInput = string(r[:len(r)-1])
+		}
+	case tea.KeySpace:
+		m.pathInput += " "
+	case tea.KeyRunes:
+		m.pathInput += string(msg.Runes)
+	}
+	if m.showPathPrompt {
+		m.updatePathMatches()
+	}
+	return m, nil
+}
+
+// deletePathElement removes the last element of a path, keeping the
+// separator before it.
+func deletePathElement(path string) string {
+	trimmed := strings.TrimSuffix(path, string(filepath.Separator))
+	i := strings.LastIndex(trimmed, string(filepath.Separator))
+	return path[:i+1]
+}
+
+func (m *model) renderPathPrompt() string {
+	dimStyle := lipgloss.NewStyle().Faint(true)
+
+	title := "Save As"
+	if m.pathPromptMode == promptOpen {
+		title = "Open"
+	}
+	inputLine := "> " + m.pathInput + "_"
+
+	var matches []string
+	if all := m.pathInputMatches; len(all) > 1 {
+		for _, match := range all[:min(len(all), pathPromptMaxMatches)] {
+			name := filepath.Base(match)
+			if strings.HasSuffix(match, string(filepath.Separator)) {
+				name += string(filepath.Separator)
+			}
+			matches = append(matches, "  "+name)
+		}
+		if len(all) > pathPromptMaxMatches {
+			matches = append(matches, fmt.Sprintf("  … %d more", len(all)-pathPromptMaxMatches))
+		}
+	}
+
+	width := max(40, lipgloss.Width(inputLine))
+	for _, line := range matches {
+		width = max(width, lipgloss.Width(line))
+	}
+
+	lines := []string{title, strings.Repeat("─", width), inputLine}
+	for _, line := range matches {
+		lines = append(lines, dimStyle.Render(line))
+	}
+	for i, line := range lines {
+		if pad := width - lipgloss.Width(line); pad > 0 {
+			lines[i] = line + strings.Repeat(" ", pad)
+		}
+	}
+
+	dialogStyle := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(themeColor(m.config.Theme.MenuBorder)).
+		Padding(0, 1)
+	return dialogStyle.Render(strings.Join(lines, "\n"))
+}
@@ -0,0 +1,340 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestSaveWritesFileAndClearsModified(t *testing.T) {
+	m := newLayerModel()
+	m.filePath = filepath.Join(t.TempDir(), "art.txt")
+	m.canvas.Set(0, 0, "A", "white", "transparent")
+	m.saveToHistory()
+	if !m.isModified() {
+		t.Fatal("a change should mark the document modified")
+	}
+	if !strings.Contains(m.renderControlBar(), "[+]") {
+		t.Error("toolbar should show the modified indicator")
+	}
+
+	m.handleKey(tea.KeyMsg{Type: tea.KeyCtrlS})
+	data, err := os.ReadFile(m.filePath)
+	if err != nil || !strings.HasPrefix(string(data), "A") {
+		t.Fatalf("saved file = %q, %v", data, err)
+	}
+	if m.isModified() || strings.Contains(m.renderControlBar(), "[+]") {
+		t.Error("saving should clear the modified indicator")
+	}
+
+	m.undo()
+	if !m.isModified() {
+		t.Error("undoing past the save should mark the document modified")
+	}
+	m.redo()
+	if m.isModified() {
+		t.Error("returning to the saved state should clear the modified flag")
+	}
+}
+
+func TestSaveWithoutPathPrompts(t *testing.T) {
+	m := newLayerModel()
+	m.save()
+	if !m.showPathPrompt || m.pathPromptMode != promptSaveAs {
+		t.Fatal("saving an unnamed document should ask for a path")
+	}
+
+	path := filepath.Join(t.TempDir(), "new.pixl")
+	m.pathInput = ""
+	typeText(m, path)
+	m.handleKey(keyMsg("enter"))
+	if m.showPathPrompt || m.filePath != path {
+		t.Fatalf("enter should save to %s, filePath = %q", path, m.filePath)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("file not written: %v", err)
+	}
+}
+
+func TestSaveFailureAlerts(t *testing.T) {
+	m := newLayerModel()
+	if m.saveAs(filepath.Join(t.TempDir(), "missing", "art.txt")) {
+		t.Fatal("saving into a missing directory should fail")
+	}
+	if !strings.HasPrefix(m.alertMessage, "Save failed") || m.filePath != "" {
+		t.Errorf("alert = %q, filePath = %q", m.alertMessage, m.filePath)
+	}
+}
+
+func TestOpenFile(t *testing.T) {
+	dir := t.TempDir()
+	src := newLayerModel()
+	src.canvas.Set(1, 2, "Z", "red", "transparent")
+	src.addLayer()
+	native := filepath.Join(dir, "art.pixl")
+	if !src.saveAs(native) {
+		t.Fatalf("save failed: %s", src.alertMessage)
+	}
+	text := filepath.Join(dir, "art.txt")
+	os.WriteFile(text, []byte("abc\nde\n"), 0644)
+
+	m := newLayerModel()
+	m.canvas.Set(0, 0, "Q", "white", "transparent")
+	m.saveToHistory()
+
+	if !m.openFile(native) {
+		t.Fatalf("open failed: %s", m.alertMessage)
+	}
+	if len(m.layers) != 2 || m.layerCanvas(0).Get(1, 2).char != "Z" || m.filePath != native {
+		t.Errorf("opened %d layers from %s", len(m.layers), m.filePath)
+	}
+	if m.isModified() || len(m.history) != 1 {
+		t.Error("a freshly opened document should be unmodified with a new history")
+	}
+
+	if !m.openFile(text) {
+		t.Fatalf("open failed: %s", m.alertMessage)
+	}
+	if m.canvas.width != 3 || m.canvas.height != 2 || m.canvas.Get(1, 1).char != "e" || len(m.layers) != 1 {
+		t.Errorf("text opened as %dx%d", m.canvas.width, m.canvas.height)
+	}
+
+	if m.openFile(filepath.Join(dir, "nope.txt")) || !strings.HasPrefix(m.alertMessage, "Open failed") {
+		t.Errorf("missing file alert = %q", m.alertMessage)
+	}
+}
+
+func TestCompletePath(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "sketch-one.txt"), nil, 0644)
+	os.WriteFile(filepath.Join(dir, "sketch-two.txt"), nil, 0644)
+	os.Mkdir(filepath.Join(dir, "scenes"), 0755)
+	os.WriteFile(filepath.Join(dir, ".hidden"), nil, 0644)
+
+	tests := []struct{ in, want string }{
+		{dir + "/sk", dir + "/sketch-"},
+		{dir + "/sketch-o", dir + "/sketch-one.txt"},
+		{dir + "/sc", dir + "/scenes/"},
+		{dir + "/s", dir + "/s"},
+		{dir + "/x", dir + "/x"},
+	}
+	for _, tt := range tests {
+		if got := completePath(tt.in); got != tt.want {
+			t.Errorf("completePath(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+	if got := pathMatches(dir + "/"); len(got) != 3 {
+		t.Errorf("pathMatches should skip dotfiles, got %v", got)
+	}
+}
+
+func TestPathPromptKeys(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "picture.txt"), []byte("hi\n"), 0644)
+
+	m := newLayerModel()
+	m.handleKey(tea.KeyMsg{Type: tea.KeyCtrlO})
+	if !m.showPathPrompt || m.pathPromptMode != promptOpen {
+		t.Fatal("ctrl+o should open the path prompt")
+	}
+	m.pathInput = dir + "/pic"
+	m.handleKey(keyMsg("tab"))
+	if m.pathInput != dir+"/picture.txt" {
+		t.Errorf("tab completed to %q", m.pathInput)
+	}
+	m.handleKey(tea.KeyMsg{Type: tea.KeyBackspace, Alt: true})
+	if m.pathInput != dir+"/" {
+		t.Errorf("alt+backspace left %q", m.pathInput)
+	}
+	m.handleKey(keyMsg("enter"))
+	if !m.showPathPrompt {
+		t.Error("enter on a directory should keep the prompt open")
+	}
+	typeText(m, "picture.txt")
+	m.handleKey(keyMsg("enter"))
+	if m.showPathPrompt || m.canvas.Get(0, 1).char != "i" {
+		t.Error("enter should open the file")
+	}
+
+	m.openPathPrompt(promptSaveAs)
+	m.handleKey(keyMsg("esc"))
+	if m.showPathPrompt {
+		t.Error("esc should close the prompt")
+	}
+}
+
+func TestPathPromptMatchesFollowInput(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"apple.txt", "apricot.txt", "banana.txt"} {
+		os.WriteFile(filepath.Join(dir, name), nil, 0644)
+	}
+
+	m := newLayerModel()
+	m.openPathPrompt(promptOpen)
+	m.pathInput = dir + "/"
+	typeText(m, "ap")
+	if len(m.pathInputMatches) != 2 {
+		t.Fatalf("matches = %q, want the two files starting with ap", m.pathInputMatches)
+	}
+
+	// Rendering uses the matches found when the input changed
+	os.Remove(filepath.Join(dir, "apricot.txt"))
+	if prompt := m.renderPathPrompt(); !strings.Contains(prompt, "apricot.txt") {
+		t.Error("the prompt should list the matches without reading the directory again")
+	}
+}
+
+func isQuit(cmd tea.Cmd) bool {
+	if cmd == nil {
+		return false
+	}
+	_, ok := cmd().(tea.QuitMsg)
+	return ok
+}
+
+func newDirtyFileModel(t *testing.T) *model {
+	m := newLayerModel()
+	m.filePath = filepath.Join(t.TempDir(), "art.txt")
+	m.canvas.Set(0, 0, "A", "white", "transparent")
+	m.saveToHistory()
+	return m
+}
+
+func TestQuitCleanDocument(t *testing.T) {
+	m := newLayerModel()
+	m.filePath = filepath.Join(t.TempDir(), "art.txt")
+	if _, cmd := m.handleKey(keyMsg("q")); !isQuit(cmd) || m.confirmQuit {
+		t.Error("quitting an unmodified document should not ask")
+	}
+	if _, err := os.Stat(m.filePath); err == nil {
+		t.Error("quitting an unmodified document should not write it")
+	}
+}
+
+func TestQuitDirtyAsksAndCancels(t *testing.T) {
+	m := newDirtyFileModel(t)
+	if _, cmd := m.handleKey(keyMsg("q")); isQuit(cmd) || !m.confirmQuit {
+		t.Fatal("quitting with unsaved changes should ask first")
+	}
+	m.ready = true
+	if !strings.Contains(m.View(), "Save changes to") {
+		t.Error("view should show the quit confirmation")
+	}
+	if _, cmd := m.handleKey(keyMsg("esc")); isQuit(cmd) || m.confirmQuit {
+		t.Error("esc should cancel quitting")
+	}
+}
+
+func TestQuitDirtySave(t *testing.T) {
+	m := newDirtyFileModel(t)
+	m.handleKey(tea.KeyMsg{Type: tea.KeyCtrlC})
+	_, cmd := m.handleKey(keyMsg("s"))
+	if !isQuit(cmd) {
+		t.Fatal("s should save and quit")
+	}
+	if data, err := os.ReadFile(m.filePath); err != nil || !strings.HasPrefix(string(data), "A") {
+		t.Errorf("saved file = %q, %v", data, err)
+	}
+}
+
+func TestQuitDirtyDiscard(t *testing.T) {
+	m := newDirtyFileModel(t)
+	m.handleKey(keyMsg("q"))
+	_, cmd := m.handleKey(keyMsg("d"))
+	if !isQuit(cmd) || !m.discardOnQuit {
+		t.Fatal("d should quit and discard")
+	}
+	if _, err := os.Stat(m.filePath); err == nil {
+		t.Error("discarding should not write the file")
+	}
+}
+
+func TestQuitUnnamedDocument(t *testing.T) {
+	m := newLayerModel()
+	m.canvas.Set(0, 0, "A", "white", "transparent")
+	m.saveToHistory()
+	if _, cmd := m.handleKey(keyMsg("q")); !isQuit(cmd) {
+		t.Error("unnamed documents are printed on quit, so should not ask")
+	}
+
+	m.noSave = true
+	if _, cmd := m.handleKey(keyMsg("q")); !isQuit(cmd) {
+		t.Error("with --no-save, quitting should not ask")
+	}
+	m.filePath = filepath.Join(t.TempDir(), "out.txt")
+	if _, cmd := m.handleKey(keyMsg("q")); !isQuit(cmd) || m.confirmQuit {
+		t.Error("with --no-save, quitting a named document should not ask")
+	}
+}
+
+func TestOpenDirtyAsksFirst(t *testing.T) {
+	m := newDirtyFileModel(t)
+	other := filepath.Join(t.TempDir(), "other.txt")
+	os.WriteFile(other, []byte("xyz\n"), 0644)
+
+	m.openPathPrompt(promptOpen)
+	m.pathInput = other
+	m.handleKey(keyMsg("enter"))
+	if !m.confirmQuit || m.filePath == other {
+		t.Fatal("opening with unsaved changes should ask first")
+	}
+	if _, cmd := m.handleKey(keyMsg("esc")); isQuit(cmd) || m.confirmQuit {
+		t.Error("esc should cancel opening")
+	}
+	if m.canvas.Get(0, 0).char != "A" || m.filePath == other || !m.isModified() {
+		t.Error("cancelling should leave the document alone")
+	}
+
+	m.requestOpen(other)
+	if _, cmd := m.handleKey(keyMsg("d")); isQuit(cmd) || m.discardOnQuit {
+		t.Error("discarding to open another file should not quit")
+	}
+	if m.filePath != other || m.canvas.Get(0, 0).char != "x" {
+		t.Errorf("opened %s, want %s", m.filePath, other)
+	}
+}
+
+func TestOpenDirtyUnnamedSavesFirst(t *testing.T) {
+	m := newLayerModel()
+	m.canvas.Set(0, 0, "A", "white", "transparent")
+	m.saveToHistory()
+	dir := t.TempDir()
+	other := filepath.Join(dir, "other.txt")
+	os.WriteFile(other, []byte("xyz\n"), 0644)
+
+	m.requestOpen(other)
+	m.handleKey(keyMsg("s"))
+	if !m.showPathPrompt || m.pathPromptMode != promptSaveAs {
+		t.Fatal("saving an unnamed document before opening should ask for a path")
+	}
+	saved := filepath.Join(dir, "art.txt")
+	m.pathInput = saved
+	m.handleKey(keyMsg("enter"))
+	if data, err := os.ReadFile(saved); err != nil || !strings.HasPrefix(string(data), "A") {
+		t.Errorf("saved file = %q, %v", data, err)
+	}
+	if m.filePath != other || m.pendingOpen != "" {
+		t.Errorf("opened %s after saving, want %s", m.filePath, other)
+	}
+}
+
+func TestTerminalResizeKeepsSavedState(t *testing.T) {
+	m := newDirtyFileModel(t)
+	m.save()
+	m.handleResize(tea.WindowSizeMsg{Width: m.canvas.width + 5, Height: m.canvas.height + controlBarHeight + 2})
+	if m.isModified() {
+		t.Error("following the terminal size shouldn't modify a saved document")
+	}
+	if _, cmd := m.handleKey(keyMsg("q")); !isQuit(cmd) {
+		t.Error("quitting after a resize should not ask")
+	}
+
+	m = newDirtyFileModel(t)
+	m.handleResize(tea.WindowSizeMsg{Width: m.canvas.width + 5, Height: m.canvas.height + controlBarHeight + 2})
+	if !m.isModified() {
+		t.Error("a resize shouldn't hide unsaved changes")
+	}
+}
@@ -0,0 +1,120 @@
+package main
+
+// fillMode is how the shape tools draw: the outline alone, the whole shape
+// filled, or the outline around a filled inside.
+type fillMode int
+
+const (
+	fillNone fillMode = iota
+	fillOnly
+	fillOutlined
+)
+
+// fillModeNames are shown in the toolbar, indexed by fillMode.
+var fillModeNames = []string{"Outline", "Filled", "Outline + Fill"}
+
+// fillTools are the tools that draw shapes which can be filled.
+var fillTools = map[string]bool{"Rectangle": true, "Box": true, "Ellipse": true}
+
+// toolFills reports whether the current tool draws shapes that can be
+// filled. Of the polyline modes, only polygons can.
+func (m *model) toolFills() bool {
+	return fillTools[m.selectedTool] || m.selectedTool == "Polyline" && m.polylineMode().closed
+}
+
+// shapeKeyPress handles the fill mode key for the shape tools' OnKeyPress.
+// The Fill Mode action passes its action id as the key, so the mode cycles
+// on whichever key the action is bound to.
+func (m *model) shapeKeyPress(key string) bool {
+	if key != actionFillMode {
+		return false
+	}
+	m.fillMode = (m.fillMode + 1) % fillMode(len(fillModeNames))
+	return true
+}
+
+// drawsOutline reports whether the shape tools draw outlines.
+func (m *model) drawsOutline() bool {
+	return m.fillMode != fillOnly
+}
+
+// shapeArea returns the points covered by a convex shape with the given
+// outline: every point between the outline's ends on each row.
+func shapeArea(outline map[[2]int]bool) map[[2]int]bool {
+	spans := map[int][2]int{}
+	for p := range outline {
+		if s, ok := spans[p[0]]; ok {
+			spans[p[0]] = [2]int{min(s[0], p[1]), max(s[1], p[1])}
+		} else {
+			spans[p[0]] = [2]int{p[1], p[1]}
+		}
+	}
+	area := map[[2]int]bool{}
+	for y, s := range spans {
+		for x := s[0]; x <= s[1]; x++ {
+			area[[2]int{y, x}] = true
+		}
+	}
+	return area
+}
+
+// fillPoints returns the points the fill covers for a shape's outline: none
+// without a fill, the whole shape when filled only, and the inside when it
+// is outlined.
+func (m *model) fillPoints(outline map[[2]int]bool) map[[2]int]bool {
+	return m.fillAreaPoints(outline, shapeArea(outline))
+}
+
+// fillAreaPoints is fillPoints for a shape that may not be convex, given
+// the area it covers including its outline.
+func (m *model) fillAreaPoints(outline, area map[[2]int]bool) map[[2]int]bool {
+	if m.fillMode == fillNone {
+		return nil
+	}
+	if m.fillMode == fillOutlined {
+		for p := range outline {
+			delete(area, p)
+		}
+	}
+	return area
+}
+
+// fillCell returns the cell the shape tools fill with: the fill glyph and
+// color over the current background.
+func (m *model) fillCell() Cell {
+	return Cell{char: m.fillChar, foregroundColor: m.fillColor, backgroundColor: m.backgroundColor, attrs: m.textAttrs}
+}
+
+// plotFill fills a point on the current tool's grid.
+func (m *model) plotFill(y, x int) {
+	switch m.toolGrid() {
+	case pixelGrid:
+		m.setPixel(y, x, m.fillColor)
+	case brailleGrid:
+		m.setDot(y, x, m.fillColor)
+	default:
+		m.canvas.SetCell(y, x, m.fillCell())
+	}
+}
+
+// drawShape draws a shape's outline with the brush and fills it as the
+// fill mode says.
+func (m *model) drawShape(outline map[[2]int]bool) {
+	if m.drawsOutline() {
+		for p := range outline {
+			m.plot(p[0], p[1])
+		}
+	}
+	for p := range m.fillPoints(outline) {
+		m.plotFill(p[0], p[1])
+	}
+}
+
+// fillPreview renders the fill's preview at a point. ok is false if the
+// fill doesn't cover it.
+func (m *model) fillPreview(row, col int) (string, bool) {
+	if !m.previewFill[[2]int{row, col}] {
+		return "", false
+	}
+	return cellStyle(m.fillColor, m.backgroundColor, m.textAttrs).Render(m.fillChar), true
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRectangleFillModes(t *testing.T) {
+	tests := []struct {
+		mode           fillMode
+		corner, inside string
+	}{
+		{fillNone, "#", " "},
+		{fillOnly, "░", "░"},
+		{fillOutlined, "#", "░"},
+	}
+	for _, tt := range tests {
+		t.Run(fillModeNames[tt.mode], func(t *testing.T) {
+			m := newDrawModel("Rectangle", 9, 7)
+			m.fillMode = tt.mode
+			drag(m, 1, 1, 4, 5)
+			checkCells(t, m, 9, 7, map[[2]int]string{{1, 1}: tt.corner, {4, 5}: tt.corner, {2, 3}: tt.inside, {5, 3}: " ", {2, 6}: " "})
+			if tt.inside == "░" && m.canvas.Get(2, 3).foregroundColor != "blue" {
+				t.Error("the fill should use the fill color")
+			}
+		})
+	}
+}
+
+func TestBoxFillKeepsBorder(t *testing.T) {
+	m := newDrawModel("Box", 9, 7)
+	m.fillMode = fillOutlined
+	drag(m, 0, 0, 3, 3)
+	checkCells(t, m, 9, 7, map[[2]int]string{{0, 0}: "┌", {0, 1}: "─", {1, 0}: "│", {1, 1}: "░", {2, 2}: "░", {3, 3}: "┘"})
+}
+
+func TestEllipseFill(t *testing.T) {
+	m := newDrawModel("Ellipse", 9, 7)
+	m.fillMode = fillOnly
+	drag(m, 0, 0, 6, 8)
+	checkCells(t, m, 9, 7, map[[2]int]string{{3, 4}: "░", {3, 0}: "░", {0, 4}: "░", {0, 0}: " ", {6, 8}: " "})
+}
+
+// TestFillPreviewMatches checks that the preview covers exactly the cells a
+// shape draws, with the same glyphs.
+func TestFillPreviewMatches(t *testing.T) {
+	for _, tool := range []string{"Rectangle", "Box", "Ellipse"} {
+		for mode := range fillModeNames {
+			m := newDrawModel(tool, 9, 7)
+			m.fillMode = fillMode(mode)
+			m.beginStroke(1, 1)
+			m.dragStroke(5, 7)
+			previews := map[[2]int]string{}
+			for y := 0; y < 7; y++ {
+				for x := 0; x < 9; x++ {
+					if rendered, ok := m.tool().RenderPreview(m, y, x); ok {
+						previews[[2]int{y, x}] = rendered
+					}
+				}
+			}
+			m.endStroke(5, 7)
+
+			for y := 0; y < 7; y++ {
+				for x := 0; x < 9; x++ {
+					cell := m.canvas.Get(y, x)
+					rendered, previewed := previews[[2]int{y, x}]
+					if drawn := cell.char != " "; drawn != previewed || drawn && !strings.Contains(rendered, cell.char) {
+						t.Errorf("%s %s: cell (%d,%d) drew %q but previewed %q", tool, fillModeNames[mode], y, x, cell.char, rendered)
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestFillModeKey(t *testing.T) {
+	m := newDrawModel("Rectangle", 9, 7)
+	m.width = 120
+	for _, want := range []fillMode{fillOnly, fillOutlined, fillNone} {
+		pressKeys(m, "F")
+		if m.fillMode != want {
+			t.Errorf("fill mode = %s, want %s", fillModeNames[m.fillMode], fillModeNames[want])
+		}
+	}
+	pressKeys(m, "F")
+	if bar := m.renderControlBar(); !strings.Contains(bar, "Filled ░") {
+		t.Error("the toolbar should show the fill mode and glyph")
+	}
+
+	m.setTool("Line")
+	pressKeys(m, "F")
+	if m.fillMode != fillOnly {
+		t.Error("the fill mode key should only work with shape tools")
+	}
+
+	// The key reaches the tool's OnKeyPress whatever it is bound to
+	m.setTool("Ellipse")
+	m.config.Keymap, _ = resolveKeymap([]keyBinding{{actionFillMode, []string{"z"}}})
+	pressKeys(m, "F", "z")
+	if m.fillMode != fillOutlined || m.circleMode {
+		t.Errorf("fill mode = %s, want the rebound key to cycle it", fillModeNames[m.fillMode])
+	}
+}
+
+func TestSetFillFromBrush(t *testing.T) {
+	m := newDrawModel("Rectangle", 9, 7)
+	m.runAction("set-fill-glyph")
+	m.foregroundColor = "green"
+	m.runAction("set-fill-color")
+	if m.fillChar != "#" || m.fillColor != "green" {
+		t.Errorf("fill is %q %s, want # green", m.fillChar, m.fillColor)
+	}
+}
+
+func TestPixelRectangleFill(t *testing.T) {
+	m := newDrawModel("Rectangle", 4, 3)
+	m.grid = pixelGrid
+	m.fillMode, m.fillColor = fillOutlined, "blue"
+	drag(m, 0, 0, 2, 3)
+	checkPixels(t, m, map[[2]int]string{{0, 0}: "red", {4, 3}: "red", {1, 1}: "blue", {3, 2}: "blue", {5, 1}: "transparent"})
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"fmt"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// isEmptyCell reports whether a cell shows nothing: a plain space over a
+// transparent background.
+func isEmptyCell(c Cell) bool {
+	return c.char == " " && c.backgroundColor == "transparent" && c.attrs == 0
+}
+
+// contentBounds returns the rows and columns spanned by the cells of all
+// layers that differ from their layer's blank cell. ok is false if every
+// cell is blank.
+func (m *model) contentBounds() (top, left, bottom, right int, ok bool) {
+	m.ensureLayers()
+	top, left, bottom, right = -1, -1, -1, -1
+	for i := range m.layers {
+		c := m.layerCanvas(i)
+		blank := layerBlank(i)
+		for row := 0; row < c.height; row++ {
+			for col := 0; col < c.width; col++ {
+				if c.cells[row][col] == blank {
+					continue
+				}
+				if !ok {
+					top, left, bottom, right, ok = row, col, row, col, true
+				}
+				top, bottom = min(top, row), max(bottom, row)
+				left, right = min(left, col), max(right, col)
+			}
+		}
+	}
+	return top, left, bottom, right, ok
+}
+
+// contentSize returns the smallest canvas size, anchored at the top-left,
+// that holds all of the content.
+func (m *model) contentSize() (width, height int) {
+	if _, _, bottom, right, ok := m.contentBounds(); ok {
+		return right + 1, bottom + 1
+	}
+	return 0, 0
+}
+
+// fitFixedSize applies the fixed canvas size when the canvas is first set up.
+// If the loaded content doesn't fit, nothing is cropped yet: the canvas keeps
+// all of it and the user is asked whether to grow the canvas or crop.
+func (m *model) fitFixedSize() {
+	width, height := m.contentSize()
+	if width > m.fixedWidth || height > m.fixedHeight {
+		m.confirmCrop = true
+		m.cropWidth, m.cropHeight = m.fixedWidth, m.fixedHeight
+		m.fixedWidth = max(m.fixedWidth, width)
+		m.fixedHeight = max(m.fixedHeight, height)
+	}
+	m.resizeLayers(m.fixedWidth, m.fixedHeight)
+}
+
+// handleCropConfirmKey handles the choice between growing the canvas to fit
+// the loaded content and cropping it to the requested size. Cropping is a
+// history state, so it can be undone and counts as an unsaved change. Other
+// keys are ignored until the user chooses, apart from ctrl+c which quits.
+func (m *model) handleCropConfirmKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "g":
+		m.confirmCrop = false
+	case "c":
+		m.confirmCrop = false
+		m.fixedWidth, m.fixedHeight = m.cropWidth, m.cropHeight
+		m.resizeLayers(m.fixedWidth, m.fixedHeight)
+		m.saveToHistoryAs("Crop")
+	case "ctrl+c":
+		return m, tea.Quit
+	}
+	return m, nil
+}
+
+func (m *model) renderCropConfirm() string {
+	dialogStyle := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(themeColor(m.config.Theme.MenuBorder)).
+		Padding(0, 1)
+	accentStyle := lipgloss.NewStyle().Foreground(themeColor(m.config.Theme.ToolbarHighlightBg))
+	name := "The input"
+	if m.filePath != "" {
+		name = shortPath(m.filePath)
+	}
+	return dialogStyle.Render(fmt.Sprintf("%s needs %dx%d, larger than the %dx%d canvas.\n",
+		name, m.fixedWidth, m.fixedHeight, m.cropWidth, m.cropHeight) +
+		accentStyle.Render("g") + fmt.Sprintf(" grow canvas to %dx%d  ", m.fixedWidth, m.fixedHeight) +
+		accentStyle.Render("c") + fmt.Sprintf(" crop to %dx%d, losing the rest when saved", m.cropWidth, m.cropHeight))
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func newFitModel(text string, width, height int) *model {
+	m := initialModel()
+	m.config.Theme = defaultTheme()
+	m.filePath = "/tmp/art.txt"
+	m.loadText(text)
+	m.fixedWidth, m.fixedHeight = width, height
+	m.Update(tea.WindowSizeMsg{Width: 80, Height: 24})
+	return m
+}
+
+func TestLoadTextKeepsLargeInput(t *testing.T) {
+	line := strings.Repeat("x", 120)
+	m := initialModel()
+	m.loadText(line + "\n" + line)
+	if m.canvas.width != 120 || m.canvas.Get(1, 119).char != "x" {
+		t.Errorf("canvas is %dx%d, want the whole 120x2 input", m.canvas.width, m.canvas.height)
+	}
+}
+
+func TestContentSize(t *testing.T) {
+	m := newLayerModel()
+	if w, h := m.contentSize(); w != 0 || h != 0 {
+		t.Errorf("blank canvas content size = %dx%d, want 0x0", w, h)
+	}
+	m.canvas.Set(1, 2, "A", "white", "transparent")
+	if w, h := m.contentSize(); w != 3 || h != 2 {
+		t.Errorf("content size = %dx%d, want 3x2", w, h)
+	}
+}
+
+func TestFittingContentIsNotQuestioned(t *testing.T) {
+	m := newFitModel("ab\ncd", 10, 5)
+	if m.confirmCrop || m.canvas.width != 10 || m.canvas.height != 5 {
+		t.Errorf("confirmCrop = %v, canvas %dx%d, want 10x5 without asking",
+			m.confirmCrop, m.canvas.width, m.canvas.height)
+	}
+}
+
+func TestOversizedContentAsksBeforeCropping(t *testing.T) {
+	m := newFitModel("abcdef\n\n\nxyz", 4, 2)
+	if !m.confirmCrop {
+		t.Fatal("content larger than the canvas should ask first")
+	}
+	if m.canvas.width != 6 || m.canvas.height != 4 || m.canvas.Get(3, 2).char != "z" {
+		t.Fatalf("nothing should be cropped before the user chooses, canvas %dx%d",
+			m.canvas.width, m.canvas.height)
+	}
+	if !strings.Contains(m.View(), "grow canvas to 6x4") {
+		t.Error("view should offer to grow the canvas")
+	}
+	m.handleKey(keyMsg("x"))
+	if !m.confirmCrop {
+		t.Error("other keys should not dismiss the prompt")
+	}
+}
+
+func TestGrowCanvasToFit(t *testing.T) {
+	m := newFitModel("abcdef\n\n\nxyz", 4, 2)
+	m.handleKey(keyMsg("g"))
+	if m.confirmCrop || m.fixedWidth != 6 || m.fixedHeight != 4 || m.canvas.Get(0, 5).char != "f" {
+		t.Errorf("g should keep everything on a 6x4 canvas, got %dx%d", m.fixedWidth, m.fixedHeight)
+	}
+	if m.isModified() {
+		t.Error("growing the canvas should not be an unsaved change")
+	}
+}
+
+func TestCropCanvasIsUndoable(t *testing.T) {
+	m := newFitModel("abcdef\n\n\nxyz", 4, 2)
+	m.handleKey(keyMsg("c"))
+	if m.confirmCrop || m.canvas.width != 4 || m.canvas.height != 2 {
+		t.Fatalf("c should crop to 4x2, got %dx%d", m.canvas.width, m.canvas.height)
+	}
+	if !m.isModified() {
+		t.Error("cropping should be an unsaved change, so quitting asks")
+	}
+	m.undo()
+	if m.canvas.width != 6 || m.canvas.Get(3, 2).char != "z" {
+		t.Error("undo should bring the cropped content back")
+	}
+}
@@ -0,0 +1,180 @@
+package main
+
+import tea "github.com/charmbracelet/bubbletea"
+
+// floatingSelection is content lifted off the active layer, or pasted, that
+// can be moved around before it is put down. While it floats, the working
+// canvas shows it over the layer with transparency, but nothing is recorded
+// in history until it is committed.
+type floatingSelection struct {
+	active   bool
+	cells    [][]Cell
+	y, x     int    // canvas position of the top-left cell
+	base     Canvas // the layer underneath the floating content
+	original Canvas // the layer before lifting or pasting, for cancelling
+	before   selectionState
+	mask     cellMask // floating cells relative to the top-left, nil if all
+	label    string
+
+	// dragging is set while the Select tool moves the content. grabY and
+	// grabX are where it was grabbed, relative to its top-left cell.
+	dragging     bool
+	grabY, grabX int
+}
+
+// floatingNudges maps keys to the step they move floating content by.
+var floatingNudges = map[string][2]int{
+	"up": {-1, 0}, "down": {1, 0}, "left": {0, -1}, "right": {0, 1},
+}
+
+// insideSelection reports whether a canvas cell is selected, so pressing
+// there moves the selection.
+func (m *model) insideSelection(y, x int) bool {
+	return m.isSelected(y, x)
+}
+
+// liftSelection lifts the selected cells off the active layer so they can
+// be moved. The space they leave is blank. With a mask, only the masked
+// cells are lifted and the rest of the rectangle stays on the layer.
+func (m *model) liftSelection() bool {
+	if m.floating.active {
+		return true
+	}
+	minY, minX, maxY, maxX, ok := m.selectionBounds()
+	if !ok || !m.checkLayerEditable() {
+		return false
+	}
+	cells := make([][]Cell, maxY-minY+1)
+	base := m.canvas.Copy()
+	blank := layerBlank(m.activeLayer)
+	var mask cellMask
+	if m.selection.mask != nil {
+		mask = cellMask{}
+	}
+	for row := range cells {
+		cells[row] = make([]Cell, maxX-minX+1)
+		for col := range cells[row] {
+			if !m.isSelected(minY+row, minX+col) {
+				cells[row][col] = transparentCell
+				continue
+			}
+			cells[row][col] = m.canvas.cells[minY+row][minX+col]
+			base.cells[minY+row][minX+col] = blank
+			if mask != nil {
+				mask[[2]int{row, col}] = true
+			}
+		}
+	}
+	m.startFloating(cells, mask, minY, minX, base, "Move Selection")
+	return true
+}
+
+// floatClipboard pastes the clipboard as floating content at a position.
+func (m *model) floatClipboard(y, x int) {
+	m.startFloating(m.clipboard.cells, m.clipboard.mask, y, x, m.canvas.Copy(), "Paste")
+}
+
+func (m *model) startFloating(cells [][]Cell, mask cellMask, y, x int, base Canvas, label string) {
+	m.floating = floatingSelection{
+		active:   true,
+		cells:    cells,
+		mask:     mask,
+		base:     base,
+		original: m.canvas.Copy(),
+		before:   m.selection,
+		label:    label,
+	}
+	m.moveFloating(y, x)
+}
+
+// moveFloating places the floating content with its top-left cell at y, x
+// and moves the selection with it. Empty cells let the layer show through,
+// and cells off the canvas aren't shown.
+func (m *model) moveFloating(y, x int) {
+	f := &m.floating
+	f.y, f.x = y, x
+	m.canvas = f.base.Copy()
+	for row := range f.cells {
+		for col, cell := range f.cells[row] {
+			if existing := m.canvas.Get(y+row, x+col); existing != nil && !isEmptyCell(cell) {
+				m.canvas.SetCell(y+row, x+col, overlayCell(*existing, cell))
+			}
+		}
+	}
+	m.selection = selectionState{
+		active: true,
+		startY: y - 1,
+		startX: x - 1,
+		endY:   y + len(f.cells),
+		endX:   x + len(f.cells[0]),
+	}
+	if f.mask != nil {
+		m.selection.mask = cellMask{}
+		for p := range f.mask {
+			m.selection.mask[[2]int{y + p[0], x + p[1]}] = true
+		}
+	}
+}
+
+// commitFloating puts the floating content down where it is, as one history
+// step for the whole move or paste. The selection stays around it.
+func (m *model) commitFloating() {
+	if !m.floating.active {
+		return
+	}
+	f := m.floating
+	m.floating = floatingSelection{}
+	if !m.canvas.Equals(f.original) {
+		m.saveToHistoryAs(f.label)
+	}
+}
+
+// cancelFloating drops the floating content, restoring the layer and the
+// selection as they were before it was lifted or pasted.
+func (m *model) cancelFloating() {
+	if !m.floating.active {
+		return
+	}
+	m.canvas = m.floating.original
+	m.selection = m.floating.before
+	m.floating = floatingSelection{}
+}
+
+// nudgesSelection reports whether a key moves the selection's contents:
+// the arrow keys do with the Select tool, unless a menu is open or they
+// move the keyboard cursor.
+func (m *model) nudgesSelection(key string) bool {
+	_, ok := floatingNudges[key]
+	return ok && m.selectedTool == "Select" && m.selection.active &&
+		m.activeMenu() < 0 && !m.keyboardCursor && !m.mouseDown
+}
+
+// handleFloatingKey handles keys while content is floating: arrows nudge
+// it, enter puts it down and esc or undo cancel the move. Any other key
+// puts it down first and then falls through, so commands see the result.
+// Keyboard cursor keys fall through as they are, so the cursor can drag it.
+func (m *model) handleFloatingKey(msg tea.KeyMsg) bool {
+	key := msg.String()
+	if m.keyboardCursor && m.activeMenu() < 0 {
+		if isCursorKey(key) {
+			return false
+		}
+	} else if step, ok := floatingNudges[key]; ok && m.activeMenu() < 0 && !m.mouseDown {
+		m.moveFloating(m.floating.y+step[0], m.floating.x+step[1])
+		return true
+	}
+	switch {
+	case key == "enter":
+		m.commitFloating()
+		return true
+	case key == "esc", m.keyAction(key) == "undo":
+		if m.mouseDown {
+			m.mouseDown = false
+			m.showPreview = false
+		}
+		m.cancelFloating()
+		return true
+	}
+	m.commitFloating()
+	return false
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+// newFloatingModel returns a 6x4 canvas with "AB" selected at (1,1) and a
+// "C" outside the selection, using the Select tool.
+func newFloatingModel() *model {
+	m := initialModel()
+	m.canvas = NewCanvas(6, 4)
+	m.width = 80
+	m.height = 20
+	m.config.Theme = defaultTheme()
+	m.selectedTool = "Select"
+	m.canvas.Set(1, 1, "A", "white", "transparent")
+	m.canvas.Set(1, 2, "B", "white", "transparent")
+	m.canvas.Set(3, 5, "C", "white", "transparent")
+	m.saveToHistory()
+	m.selection = selectionState{active: true, startY: 0, startX: 0, endY: 2, endX: 3}
+	return m
+}
+
+func TestDragSelectionFloatsContents(t *testing.T) {
+	m := newFloatingModel()
+	steps := len(m.history)
+	drag(m, 1, 2, 2, 4)
+
+	checkCells(t, m, 6, 4, map[[2]int]string{{1, 1}: " ", {1, 2}: " ", {2, 3}: "A", {2, 4}: "B", {3, 5}: "C"})
+	if !m.floating.active {
+		t.Fatal("contents should still float after the drag")
+	}
+	if len(m.history) != steps {
+		t.Error("a floating move should not be recorded until it is put down")
+	}
+	if minY, minX, maxY, maxX, _ := m.selectionBounds(); minY != 2 || minX != 3 || maxY != 2 || maxX != 4 {
+		t.Errorf("selection is (%d,%d)-(%d,%d), want (2,3)-(2,4)", minY, minX, maxY, maxX)
+	}
+
+	// Dragging again moves the same floating contents
+	drag(m, 2, 3, 3, 3)
+	checkCells(t, m, 6, 4, map[[2]int]string{{2, 3}: " ", {3, 3}: "A", {3, 4}: "B"})
+}
+
+func TestCommitFloatingIsOneStep(t *testing.T) {
+	m := newFloatingModel()
+	steps := len(m.history)
+	drag(m, 1, 1, 2, 1)
+	pressKeys(m, "right", "enter")
+
+	if m.floating.active {
+		t.Fatal("enter should put the contents down")
+	}
+	if len(m.history) != steps+1 || m.history[m.historyIndex].label != "Move Selection" {
+		t.Fatalf("want one Move Selection step, got %d steps", len(m.history)-steps)
+	}
+	checkCells(t, m, 6, 4, map[[2]int]string{{2, 2}: "A", {2, 3}: "B", {1, 1}: " "})
+
+	m.undo()
+	checkCells(t, m, 6, 4, map[[2]int]string{{1, 1}: "A", {1, 2}: "B", {2, 2}: " "})
+}
+
+func TestArrowsNudgeSelection(t *testing.T) {
+	m := newFloatingModel()
+	pressKeys(m, "down", "down", "right")
+	if !m.floating.active {
+		t.Fatal("arrows should lift the selection")
+	}
+	checkCells(t, m, 6, 4, map[[2]int]string{{3, 2}: "A", {3, 3}: "B", {1, 1}: " "})
+
+	m.selectedTool = "Point"
+	m.floating = floatingSelection{}
+	m.selection.active = true
+	if m.nudgesSelection("up") {
+		t.Error("arrows should only nudge with the Select tool")
+	}
+}
+
+func TestCancelFloatingRestores(t *testing.T) {
+	for _, key := range []string{"esc", "u"} {
+		m := newFloatingModel()
+		before, selection := m.canvas.Copy(), m.selection
+		steps := len(m.history)
+		drag(m, 1, 1, 3, 3)
+		pressKeys(m, "left")
+		pressKeys(m, key)
+
+		if m.floating.active || !m.canvas.Equals(before) || !reflect.DeepEqual(m.selection, selection) {
+			t.Errorf("%s should put the contents and selection back", key)
+		}
+		if len(m.history) != steps {
+			t.Errorf("%s should not record a step", key)
+		}
+	}
+}
+
+func TestFloatingShowsLayerThrough(t *testing.T) {
+	m := newFloatingModel()
+	m.canvas.Set(3, 4, "D", "white", "transparent")
+	m.saveToHistory()
+	m.selection = selectionState{active: true, startY: 0, startX: 0, endY: 2, endX: 4}
+
+	// The blank cell after "AB" lands on "D" without hiding it
+	drag(m, 1, 1, 3, 2)
+	checkCells(t, m, 6, 4, map[[2]int]string{{3, 2}: "A", {3, 3}: "B", {3, 4}: "D"})
+}
+
+func TestClickOutsideCommitsFloating(t *testing.T) {
+	m := newFloatingModel()
+	drag(m, 1, 1, 2, 1)
+	m.beginStroke(0, 5)
+	m.endStroke(0, 5)
+
+	if m.floating.active || m.history[m.historyIndex].label != "Move Selection" {
+		t.Error("clicking outside the selection should put the contents down")
+	}
+	if m.selection.active {
+		t.Error("clicking outside should clear the selection as usual")
+	}
+}
+
+func TestOtherKeyCommitsFloating(t *testing.T) {
+	m := newFloatingModel()
+	pressKeys(m, "down")
+	pressKeys(m, "x")
+
+	if m.floating.active || m.history[m.historyIndex].label != "Move Selection" {
+		t.Error("other keys should put the contents down before running")
+	}
+}
+
+func TestPasteFloats(t *testing.T) {
+	m := newFloatingModel()
+	m.copySelection()
+	m.selection.active = false
+	m.mouseX, m.mouseY = 3, controlBarHeight
+	steps := len(m.history)
+	m.paste()
+
+	if !m.floating.active || len(m.history) != steps {
+		t.Fatal("paste should float the clipboard without recording a step")
+	}
+	pressKeys(m, "down", "enter")
+	checkCells(t, m, 6, 4, map[[2]int]string{{1, 3}: "A", {1, 4}: "B", {1, 1}: "A", {1, 2}: "B"})
+	if len(m.history) != steps+1 || m.history[m.historyIndex].label != "Paste" {
+		t.Error("putting a paste down should record one Paste step")
+	}
+}
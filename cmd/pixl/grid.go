@@ -0,0 +1,115 @@
+package main
+
+// drawGrid is what the drawing tools draw on: whole cells, or a finer grid
+// of dots with several to a cell.
+type drawGrid int
+
+const (
+	cellGrid drawGrid = iota
+	pixelGrid
+	brailleGrid
+)
+
+type gridSpec struct {
+	name       string
+	rows, cols int             // dots per cell
+	tools      map[string]bool // tools that draw dots; the others use cells
+}
+
+// drawGrids describes each grid, indexed by drawGrid.
+var drawGrids = []gridSpec{
+	{"Cells", 1, 1, nil},
+	{"Pixels", 2, 1, map[string]bool{"Point": true, "Line": true, "Rectangle": true, "Ellipse": true, "Fill": true}},
+	{"Braille", 4, 2, map[string]bool{"Point": true, "Line": true, "Ellipse": true}},
+}
+
+// toolGrid returns the grid the current tool draws on. Its coordinates are
+// then dot rows and columns rather than cells.
+func (m *model) toolGrid() drawGrid {
+	if drawGrids[m.grid].tools[m.selectedTool] {
+		return m.grid
+	}
+	return cellGrid
+}
+
+// toggleGrid switches between drawing on a grid and on cells, ending any
+// stroke in progress first so that it isn't finished on a different grid.
+func (m *model) toggleGrid(g drawGrid) {
+	if m.mouseDown {
+		m.endStroke(m.hoverRow, m.hoverCol)
+	}
+	if m.grid == g {
+		m.grid = cellGrid
+	} else {
+		m.grid = g
+	}
+}
+
+// gridSize returns the number of rows and columns the current tool draws
+// on.
+func (m *model) gridSize() (rows, cols int) {
+	spec := drawGrids[m.toolGrid()]
+	return m.canvas.height * spec.rows, m.canvas.width * spec.cols
+}
+
+// clampToGrid keeps a point on the grid the current tool draws on.
+func (m *model) clampToGrid(y, x int) (int, int) {
+	rows, cols := m.gridSize()
+	y = max(min(y, rows-1), 0)
+	x = max(min(x, cols-1), 0)
+	return y, x
+}
+
+// strokePoint converts a canvas cell to the point the current tool draws
+// at. On a grid of dots this is the dot the keyboard cursor or the mouse is
+// on. Mouse events put it on the cell's top-left dot unless the terminal
+// reports the mouse in pixels (see pixel_mouse.go).
+func (m *model) strokePoint(y, x int) (int, int) {
+	g := m.toolGrid()
+	if g == cellGrid {
+		return y, x
+	}
+	spec := drawGrids[g]
+	return y*spec.rows + min(m.hoverDotY, spec.rows-1), x*spec.cols + min(m.hoverDotX, spec.cols-1)
+}
+
+// moveGridCursor moves the keyboard cursor by dots on the current tool's
+// grid.
+func (m *model) moveGridCursor(dy, dx int) {
+	spec := drawGrids[m.toolGrid()]
+	y, x := m.strokePoint(m.hoverRow, m.hoverCol)
+	y, x = m.clampToGrid(y+dy, x+dx)
+	m.hoverRow, m.hoverDotY = y/spec.rows, y%spec.rows
+	m.hoverCol, m.hoverDotX = x/spec.cols, x%spec.cols
+}
+
+// dotColor returns the color the stroke preview or the cursor draws a dot
+// in. ok is false if neither covers it. The cursor isn't drawn in the
+// transparent color, which would hide it.
+func (m *model) dotColor(y, x int) (color string, ok bool) {
+	if m.showPreview {
+		if m.previewFill[[2]int{y, x}] {
+			return m.fillColor, true
+		}
+		if _, ok := m.tool().RenderPreview(m, y, x); ok {
+			return m.foregroundColor, true
+		}
+	}
+	if m.mouseDown || !m.cursorVisible || m.foregroundColor == "transparent" {
+		return "", false
+	}
+	cy, cx := m.strokePoint(m.hoverRow, m.hoverCol)
+	return m.foregroundColor, y == cy && x == cx
+}
+
+// renderGridCell renders a cell with the stroke preview and cursor drawn on
+// its dots. ok is false if neither is on the cell.
+func (m *model) renderGridCell(row, col int) (string, bool) {
+	switch m.toolGrid() {
+	case pixelGrid:
+		return m.renderPixels(row, col)
+	case brailleGrid:
+		return m.renderBraille(row, col)
+	}
+	return "", false
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestToggleGrid(t *testing.T) {
+	m := newDrawModel("Line", 10, 10)
+	m.runAction("pixel-mode")
+	if m.grid != pixelGrid {
+		t.Fatalf("grid = %d, want pixels", m.grid)
+	}
+	m.runAction("braille-mode")
+	if m.grid != brailleGrid {
+		t.Fatalf("grid = %d, want braille replacing pixels", m.grid)
+	}
+	m.width = 80
+	if bar := m.renderControlBar(); !strings.Contains(bar, "Braille") {
+		t.Error("the toolbar should show the grid")
+	}
+	m.runAction("braille-mode")
+	if m.grid != cellGrid {
+		t.Errorf("grid = %d, want cells after toggling braille off", m.grid)
+	}
+}
+
+func TestToolGridFallsBackToCells(t *testing.T) {
+	m := newDrawModel("Fill", 4, 3)
+	m.grid = brailleGrid
+	if m.toolGrid() != cellGrid {
+		t.Error("Fill should draw cells in braille mode")
+	}
+	if y, x := m.strokePoint(2, 3); y != 2 || x != 3 {
+		t.Errorf("strokePoint(2, 3) = (%d,%d), want the cell itself", y, x)
+	}
+
+	// The cursor's dot is kept within a cell of the pixel grid
+	m.hoverDotY, m.hoverDotX = 3, 1
+	m.grid, m.selectedTool = pixelGrid, "Point"
+	if y, x := m.strokePoint(1, 2); y != 3 || x != 2 {
+		t.Errorf("strokePoint(1, 2) = (%d,%d), want the lower pixel (3,2)", y, x)
+	}
+}
+
+func TestGridStrokeClampsToDots(t *testing.T) {
+	m := newDrawModel("Line", 4, 3)
+	m.grid = brailleGrid
+	drag(m, 0, 0, 9, 9)
+	if cell := m.canvas.Get(2, 3); brailleDots(*cell)&brailleBits[3][1] == 0 {
+		t.Errorf("cell (2,3) = %q, want the line to end on its last dot", cell.char)
+	}
+}
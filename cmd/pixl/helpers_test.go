@@ -0,0 +1,93 @@
+package main
+
+import (
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// newDrawModel returns a width x height canvas drawing red "#" glyphs, and
+// blue "░" fills, with the given tool. The keyboard cursor is off.
+func newDrawModel(tool string, width, height int) *model {
+	m := &model{
+		canvas:          NewCanvas(width, height),
+		selectedChar:    "#",
+		foregroundColor: "red",
+		backgroundColor: "transparent",
+		fillChar:        "░",
+		fillColor:       "blue",
+		selectedTool:    tool,
+		drawingTool:     tool,
+		width:           10,
+		height:          11,
+		lastMenu:        -1,
+		config:          Config{MergeBoxBorders: true},
+	}
+	m.saveToHistory()
+	return m
+}
+
+// keyMsg returns the key message for a key as tea.KeyMsg.String names it.
+func keyMsg(s string) tea.KeyMsg {
+	switch s {
+	case "tab":
+		return tea.KeyMsg{Type: tea.KeyTab}
+	case "enter":
+		return tea.KeyMsg{Type: tea.KeyEnter}
+	case "esc":
+		return tea.KeyMsg{Type: tea.KeyEscape}
+	case "up":
+		return tea.KeyMsg{Type: tea.KeyUp}
+	case "down":
+		return tea.KeyMsg{Type: tea.KeyDown}
+	case "left":
+		return tea.KeyMsg{Type: tea.KeyLeft}
+	case "right":
+		return tea.KeyMsg{Type: tea.KeyRight}
+	case "backspace":
+		return tea.KeyMsg{Type: tea.KeyBackspace}
+	case " ":
+		return tea.KeyMsg{Type: tea.KeySpace}
+	}
+	return tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(s)}
+}
+
+// pressKeys sends each key to the model in turn.
+func pressKeys(m *model, keys ...string) {
+	for _, k := range keys {
+		m.handleKey(keyMsg(k))
+	}
+}
+
+// typeText sends each character of s to the model as its own key.
+func typeText(m *model, s string) {
+	for _, r := range s {
+		m.handleKey(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{r}})
+	}
+}
+
+// click presses and releases the pointer on a canvas cell.
+func click(m *model, y, x int) {
+	m.beginStroke(y, x)
+	m.endStroke(y, x)
+}
+
+// drag presses the pointer on one canvas cell and releases it on another.
+func drag(m *model, fromY, fromX, toY, toX int) {
+	m.beginStroke(fromY, fromX)
+	m.dragStroke(toY, toX)
+	m.endStroke(toY, toX)
+}
+
+// checkCells checks the canvas size and the glyphs of the given cells.
+func checkCells(t *testing.T, m *model, width, height int, cells map[[2]int]string) {
+	t.Helper()
+	if m.canvas.width != width || m.canvas.height != height {
+		t.Fatalf("canvas is %dx%d, want %dx%d", m.canvas.width, m.canvas.height, width, height)
+	}
+	for pos, want := range cells {
+		if got := m.canvas.Get(pos[0], pos[1]).char; got != want {
+			t.Errorf("cell (%d,%d) = %q, want %q", pos[0], pos[1], got, want)
+		}
+	}
+}
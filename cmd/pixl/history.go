@@ -1,106 +1,486 @@
 package main
 
+import (
+	"fmt"
+	"time"
+)
+
+// Undo history is a tree of states that stores the changes between states
+// rather than full copies. m.historyBase holds the state at m.historyIndex;
+// every entry but the root describes how to get to its state from its parent.
+
+const (
+	defaultUndoLimit    = 50
+	defaultUndoMemoryMB = 64
+
+	// Approximate in-memory sizes used to keep history within its budget.
+	cellBytes       = 56
+	cellChangeBytes = 3*8 + 2*cellBytes
+	layerMetaBytes  = 64
+)
+
+// historyState is a snapshot of the whole layer stack.
+type historyState struct {
+	layers      []Layer
+	activeLayer int
+}
+
+// layerMeta is the part of a layer that isn't cells.
+type layerMeta struct {
+	name    string
+	visible bool
+	locked  bool
+}
+
+// cellChange records one cell of one layer before and after a change.
+type cellChange struct {
+	layer, row, col int
+	before, after   Cell
+}
+
+// historyEntry is the difference between two neighbouring history states.
+// Changes that keep the layer count and canvas size are stored cell by cell;
+// anything else (adding layers, resizing) keeps full snapshots.
+type historyEntry struct {
+	parent    int // -1 for the root
+	redoChild int // child that redo follows, -1 if none
+	label     string
+	time      time.Time
+
+	cells                     []cellChange
+	metaBefore, metaAfter     []layerMeta
+	activeBefore, activeAfter int
+	before, after             *historyState
+}
+
+func (e historyEntry) size() int {
+	n := len(e.cells)*cellChangeBytes + (len(e.metaBefore)+len(e.metaAfter))*layerMetaBytes
+	for _, s := range []*historyState{e.before, e.after} {
+		if s != nil {
+			for _, l := range s.layers {
+				n += l.canvas.width*l.canvas.height*cellBytes + layerMetaBytes
+			}
+		}
+	}
+	return n
+}
+
+// region describes the area of the canvas an entry changed, e.g. "3,4–10,8".
+func (e historyEntry) region() string {
+	if len(e.cells) == 0 {
+		return ""
+	}
+	minRow, minCol := e.cells[0].row, e.cells[0].col
+	maxRow, maxCol := minRow, minCol
+	for _, c := range e.cells[1:] {
+		minRow, maxRow = min(minRow, c.row), max(maxRow, c.row)
+		minCol, maxCol = min(minCol, c.col), max(maxCol, c.col)
+	}
+	if minRow == maxRow && minCol == maxCol {
+		return fmt.Sprintf("%d,%d", minCol, minRow)
+	}
+	return fmt.Sprintf("%d,%d–%d,%d", minCol, minRow, maxCol, maxRow)
+}
+
+func (s historyState) clone() historyState {
+	layers := make([]Layer, len(s.layers))
+	for i, l := range s.layers {
+		layers[i] = l
+		layers[i].canvas = l.canvas.Copy()
+	}
+	return historyState{layers: layers, activeLayer: s.activeLayer}
+}
+
+func (s historyState) meta() []layerMeta {
+	meta := make([]layerMeta, len(s.layers))
+	for i, l := range s.layers {
+		meta[i] = layerMeta{l.name, l.visible, l.locked}
+	}
+	return meta
+}
+
+func (s *historyState) setMeta(meta []layerMeta) {
+	for i, lm := range meta {
+		s.layers[i].name, s.layers[i].visible, s.layers[i].locked = lm.name, lm.visible, lm.locked
+	}
+}
+
+// sameShape reports whether two states have the same layer count and sizes,
+// so that one can be turned into the other cell by cell.
+func (s historyState) sameShape(o historyState) bool {
+	if len(s.layers) != len(o.layers) {
+		return false
+	}
+	for i := range s.layers {
+		a, b := s.layers[i].canvas, o.layers[i].canvas
+		if a.width != b.width || a.height != b.height {
+			return false
+		}
+	}
+	return true
+}
+
+func metaEqual(a, b []layerMeta) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// apply moves s forward across the entry.
+func (e historyEntry) apply(s *historyState) {
+	if e.after != nil {
+		*s = e.after.clone()
+		return
+	}
+	for _, c := range e.cells {
+		s.layers[c.layer].canvas.cells[c.row][c.col] = c.after
+	}
+	if e.metaAfter != nil {
+		s.setMeta(e.metaAfter)
+	}
+	s.activeLayer = e.activeAfter
+}
+
+// revert moves s back across the entry.
+func (e historyEntry) revert(s *historyState) {
+	if e.before != nil {
+		*s = e.before.clone()
+		return
+	}
+	for _, c := range e.cells {
+		s.layers[c.layer].canvas.cells[c.row][c.col] = c.before
+	}
+	if e.metaBefore != nil {
+		s.setMeta(e.metaBefore)
+	}
+	s.activeLayer = e.activeBefore
+}
+
+func (m *model) snapshot() historyState {
+	m.syncActiveLayer()
+	return historyState{layers: m.layers, activeLayer: m.activeLayer}.clone()
+}
+
+// restore replaces the layer stack with a history state. A fixed canvas size
+// follows the state, since resizing the canvas is undoable.
+func (m *model) restore(state historyState) {
+	state = state.clone()
+	m.layers = state.layers
+	m.activeLayer = state.activeLayer
+	m.canvas = m.layers[m.activeLayer].canvas
+	if m.hasFixedSize() {
+		m.fixedWidth, m.fixedHeight = m.canvas.width, m.canvas.height
+	}
+}
+
+// diffFromBase builds the entry that turns the history base into the current
+// state and brings the base up to date.
+func (m *model) diffFromBase() historyEntry {
+	m.syncActiveLayer()
+	base := &m.historyBase
+	current := historyState{layers: m.layers, activeLayer: m.activeLayer}
+	e := historyEntry{activeBefore: base.activeLayer, activeAfter: m.activeLayer}
+
+	if !base.sameShape(current) {
+		before := *base
+		after := current.clone()
+		e.before, e.after = &before, &after
+		m.historyBase = after.clone()
+		return e
+	}
+
+	for i, l := range current.layers {
+		baseCells := base.layers[i].canvas.cells
+		for row := range l.canvas.cells {
+			for col, cell := range l.canvas.cells[row] {
+				if old := baseCells[row][col]; old != cell {
+					e.cells = append(e.cells, cellChange{i, row, col, old, cell})
+					baseCells[row][col] = cell
+				}
+			}
+		}
+	}
+	if before, after := base.meta(), current.meta(); !metaEqual(before, after) {
+		e.metaBefore, e.metaAfter = before, after
+		base.setMeta(after)
+	}
+	base.activeLayer = m.activeLayer
+	return e
+}
+
+func (m *model) undoLimit() int {
+	if m.config.UndoLimit == 0 {
+		return defaultUndoLimit
+	}
+	return m.config.UndoLimit
+}
+
+func (m *model) undoMemory() int {
+	if m.config.UndoMemoryMB == 0 {
+		return defaultUndoMemoryMB << 20
+	}
+	return m.config.UndoMemoryMB << 20
+}
+
+// trimHistory drops the oldest states until the history fits within the
+// configured depth and memory budget. The oldest state is dropped together
+// with every branch that doesn't lead to the current state. At least one undo
+// step is always kept.
+func (m *model) trimHistory() {
+	limit := m.undoLimit()
+	for {
+		total := 0
+		for _, e := range m.history {
+			total += e.size()
+		}
+		overLimit := limit > 0 && len(m.history) > limit
+		if !overLimit && total <= m.undoMemory() {
+			return
+		}
+
+		// Find the root's child on the path to the current state
+		newRoot := m.historyIndex
+		for newRoot > 0 && m.history[newRoot].parent > 0 {
+			newRoot = m.history[newRoot].parent
+		}
+		if newRoot == 0 || newRoot == m.historyIndex {
+			return
+		}
+
+		keep := make([]bool, len(m.history))
+		keep[newRoot] = true
+		for i := newRoot + 1; i < len(m.history); i++ {
+			keep[i] = keep[m.history[i].parent]
+		}
+		m.pruneHistory(keep)
+	}
+}
+
+// pruneHistory drops the entries not marked in keep, renumbering the rest.
+// The first kept entry becomes the new root.
+func (m *model) pruneHistory(keep []bool) {
+	index := make([]int, len(m.history))
+	var kept []historyEntry
+	for i, e := range m.history {
+		index[i] = -1
+		if keep[i] {
+			index[i] = len(kept)
+			kept = append(kept, e)
+		}
+	}
+	for i := range kept {
+		e := &kept[i]
+		if i == 0 {
+			e.parent = -1
+			e.cells, e.metaBefore, e.metaAfter, e.before, e.after = nil, nil, nil, nil, nil
+		} else {
+			e.parent = index[e.parent]
+		}
+		if e.redoChild >= 0 {
+			e.redoChild = index[e.redoChild]
+		}
+	}
+	m.history = kept
+	m.historyIndex = index[m.historyIndex]
+	if m.savedHistoryIndex >= 0 {
+		m.savedHistoryIndex = index[m.savedHistoryIndex]
+	}
+}
+
 func (m *model) saveToHistory() {
+	m.saveToHistoryAs(m.tool().Name())
+}
+
+// saveToHistoryAs records the current state as a new child of the current
+// history state, labelled for the history panel. Earlier branches are kept.
+func (m *model) saveToHistoryAs(label string) {
 	if len(m.history) == 0 {
-		m.history = []Canvas{m.canvas.Copy()}
+		m.historyBase = m.snapshot()
+		m.history = []historyEntry{{parent: -1, redoChild: -1, label: "Original", time: time.Now()}}
 		m.historyIndex = 0
 		return
 	}
 
-	// Remove any redo history when making a new change
-	if m.historyIndex < len(m.history)-1 {
-		m.history = m.history[:m.historyIndex+1]
-	}
-
-	m.history = append(m.history, m.canvas.Copy())
-	m.historyIndex++
+	e := m.diffFromBase()
+	e.parent = m.historyIndex
+	e.redoChild = -1
+	e.label = label
+	e.time = time.Now()
+	m.history = append(m.history, e)
+	m.historyIndex = len(m.history) - 1
+	m.history[e.parent].redoChild = m.historyIndex
+	m.trimHistory()
+}
 
-	// Limit history size to 50 states
-	if len(m.history) > 50 {
-		m.history = m.history[1:]
-		m.historyIndex--
+// historyUp moves the history base to the parent of the current state.
+func (m *model) historyUp() bool {
+	e := m.history[m.historyIndex]
+	if e.parent < 0 {
+		return false
 	}
+	e.revert(&m.historyBase)
+	m.history[e.parent].redoChild = m.historyIndex
+	m.historyIndex = e.parent
+	return true
+}
+
+// historyDown moves the history base to a child of the current state.
+func (m *model) historyDown(child int) {
+	m.history[child].apply(&m.historyBase)
+	m.history[m.historyIndex].redoChild = child
+	m.historyIndex = child
 }
 
 func (m *model) undo() {
-	if m.historyIndex > 0 {
-		m.historyIndex--
-		m.canvas = m.history[m.historyIndex].Copy()
+	if len(m.history) > 0 && m.historyUp() {
+		m.restore(m.historyBase)
 	}
 }
 
+// redo moves to the child of the current state that was visited last.
 func (m *model) redo() {
-	if m.historyIndex < len(m.history)-1 {
-		m.historyIndex++
-		m.canvas = m.history[m.historyIndex].Copy()
+	if len(m.history) == 0 {
+		return
+	}
+	if child := m.history[m.historyIndex].redoChild; child >= 0 {
+		m.historyDown(child)
+		m.restore(m.historyBase)
 	}
 }
 
-func (m *model) copySelection() {
-	if !m.selection.active {
+// jumpToHistory moves to any state in the history tree by undoing up to the
+// common ancestor and redoing down to the target.
+func (m *model) jumpToHistory(target int) {
+	if target < 0 || target >= len(m.history) || target == m.historyIndex {
 		return
 	}
+	var path []int
+	onPath := map[int]bool{}
+	for n := target; n >= 0; n = m.history[n].parent {
+		path = append(path, n)
+		onPath[n] = true
+	}
+	for !onPath[m.historyIndex] {
+		m.historyUp()
+	}
+	for i := len(path) - 1; i >= 0; i-- {
+		if m.history[path[i]].parent == m.historyIndex {
+			m.historyDown(path[i])
+		}
+	}
+	m.restore(m.historyBase)
+}
 
-	minY, minX, maxY, maxX := normalizeRect(m.selection.startY, m.selection.startX, m.selection.endY, m.selection.endX)
+// historyChildren returns the children of a history state, oldest first.
+func (m *model) historyChildren(n int) []int {
+	var children []int
+	for i := n + 1; i < len(m.history); i++ {
+		if m.history[i].parent == n {
+			children = append(children, i)
+		}
+	}
+	return children
+}
+
+// stepHistoryTime moves to the state saved just before (dir < 0) or after
+// the current one, regardless of branch.
+func (m *model) stepHistoryTime(dir int) {
+	m.jumpToHistory(m.historyIndex + dir)
+}
+
+// switchHistoryBranch moves to the tip of the next (dir > 0) or previous
+// sibling branch at the nearest branch point above the current state.
+func (m *model) switchHistoryBranch(dir int) {
+	if len(m.history) == 0 {
+		return
+	}
+	for n := m.historyIndex; m.history[n].parent >= 0; n = m.history[n].parent {
+		siblings := m.historyChildren(m.history[n].parent)
+		if len(siblings) < 2 {
+			continue
+		}
+		pos := 0
+		for i, sib := range siblings {
+			if sib == n {
+				pos = i
+			}
+		}
+		target := siblings[(pos+dir+len(siblings))%len(siblings)]
+		for m.history[target].redoChild >= 0 {
+			target = m.history[target].redoChild
+		}
+		m.jumpToHistory(target)
+		return
+	}
+}
 
-	// Internal region excludes the visual border
-	internalMinY := minY + 1
-	internalMaxY := maxY - 1
-	internalMinX := minX + 1
-	internalMaxX := maxX - 1
+// copySelection copies the selected cells of the active layer. Cells
+// outside a mask selection are copied as transparent, so pasting leaves
+// the cells under them alone.
+func (m *model) copySelection() {
+	if !m.selection.active {
+		return
+	}
 
-	if internalMaxY < internalMinY || internalMaxX < internalMinX {
-		m.clipboard.cells = nil
-		m.clipboard.width = 0
-		m.clipboard.height = 0
+	minY, minX, maxY, maxX, ok := m.selectionBounds()
+	if !ok {
+		m.clipboard = clipboardData{}
 		return
 	}
 
-	m.clipboard.height = internalMaxY - internalMinY + 1
-	m.clipboard.width = internalMaxX - internalMinX + 1
+	m.clipboard.height = maxY - minY + 1
+	m.clipboard.width = maxX - minX + 1
 	m.clipboard.cells = make([][]Cell, m.clipboard.height)
+	m.clipboard.mask = nil
+	if m.selection.mask != nil {
+		m.clipboard.mask = cellMask{}
+	}
 
 	for y := 0; y < m.clipboard.height; y++ {
 		m.clipboard.cells[y] = make([]Cell, m.clipboard.width)
 		for x := 0; x < m.clipboard.width; x++ {
-			cell := m.canvas.Get(internalMinY+y, internalMinX+x)
-			if cell != nil {
-				m.clipboard.cells[y][x] = *cell
-			} else {
-				m.clipboard.cells[y][x] = Cell{char: " ", foregroundColor: "transparent", backgroundColor: "transparent"}
+			if !m.isSelected(minY+y, minX+x) {
+				m.clipboard.cells[y][x] = transparentCell
+				continue
+			}
+			m.clipboard.cells[y][x] = *m.canvas.Get(minY+y, minX+x)
+			if m.clipboard.mask != nil {
+				m.clipboard.mask[[2]int{y, x}] = true
 			}
 		}
 	}
 }
 
 func (m *model) cutSelection() {
-	if !m.selection.active {
+	if !m.selection.active || !m.checkLayerEditable() {
 		return
 	}
 
 	m.copySelection()
 
-	minY, minX, maxY, maxX := normalizeRect(m.selection.startY, m.selection.startX, m.selection.endY, m.selection.endX)
-
-	internalMinY := minY + 1
-	internalMaxY := maxY - 1
-	internalMinX := minX + 1
-	internalMaxX := maxX - 1
-
-	if internalMaxY >= internalMinY && internalMaxX >= internalMinX {
-		for y := internalMinY; y <= internalMaxY; y++ {
-			for x := internalMinX; x <= internalMaxX; x++ {
-				m.canvas.Set(y, x, " ", "transparent", "transparent")
-			}
+	if mask := m.selectionMask(); len(mask) > 0 {
+		for p := range mask {
+			m.canvas.SetCell(p[0], p[1], layerBlank(m.activeLayer))
 		}
-		m.saveToHistory()
+		m.saveToHistoryAs("Cut")
 	}
 }
 
+// paste floats the clipboard inside the selection, or at the mouse, so it
+// can be moved into place before it is put down.
 func (m *model) paste() {
 	if m.clipboard.cells == nil || m.clipboard.height == 0 || m.clipboard.width == 0 {
 		return
 	}
+	if !m.checkLayerEditable() {
+		return
+	}
 
 	var originY, originX int
 	if m.selection.active {
@@ -115,39 +495,5 @@ func (m *model) paste() {
 		originX, originY = m.screenToCanvas(m.mouseX, m.mouseY)
 	}
 
-	for y := 0; y < m.clipboard.height; y++ {
-		for x := 0; x < m.clipboard.width; x++ {
-			targetY := originY + y
-			targetX := originX + x
-			if targetY < 0 || targetY >= m.canvas.height || targetX < 0 || targetX >= m.canvas.width {
-				continue
-			}
-
-			cell := m.clipboard.cells[y][x]
-			existingCell := m.canvas.Get(targetY, targetX)
-
-			// Skip fully transparent cells
-			if cell.foregroundColor == "transparent" && cell.backgroundColor == "transparent" {
-				continue
-			}
-
-			newChar := cell.char
-			newFg := cell.foregroundColor
-			newBg := cell.backgroundColor
-
-			if cell.foregroundColor == "transparent" && existingCell != nil {
-				newChar = existingCell.char
-				newFg = existingCell.foregroundColor
-			}
-
-			if cell.backgroundColor == "transparent" && existingCell != nil {
-				newBg = existingCell.backgroundColor
-			}
-
-			m.canvas.Set(targetY, targetX, newChar, newFg, newBg)
-		}
-	}
-
-	m.saveToHistory()
-	m.selection.active = false
+	m.floatClipboard(originY, originX)
 }
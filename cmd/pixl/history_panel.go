@@ -0,0 +1,152 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+const (
+	historyPanelMaxVisible = 12
+	historyPanelHint       = "↑/↓ select  enter jump  esc close"
+)
+
+// historyRow is one line of the history panel.
+type historyRow struct {
+	node   int
+	indent int
+}
+
+// historyRows lists the history tree depth first. A state's first child
+// continues its column; later children start a branch one column in.
+func (m *model) historyRows() []historyRow {
+	if len(m.history) == 0 {
+		return nil
+	}
+	children := make([][]int, len(m.history))
+	for i, e := range m.history {
+		if e.parent >= 0 {
+			children[e.parent] = append(children[e.parent], i)
+		}
+	}
+	var rows []historyRow
+	var visit func(n, indent int)
+	visit = func(n, indent int) {
+		rows = append(rows, historyRow{n, indent})
+		for i, child := range children[n] {
+			if i == 0 {
+				visit(child, indent)
+			} else {
+				visit(child, indent+1)
+			}
+		}
+	}
+	visit(0, 0)
+	return rows
+}
+
+func (m *model) historyRowText(r historyRow) string {
+	e := m.history[r.node]
+	mark := "○"
+	if r.node == m.historyIndex {
+		mark = "●"
+	}
+	text := strings.Repeat("  ", r.indent) + mark + " " + e.label
+	if region := e.region(); region != "" {
+		text += " " + region
+	}
+	return text + "  " + e.time.Format("15:04:05")
+}
+
+// historyCursorRow returns the row of the state selected in the panel.
+func (m *model) historyCursorRow(rows []historyRow) int {
+	for i, r := range rows {
+		if r.node == m.historyCursor {
+			return i
+		}
+	}
+	return 0
+}
+
+func (m *model) openHistoryPanel() {
+	m.closeMenus()
+	m.showHistory = true
+	m.historyCursor = m.historyIndex
+}
+
+func (m *model) toggleHistoryPanel() {
+	if m.showHistory {
+		m.showHistory = false
+		return
+	}
+	m.openHistoryPanel()
+}
+
+func (m *model) renderHistoryPanel() string {
+	selectedStyle := lipgloss.NewStyle().
+		Background(themeColor(m.config.Theme.MenuSelectedBg)).
+		Foreground(themeColor(m.config.Theme.MenuSelectedFg))
+	hintStyle := lipgloss.NewStyle().Faint(true)
+
+	rows := m.historyRows()
+	cursorRow := m.historyCursorRow(rows)
+	start := 0
+	if len(rows) > historyPanelMaxVisible {
+		start = min(max(cursorRow-historyPanelMaxVisible/2, 0), len(rows)-historyPanelMaxVisible)
+	}
+	visible := rows[start:min(start+historyPanelMaxVisible, len(rows))]
+
+	title := fmt.Sprintf("History (%d states)", len(m.history))
+	width := max(lipgloss.Width(title), lipgloss.Width(historyPanelHint))
+	for _, r := range visible {
+		width = max(width, lipgloss.Width(m.historyRowText(r)))
+	}
+	pad := func(s string) string {
+		return s + strings.Repeat(" ", max(width-lipgloss.Width(s), 0))
+	}
+
+	lines := []string{pad(title), strings.Repeat("─", width)}
+	for _, r := range visible {
+		line := pad(m.historyRowText(r))
+		if r.node == m.historyCursor {
+			line = selectedStyle.Render(line)
+		}
+		lines = append(lines, line)
+	}
+	lines = append(lines, strings.Repeat("─", width), hintStyle.Render(pad(historyPanelHint)))
+
+	dialogStyle := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(themeColor(m.config.Theme.MenuBorder)).
+		Padding(0, 1)
+	return dialogStyle.Render(strings.Join(lines, "\n"))
+}
+
+// handleHistoryPanelKey handles keys while the history panel is open. The
+// panel is modal, so every key is consumed.
+func (m *model) handleHistoryPanelKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	rows := m.historyRows()
+	cursorRow := m.historyCursorRow(rows)
+
+	switch msg.String() {
+	case "up", "k":
+		if cursorRow > 0 {
+			m.historyCursor = rows[cursorRow-1].node
+		}
+	case "down", "j":
+		if cursorRow < len(rows)-1 {
+			m.historyCursor = rows[cursorRow+1].node
+		}
+	case "enter":
+		m.jumpToHistory(m.historyCursor)
+	case "esc", "q":
+		m.showHistory = false
+	default:
+		if m.keyAction(msg.String()) == "history" {
+			m.showHistory = false
+		}
+	}
+	return m, nil
+}
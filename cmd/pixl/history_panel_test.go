@@ -0,0 +1,130 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// newBranchedHistoryModel builds the tree 0 → 1 → 2 with a second branch
+// 0 → 3, and leaves the current state at 3.
+func newBranchedHistoryModel() *model {
+	m := newHistoryModel()
+	m.saveToHistory()
+	m.canvas.Set(0, 0, "A", "white", "transparent")
+	m.saveToHistoryAs("Point")
+	m.canvas.Set(1, 1, "B", "white", "transparent")
+	m.canvas.Set(2, 3, "B", "white", "transparent")
+	m.saveToHistoryAs("Rectangle")
+	m.undo()
+	m.undo()
+	m.canvas.Set(4, 4, "C", "white", "transparent")
+	m.saveToHistoryAs("Fill")
+	return m
+}
+
+func TestHistoryRowsShowBranches(t *testing.T) {
+	m := newBranchedHistoryModel()
+	rows := m.historyRows()
+	want := []historyRow{{0, 0}, {1, 0}, {2, 0}, {3, 1}}
+	if len(rows) != len(want) {
+		t.Fatalf("rows = %v, want %v", rows, want)
+	}
+	for i := range want {
+		if rows[i] != want[i] {
+			t.Errorf("row %d = %v, want %v", i, rows[i], want[i])
+		}
+	}
+
+	if got := m.historyRowText(rows[2]); !strings.HasPrefix(got, "○ Rectangle 1,1–3,2  ") {
+		t.Errorf("row text = %q", got)
+	}
+	if got := m.historyRowText(rows[3]); !strings.HasPrefix(got, "  ● Fill 4,4  ") {
+		t.Errorf("current row text = %q", got)
+	}
+}
+
+func TestHistoryPanelJump(t *testing.T) {
+	m := newBranchedHistoryModel()
+	m.handleKey(keyMsg("U"))
+	if !m.showHistory || m.historyCursor != 3 {
+		t.Fatalf("U should open the panel on the current state, cursor = %d", m.historyCursor)
+	}
+
+	m.handleKey(keyMsg("up"))
+	m.handleKey(keyMsg("enter"))
+	if m.historyIndex != 2 || m.canvas.Get(1, 1).char != "B" || m.canvas.Get(4, 4).char != " " {
+		t.Errorf("enter should jump to state 2, at %d", m.historyIndex)
+	}
+	if !m.showHistory || !strings.Contains(m.renderHistoryPanel(), "History (4 states)") {
+		t.Error("panel should stay open after a jump")
+	}
+
+	m.handleKey(keyMsg("u"))
+	if m.historyIndex != 2 {
+		t.Error("keys should not reach the canvas while the panel is open")
+	}
+	m.handleKey(tea.KeyMsg{Type: tea.KeyEscape})
+	if m.showHistory {
+		t.Error("esc should close the panel")
+	}
+}
+
+func TestSwitchHistoryBranch(t *testing.T) {
+	m := newBranchedHistoryModel()
+	m.switchHistoryBranch(1)
+	if m.historyIndex != 2 {
+		t.Fatalf("next branch should go to the tip of the other branch, at %d", m.historyIndex)
+	}
+	if m.canvas.Get(0, 0).char != "A" || m.canvas.Get(4, 4).char != " " {
+		t.Error("canvas should show the other branch")
+	}
+	m.switchHistoryBranch(-1)
+	if m.historyIndex != 3 || m.canvas.Get(4, 4).char != "C" {
+		t.Errorf("previous branch should come back, at %d", m.historyIndex)
+	}
+}
+
+func TestStepHistoryTime(t *testing.T) {
+	m := newBranchedHistoryModel()
+	m.stepHistoryTime(-1)
+	if m.historyIndex != 2 || m.canvas.Get(1, 1).char != "B" {
+		t.Errorf("older state should be the one saved before, at %d", m.historyIndex)
+	}
+	m.stepHistoryTime(1)
+	m.stepHistoryTime(1)
+	if m.historyIndex != 3 {
+		t.Errorf("newer state past the end should stay put, at %d", m.historyIndex)
+	}
+}
+
+func TestTrimHistoryDropsAbandonedBranches(t *testing.T) {
+	m := newBranchedHistoryModel()
+	m.config.UndoLimit = 2
+	m.canvas.Set(3, 3, "D", "white", "transparent")
+	m.saveToHistoryAs("Point")
+
+	if len(m.history) != 2 || m.historyIndex != 1 || m.history[0].parent != -1 {
+		t.Fatalf("history = %d states at %d, want 2 at 1", len(m.history), m.historyIndex)
+	}
+	m.undo()
+	if m.canvas.Get(4, 4).char != "C" || m.canvas.Get(3, 3).char != " " {
+		t.Error("undo should still work after trimming")
+	}
+	m.undo()
+	if m.canvas.Get(4, 4).char != "C" {
+		t.Error("undo past the new root should do nothing")
+	}
+}
+
+func TestHistoryPanelRendersInView(t *testing.T) {
+	m := newScrollModel()
+	m.saveToHistory()
+	m.addLayer()
+	m.openHistoryPanel()
+	view := m.View()
+	if !strings.Contains(view, "History (2 states)") || !strings.Contains(view, "Add Layer") {
+		t.Errorf("view should show the history panel:\n%s", view)
+	}
+}
@@ -8,7 +8,7 @@ func newHistoryModel() *model {
 		selectedChar:    "#",
 		foregroundColor: "white",
 		backgroundColor: "transparent",
-		history:         []Canvas{},
+		history:         []historyEntry{},
 		historyIndex:    -1,
 	}
 }
@@ -32,7 +32,7 @@ func TestSaveToHistoryIsIndependent(t *testing.T) {
 
 	m.canvas.Set(0, 0, "B", "white", "transparent")
 
-	saved := m.history[0].Get(0, 0)
+	saved := m.historyBase.layers[0].canvas.Get(0, 0)
 	if saved.char != "A" {
 		t.Errorf("history snapshot mutated: char = %q, want A", saved.char)
 	}
@@ -90,7 +90,7 @@ func TestRedoAtBoundaryIsNoop(t *testing.T) {
 	}
 }
 
-func TestHistoryTruncatesRedoBranch(t *testing.T) {
+func TestNewChangeAfterUndoKeepsBranch(t *testing.T) {
 	m := newHistoryModel()
 	m.saveToHistory() // 0
 
@@ -104,15 +104,27 @@ func TestHistoryTruncatesRedoBranch(t *testing.T) {
 	m.undo() // back to 0
 
 	m.canvas.Set(0, 0, "C", "white", "transparent")
-	m.saveToHistory() // should truncate redo branch
+	m.saveToHistory() // 3: a new branch from 0
 
-	if len(m.history) != 2 {
-		t.Errorf("history length = %d, want 2 (original + new)", len(m.history))
+	if len(m.history) != 4 || m.history[3].parent != 0 {
+		t.Fatalf("history length = %d, want 4 with the new state branching from 0", len(m.history))
 	}
 
-	m.redo() // should be no-op since redo branch was truncated
+	m.redo() // the new branch has no redo
 	if cell := m.canvas.Get(0, 0); cell.char != "C" {
-		t.Errorf("after redo on truncated branch: char = %q, want C", cell.char)
+		t.Errorf("after redo at branch tip: char = %q, want C", cell.char)
+	}
+
+	m.jumpToHistory(2)
+	if cell := m.canvas.Get(0, 0); cell.char != "B" {
+		t.Errorf("jump to old branch: char = %q, want B", cell.char)
+	}
+	m.undo()
+	m.undo()
+	m.redo()
+	m.redo()
+	if cell := m.canvas.Get(0, 0); cell.char != "B" {
+		t.Errorf("redo should follow the branch visited last, char = %q, want B", cell.char)
 	}
 }
 
@@ -207,11 +219,11 @@ func TestCutSelectionClearsRegion(t *testing.T) {
 		t.Fatal("clipboard is nil after cut")
 	}
 
-	// Internal region (1,1)-(2,2) should be cleared
-	if cell := m.canvas.Get(1, 1); cell.char != " " || cell.foregroundColor != "transparent" {
+	// Internal region (1,1)-(2,2) should be cleared to the layer's blank
+	if cell := m.canvas.Get(1, 1); *cell != layerBlank(m.activeLayer) {
 		t.Errorf("cut region cell(1,1) not cleared: %+v", *cell)
 	}
-	if cell := m.canvas.Get(2, 2); cell.char != " " || cell.foregroundColor != "transparent" {
+	if cell := m.canvas.Get(2, 2); *cell != layerBlank(m.activeLayer) {
 		t.Errorf("cut region cell(2,2) not cleared: %+v", *cell)
 	}
 }
@@ -258,3 +270,103 @@ func TestPasteSkipsTransparentCells(t *testing.T) {
 		t.Errorf("transparent paste should preserve existing cell, got char = %q", cell.char)
 	}
 }
+
+func TestHistoryStoresOnlyChangedCells(t *testing.T) {
+	m := newHistoryModel()
+	m.saveToHistory()
+
+	m.canvas.Set(1, 1, "A", "red", "transparent")
+	m.canvas.Set(2, 3, "B", "red", "transparent")
+	m.saveToHistory()
+
+	e := m.history[1]
+	if len(e.cells) != 2 || e.before != nil || e.after != nil {
+		t.Fatalf("entry has %d cells, snapshots %v/%v; want 2 cells and no snapshots", len(e.cells), e.before != nil, e.after != nil)
+	}
+	if e.cells[0].before.char != " " || e.cells[0].after.char != "A" {
+		t.Errorf("first change = %+v, want space to A", e.cells[0])
+	}
+}
+
+func TestUndoRedoAcrossResize(t *testing.T) {
+	m := newHistoryModel()
+	m.canvas.Set(0, 0, "A", "white", "transparent")
+	m.saveToHistory()
+
+	m.resizeLayers(8, 3)
+	m.canvas.Set(2, 7, "B", "white", "transparent")
+	m.saveToHistory()
+
+	m.undo()
+	if m.canvas.width != 5 || m.canvas.height != 5 || m.canvas.Get(0, 0).char != "A" {
+		t.Errorf("undo gave %dx%d canvas, want the original 5x5", m.canvas.width, m.canvas.height)
+	}
+	m.redo()
+	if m.canvas.width != 8 || m.canvas.Get(2, 7).char != "B" {
+		t.Errorf("redo gave %dx%d canvas, want 8x3 with B", m.canvas.width, m.canvas.height)
+	}
+}
+
+func TestUndoDiscardsUnsavedChanges(t *testing.T) {
+	m := newHistoryModel()
+	m.saveToHistory()
+	m.canvas.Set(0, 0, "A", "white", "transparent")
+	m.saveToHistory()
+
+	m.canvas.Set(4, 4, "Z", "white", "transparent")
+	m.undo()
+	if m.canvas.Get(0, 0).char != " " || m.canvas.Get(4, 4).char != " " {
+		t.Error("undo should restore the previous state exactly")
+	}
+	m.redo()
+	if m.canvas.Get(0, 0).char != "A" || m.canvas.Get(4, 4).char != " " {
+		t.Error("redo should restore the saved state, not unsaved edits")
+	}
+}
+
+func TestHistoryConfigurableLimit(t *testing.T) {
+	m := newHistoryModel()
+	m.config.UndoLimit = 5
+	for i := 0; i < 10; i++ {
+		m.canvas.Set(0, i%5, "X", "white", "transparent")
+		m.saveToHistory()
+	}
+	if len(m.history) != 5 || m.historyIndex != 4 {
+		t.Errorf("history length = %d index = %d, want 5 and 4", len(m.history), m.historyIndex)
+	}
+
+	m.config.UndoLimit = -1
+	for i := 0; i < 100; i++ {
+		m.canvas.Set(1, 0, string(rune('a'+i%26)), "white", "transparent")
+		m.saveToHistory()
+	}
+	if len(m.history) != 105 {
+		t.Errorf("unlimited history length = %d, want 105", len(m.history))
+	}
+}
+
+func TestHistoryMemoryBudget(t *testing.T) {
+	m := newHistoryModel()
+	m.canvas = NewCanvas(200, 200)
+	m.config.UndoLimit = -1
+	m.config.UndoMemoryMB = 1
+	m.saveToHistory()
+
+	// Each fill changes 40000 cells, so only a few fit in 1 MB.
+	for i := 0; i < 10; i++ {
+		ch := string(rune('a' + i))
+		for row := 0; row < 200; row++ {
+			for col := 0; col < 200; col++ {
+				m.canvas.Set(row, col, ch, "white", "transparent")
+			}
+		}
+		m.saveToHistory()
+	}
+	if len(m.history) >= 11 || len(m.history) < 2 {
+		t.Errorf("history length = %d, want trimmed to the budget", len(m.history))
+	}
+	m.undo()
+	if m.canvas.Get(0, 0).char != "i" {
+		t.Errorf("after undo char = %q, want i", m.canvas.Get(0, 0).char)
+	}
+}
@@ -26,6 +26,9 @@ func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case alertTimeout:
 		m.alertMessage = ""
 		return m, nil
+	case autosaveTick:
+		m.autosave()
+		return m, m.autosaveCmd()
 	case textCursorTick:
 		if !m.textInsertActive {
 			m.textCursorTicking = false
@@ -36,6 +39,9 @@ func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return textCursorTick{}
 		})
 	}
+	if seq, ok := csiReply(msg); ok {
+		return m, m.handleTerminalReply(seq)
+	}
 	return m, nil
 }
 
@@ -46,42 +52,29 @@ func (m *model) handleResize(msg tea.WindowSizeMsg) (tea.Model, tea.Cmd) {
 
 	if m.hasFixedSize() {
 		if !m.canvasInitialized {
-			newCanvas := NewCanvas(m.fixedWidth, m.fixedHeight)
-			for row := 0; row < min(m.canvas.height, m.fixedHeight); row++ {
-				for col := 0; col < min(m.canvas.width, m.fixedWidth); col++ {
-					cell := m.canvas.Get(row, col)
-					if cell != nil {
-						newCanvas.Set(row, col, cell.char, cell.foregroundColor, cell.backgroundColor)
-					}
-				}
-			}
-			m.canvas = newCanvas
+			m.fitFixedSize()
 			m.canvasInitialized = true
 			if len(m.history) == 0 {
-				m.history = []Canvas{m.canvas.Copy()}
-				m.historyIndex = 0
+				m.saveToHistory()
 			}
 		}
 	} else {
 		canvasHeight := m.height - controlBarHeight
 		if canvasHeight > 0 && (canvasHeight != m.canvas.height || m.width != m.canvas.width) {
-			m.saveToHistory()
-			newCanvas := NewCanvas(m.width, canvasHeight)
-			for row := 0; row < min(m.canvas.height, canvasHeight); row++ {
-				for col := 0; col < min(m.canvas.width, m.width); col++ {
-					cell := m.canvas.Get(row, col)
-					if cell != nil {
-						newCanvas.Set(row, col, cell.char, cell.foregroundColor, cell.backgroundColor)
-					}
-				}
-			}
-			m.canvas = newCanvas
-			if len(m.history) == 0 {
-				m.history = []Canvas{m.canvas.Copy()}
-				m.historyIndex = 0
+			// Following the terminal isn't an edit, so a saved document
+			// stays saved
+			saved := !m.isModified()
+			m.resizeLayers(m.width, canvasHeight)
+			m.saveToHistoryAs("Resize")
+			if saved {
+				m.savedHistoryIndex = m.historyIndex
 			}
 		}
 	}
+	// The font may have been resized along with the window
+	if m.pixelMouse.on {
+		return m, writeTerminal(queryCellSize)
+	}
 	return m, nil
 }
 
@@ -93,18 +86,75 @@ func (m *model) handleKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		return m, nil
 	}
 
+	if m.pendingRecovery != "" {
+		return m.handleRecoveryKey(msg)
+	}
+
+	if m.confirmCrop {
+		return m.handleCropConfirmKey(msg)
+	}
+
+	if m.confirmQuit {
+		return m.handleQuitConfirmKey(msg)
+	}
+
 	if m.showPalette {
 		return m.handlePaletteKey(msg)
 	}
 
+	if m.showHistory {
+		return m.handleHistoryPanelKey(msg)
+	}
+
+	if m.showPathPrompt {
+		return m.handlePathPromptKey(msg)
+	}
+
+	if m.showSizePrompt {
+		return m.handleSizePromptKey(msg)
+	}
+
 	if m.textInsertActive && m.selectedTool == "Text" {
 		return m.handleTextKey(msg)
 	}
 
-	if m.confirmClear && msg.String() != "c" {
+	if m.floating.active {
+		if m.handleFloatingKey(msg) {
+			return m, nil
+		}
+	} else if m.nudgesSelection(msg.String()) && m.liftSelection() {
+		m.handleFloatingKey(msg)
+		return m, nil
+	}
+
+	if m.confirmClear && m.keyAction(msg.String()) != actionClearCanvas {
 		m.confirmClear = false
 	}
 
+	if (m.showFgPicker || m.showBgPicker) && m.colorPickerExtended && m.handleColorPickerKey(msg) {
+		return m, nil
+	}
+
+	if m.showLayerPicker && m.handleLayerPanelKey(msg) {
+		return m, nil
+	}
+
+	// Pastes not taken by a text input above float on the canvas
+	if msg.Paste {
+		m.pasteText(string(msg.Runes))
+		return m, nil
+	}
+
+	if m.keyboardCursor && m.activeMenu() < 0 {
+		if handled, cmd := m.handleCursorKey(msg); handled {
+			return m, cmd
+		}
+	}
+
+	if action := m.keyAction(msg.String()); action != "" {
+		return m, m.runAction(action)
+	}
+
 	switch msg.String() {
 	case "1", "2", "3", "4", "5", "6", "7", "8", "9":
 		idx := int(msg.String()[0] - '1')
@@ -145,59 +195,15 @@ func (m *model) handleKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 			}
 		}
 	case "ctrl+c", "q":
-		return m, tea.Quit
-	case "c":
-		if !m.confirmClear {
-			m.confirmClear = true
-			return m, tea.Tick(time.Second, func(time.Time) tea.Msg {
-				return clearConfirmTimeout{}
-			})
-		}
-		m.confirmClear = false
-		m.canvas.Clear()
-		m.saveToHistory()
-		return m, nil
-	case "u":
-		m.undo()
-		return m, nil
-	case "r":
-		m.redo()
-		return m, nil
-	case "x":
-		m.foregroundColor, m.backgroundColor = m.backgroundColor, m.foregroundColor
-		return m, nil
+		return m, m.requestQuit()
 	case ":":
 		m.showPalette = true
 		m.paletteQuery = ""
 		m.paletteIndex = 0
 		return m, nil
-	case "i":
-		cell := m.canvas.Get(m.hoverRow, m.hoverCol)
-		if cell != nil {
-			m.selectedChar = cell.char
-			m.foregroundColor = cell.foregroundColor
-			m.backgroundColor = cell.backgroundColor
-		}
-		return m, nil
-	case "y":
-		m.copySelection()
-		return m, nil
-	case "d":
-		m.cutSelection()
-		return m, nil
-	case "p":
-		m.paste()
-		return m, nil
-	case "f", "b", "g", "t":
-		for i, key := range menuKeys {
-			if msg.String() == key {
-				if m.activeMenu() == i {
-					m.closeMenus()
-				} else {
-					m.openMenu(i)
-				}
-				return m, nil
-			}
+	case "tab":
+		if m.showFgPicker || m.showBgPicker {
+			m.openExtendedColorPicker()
 		}
 		return m, nil
 	case "[":
@@ -216,6 +222,26 @@ func (m *model) handleKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 			m.openMenu((active + 1) % menuCount)
 		}
 		return m, nil
+	case "shift+up":
+		m.scrollBy(-1, 0)
+		return m, nil
+	case "shift+down":
+		m.scrollBy(1, 0)
+		return m, nil
+	case "shift+left":
+		m.scrollBy(0, -1)
+		return m, nil
+	case "shift+right":
+		m.scrollBy(0, 1)
+		return m, nil
+	case "pgup":
+		rows, _ := m.viewportSize()
+		m.scrollBy(-rows, 0)
+		return m, nil
+	case "pgdown":
+		rows, _ := m.viewportSize()
+		m.scrollBy(rows, 0)
+		return m, nil
 	case "esc":
 		if m.showToolPicker && m.toolPickerFocusLevel > 0 {
 			m.toolPickerFocusLevel--
@@ -229,6 +255,10 @@ func (m *model) handleKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 			m.closeMenus()
 			return m, nil
 		}
+		if len(m.polyline) > 0 {
+			m.cancelPolyline()
+			return m, nil
+		}
 		m.selection.active = false
 		return m, nil
 	case "left":
@@ -384,6 +414,7 @@ func (m *model) handleTextKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	case tea.KeyEnter:
 		m.textInsertRow++
 		m.textInsertCol = m.textInsertStartX
+		m.scrollToCell(m.textInsertRow, m.textInsertCol)
 		m.textCursorBlink = true
 		return m, nil
 	case tea.KeyBackspace:
@@ -398,7 +429,7 @@ func (m *model) handleTextKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 					break
 				}
 				m.textInsertCol--
-				m.canvas.Set(m.textInsertRow, m.textInsertCol, " ", "white", "transparent")
+				m.canvas.SetCell(m.textInsertRow, m.textInsertCol, layerBlank(m.activeLayer))
 			}
 			for m.textInsertCol > 0 {
 				cell := m.canvas.Get(m.textInsertRow, m.textInsertCol-1)
@@ -406,11 +437,11 @@ func (m *model) handleTextKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 					break
 				}
 				m.textInsertCol--
-				m.canvas.Set(m.textInsertRow, m.textInsertCol, " ", "white", "transparent")
+				m.canvas.SetCell(m.textInsertRow, m.textInsertCol, layerBlank(m.activeLayer))
 			}
 		} else {
 			m.textInsertCol--
-			m.canvas.Set(m.textInsertRow, m.textInsertCol, " ", "white", "transparent")
+			m.canvas.SetCell(m.textInsertRow, m.textInsertCol, layerBlank(m.activeLayer))
 		}
 		m.saveToHistory()
 		m.textCursorBlink = true
@@ -418,20 +449,22 @@ func (m *model) handleTextKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	case tea.KeySpace:
 		if m.textInsertRow < m.canvas.height &&
 			m.textInsertCol >= 0 && m.textInsertCol < m.canvas.width {
-			m.canvas.Set(m.textInsertRow, m.textInsertCol, " ", m.foregroundColor, m.backgroundColor)
+			m.canvas.SetCell(m.textInsertRow, m.textInsertCol, m.brushCell(" "))
 			m.textInsertCol++
 			m.saveToHistory()
 		}
+		m.scrollToCell(m.textInsertRow, m.textInsertCol)
 		m.textCursorBlink = true
 		return m, nil
 	case tea.KeyRunes:
 		ch := string(msg.Runes)
 		if m.textInsertRow < m.canvas.height &&
 			m.textInsertCol >= 0 && m.textInsertCol < m.canvas.width {
-			m.canvas.Set(m.textInsertRow, m.textInsertCol, ch, m.foregroundColor, m.backgroundColor)
+			m.canvas.SetCell(m.textInsertRow, m.textInsertCol, m.brushCell(ch))
 			m.textInsertCol++
 			m.saveToHistory()
 		}
+		m.scrollToCell(m.textInsertRow, m.textInsertCol)
 		m.textCursorBlink = true
 		return m, nil
 	}
@@ -439,28 +472,64 @@ func (m *model) handleTextKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 }
 
 func (m *model) handleMouse(msg tea.MouseMsg) (tea.Model, tea.Cmd) {
+	dotY, dotX := 0, 0
+	if m.pixelMouse.on {
+		spec := drawGrids[m.toolGrid()]
+		msg, dotY, dotX = m.pixelMouse.toCells(msg, spec.rows, spec.cols)
+	}
 	m.mouseX = msg.X
 	m.mouseY = msg.Y
 
 	hoverX, hoverY := m.screenToCanvas(m.mouseX, m.mouseY)
 	m.hoverRow = hoverY
 	m.hoverCol = hoverX
-	m.cursorVisible = hoverY >= 0 && hoverY < m.canvas.height && hoverX >= 0 && hoverX < m.canvas.width
+	m.hoverDotY, m.hoverDotX = dotY, dotX
+	m.cursorVisible = m.viewportContains(hoverY, hoverX)
+	m.trackPolyline(hoverY, hoverX)
+
+	switch msg.Type {
+	case tea.MouseWheelUp, tea.MouseWheelDown, tea.MouseWheelLeft, tea.MouseWheelRight:
+		m.handleWheel(msg)
+		return m, nil
+	}
 
 	if m.confirmClear && msg.Type == tea.MouseLeft {
 		m.confirmClear = false
 	}
+	if m.confirmQuit && msg.Type == tea.MouseLeft {
+		m.confirmQuit = false
+		m.pendingOpen = ""
+		return m, nil
+	}
+
+	// Clicking anywhere but inside the selection with the Select tool puts
+	// down floating content, before the click can change layers or tools
+	if msg.Type == tea.MouseLeft && !m.mouseDown && m.floating.active &&
+		(m.activeMenu() >= 0 || m.selectedTool != "Select" || m.mouseY < controlBarHeight ||
+			!m.insideSelection(hoverY, hoverX)) {
+		m.commitFloating()
+	}
 
 	// Handle popup and menu clicks (only on initial click, not during drag)
 	if msg.Type == tea.MouseLeft && !m.mouseDown {
-		if m.showFgPicker {
-			if idx := m.colorPickerClickIndex(msg, m.toolbar.foregroundItemX); idx >= 0 {
-				m.foregroundColor = colors[idx].name
+		if m.showFgPicker || m.showBgPicker {
+			itemX := m.toolbar.foregroundItemX
+			if m.showBgPicker {
+				itemX = m.toolbar.backgroundItemX
+			}
+			if m.colorPickerExtended {
+				if m.extendedColorPickerClick(msg, itemX) {
+					return m, nil
+				}
+			} else if idx := m.colorPickerClickIndex(msg, itemX); idx == colorPickerMoreID {
+				m.openExtendedColorPicker()
+				return m, nil
+			} else if idx >= 0 {
+				m.setPickerColor(colors[idx].name)
 				return m, nil
 			}
-		} else if m.showBgPicker {
-			if idx := m.colorPickerClickIndex(msg, m.toolbar.backgroundItemX); idx >= 0 {
-				m.backgroundColor = colors[idx].name
+		} else if m.showLayerPicker {
+			if m.layerPanelClick(msg) {
 				return m, nil
 			}
 		} else if m.showToolPicker {
@@ -590,7 +659,19 @@ func (m *model) handleMouse(msg tea.MouseMsg) (tea.Model, tea.Cmd) {
 
 		// Check if clicking on control bar buttons
 		if msg.Y < controlBarHeight {
-			if m.toolbar.toolX > 0 && msg.X >= m.toolbar.toolX {
+			if attr, ok := m.toolbarAttrAt(msg.X); ok {
+				m.textAttrs ^= attr
+				return m, nil
+			} else if m.toolbar.attrX > 0 && msg.X >= m.toolbar.attrX-toolbarButtonPadding {
+				return m, nil
+			} else if m.toolbar.layerX > 0 && msg.X >= m.toolbar.layerX {
+				if m.activeMenu() == menuLayer {
+					m.closeMenus()
+				} else {
+					m.openMenu(menuLayer)
+				}
+				return m, nil
+			} else if m.toolbar.toolX > 0 && msg.X >= m.toolbar.toolX {
 				if m.activeMenu() == menuTool {
 					m.closeMenus()
 				} else {
@@ -625,56 +706,133 @@ func (m *model) handleMouse(msg tea.MouseMsg) (tea.Model, tea.Cmd) {
 	// Handle mouse press (start of stroke)
 	if msg.Type == tea.MouseLeft && !m.mouseDown && msg.Y >= controlBarHeight {
 		cx, cy := m.screenToCanvas(msg.X, msg.Y)
-		if m.hasFixedSize() && (cy < 0 || cy >= m.canvas.height || cx < 0 || cx >= m.canvas.width) {
+		if m.hasFixedSize() && !m.viewportContains(cy, cx) {
 			return m, nil
 		}
-		m.mouseDown = true
-		m.canvasBeforeStroke = m.canvas.Copy()
-		m.startX = cx
-		m.startY = cy
-		m.tool().OnPress(m, cy, cx)
-		if m.selectedTool == "Text" && !m.textCursorTicking {
-			m.textCursorTicking = true
-			return m, tea.Tick(500*time.Millisecond, func(time.Time) tea.Msg {
-				return textCursorTick{}
-			})
+		m.selectionOp = selectionOpFor(msg.Shift, msg.Alt, msg.Ctrl)
+		started, cmd := m.beginStroke(cy, cx)
+		if !started || cmd != nil {
+			return m, cmd
 		}
 	}
 
 	// Handle drag events
 	if (msg.Type == tea.MouseLeft || msg.Type == tea.MouseMotion) && m.mouseDown {
+		m.autoScroll(msg.X, msg.Y)
 		canvasX, canvasY := m.screenToCanvas(msg.X, msg.Y)
 
-		m.tool().OnDrag(m, canvasY, canvasX)
+		m.dragStroke(canvasY, canvasX)
 	}
 
 	// Handle mouse release (end of stroke)
 	if msg.Type == tea.MouseRelease && m.mouseDown {
-		m.mouseDown = false
-		m.showPreview = false
-		m.previewPoints = nil
-
 		canvasX, canvasY := m.screenToCanvas(msg.X, msg.Y)
-		clampedY, clampedX := m.clampToCanvas(canvasY, canvasX)
+		m.endStroke(canvasY, canvasX)
+	}
 
-		tool := m.tool()
-		tool.OnRelease(m, clampedY, clampedX)
+	return m, nil
+}
 
-		m.optionKeyHeld = false
+// beginStroke starts a tool stroke at a canvas position. It reports whether
+// the stroke started and returns the text cursor blink command if the Text
+// tool needs one.
+func (m *model) beginStroke(y, x int) (bool, tea.Cmd) {
+	if m.tool().ModifiesCanvas() && !m.checkLayerEditable() {
+		return false, nil
+	}
+	if m.floating.active && (m.selectedTool != "Select" || !m.insideSelection(y, x)) {
+		m.commitFloating()
+	}
+	y, x = m.strokePoint(y, x)
+	m.mouseDown = true
+	m.canvasBeforeStroke = m.canvas.Copy()
+	m.startX = x
+	m.startY = y
+	m.tool().OnPress(m, y, x)
+	if m.selectedTool == "Text" && !m.textCursorTicking {
+		m.textCursorTicking = true
+		return true, tea.Tick(500*time.Millisecond, func(time.Time) tea.Msg {
+			return textCursorTick{}
+		})
+	}
+	return true, nil
+}
 
-		if tool.ModifiesCanvas() && !m.canvas.Equals(m.canvasBeforeStroke) {
-			m.saveToHistory()
-		}
+// dragStroke moves the current stroke to a canvas position.
+func (m *model) dragStroke(y, x int) {
+	y, x = m.strokePoint(y, x)
+	m.tool().OnDrag(m, y, x)
+}
+
+// endStroke finishes the current stroke at a canvas position and records it
+// in history if the canvas changed.
+func (m *model) endStroke(y, x int) {
+	m.mouseDown = false
+	m.showPreview = false
+	m.previewPoints = nil
+	m.previewFill = nil
+	m.previewGlyphs = nil
+
+	clampedY, clampedX := m.clampToGrid(m.strokePoint(y, x))
+
+	tool := m.tool()
+	tool.OnRelease(m, clampedY, clampedX)
+
+	m.optionKeyHeld = false
+	m.selectionOp = selectReplace
+
+	if tool.ModifiesCanvas() && !m.canvas.Equals(m.canvasBeforeStroke) {
+		m.saveToHistory()
 	}
+}
 
-	return m, nil
+// Mouse wheel scroll steps, in cells
+const (
+	wheelScrollRows = 3
+	wheelScrollCols = 6
+)
+
+// handleWheel scrolls the viewport. Shift turns vertical wheel motion into
+// horizontal scrolling.
+func (m *model) handleWheel(msg tea.MouseMsg) {
+	switch {
+	case msg.Type == tea.MouseWheelLeft || (msg.Type == tea.MouseWheelUp && msg.Shift):
+		m.scrollBy(0, -wheelScrollCols)
+	case msg.Type == tea.MouseWheelRight || (msg.Type == tea.MouseWheelDown && msg.Shift):
+		m.scrollBy(0, wheelScrollCols)
+	case msg.Type == tea.MouseWheelUp:
+		m.scrollBy(-wheelScrollRows, 0)
+	case msg.Type == tea.MouseWheelDown:
+		m.scrollBy(wheelScrollRows, 0)
+	}
+}
+
+// autoScroll nudges the viewport when a drag moves past its edge.
+func (m *model) autoScroll(screenX, screenY int) {
+	if !m.hasFixedSize() {
+		return
+	}
+	rows, cols := m.viewportSize()
+	cx, cy := m.screenToCanvas(screenX, screenY)
+	dy, dx := 0, 0
+	if cy < m.scrollY {
+		dy = -1
+	} else if cy >= m.scrollY+rows {
+		dy = 1
+	}
+	if cx < m.scrollX {
+		dx = -1
+	} else if cx >= m.scrollX+cols {
+		dx = 1
+	}
+	m.scrollBy(dy, dx)
 }
 
 func (m *model) screenToCanvas(screenX, screenY int) (canvasX, canvasY int) {
 	if m.hasFixedSize() {
 		offY, offX := m.canvasOffset()
-		canvasY = screenY - controlBarHeight - offY - 2
-		canvasX = screenX - offX - 1
+		canvasY = screenY - controlBarHeight - offY - 2 + m.scrollY
+		canvasX = screenX - offX - 1 + m.scrollX
 	} else {
 		canvasY = screenY - controlBarHeight
 		canvasX = screenX
@@ -683,7 +841,7 @@ func (m *model) screenToCanvas(screenX, screenY int) (canvasX, canvasY int) {
 }
 
 func (m *model) colorPickerClickIndex(msg tea.MouseMsg, itemX int) int {
-	pickerHeight := len(colors) + pickerBorderWidth
+	pickerHeight := len(colors) + 1 + pickerBorderWidth
 	pickerTop := controlBarHeight
 	pickerLeft := itemX - pickerContentOffset
 
@@ -703,6 +861,9 @@ func (m *model) colorPickerClickIndex(msg tea.MouseMsg, itemX int) int {
 		return -1
 	}
 	colorIdx := msg.Y - pickerTop - 1
+	if colorIdx == len(colors) {
+		return colorPickerMoreID
+	}
 	if colorIdx < 0 || colorIdx >= len(colors) {
 		return -1
 	}
@@ -784,3 +784,137 @@ func TestClampToCanvas(t *testing.T) {
 		})
 	}
 }
+
+func TestMouseDragAppliesTextAttributes(t *testing.T) {
+	m := &model{
+		canvas:          NewCanvas(10, 10),
+		selectedChar:    "X",
+		foregroundColor: "red",
+		backgroundColor: "transparent",
+		textAttrs:       attrBold | attrItalic,
+		selectedTool:    "Point",
+		drawingTool:     "Point",
+		width:           10,
+		height:          11,
+	}
+	m.saveToHistory()
+
+	m.handleMouse(tea.MouseMsg{X: 1, Y: controlBarHeight, Type: tea.MouseLeft})
+	m.handleMouse(tea.MouseMsg{X: 2, Y: controlBarHeight, Type: tea.MouseLeft})
+
+	if cell := m.canvas.Get(0, 2); cell == nil || cell.attrs != attrBold|attrItalic {
+		t.Errorf("cell(0,2) = %+v, want bold+italic", cell)
+	}
+}
+
+func TestToolbarAttributeClickToggles(t *testing.T) {
+	m := initialModel()
+	m.width = 160
+	m.height = 40
+	m.config.Theme = defaultTheme()
+	m.renderControlBar()
+
+	// Letters are two columns apart: B D I U R S
+	underlineX := m.toolbar.attrX + 2*3
+	m.handleMouse(tea.MouseMsg{X: underlineX, Y: 0, Type: tea.MouseLeft})
+	if m.textAttrs != attrUnderline {
+		t.Fatalf("textAttrs = %06b, want underline", m.textAttrs)
+	}
+	m.handleMouse(tea.MouseMsg{X: underlineX, Y: 0, Type: tea.MouseRelease})
+	m.handleMouse(tea.MouseMsg{X: underlineX, Y: 0, Type: tea.MouseLeft})
+	if m.textAttrs != 0 {
+		t.Errorf("second click should clear underline, got %06b", m.textAttrs)
+	}
+	if m.activeMenu() != -1 {
+		t.Error("attribute click should not open a menu")
+	}
+}
+
+func TestScreenToCanvasScrolled(t *testing.T) {
+	m := newScrollModel()
+	m.scrollBy(40, 100)
+	offY, offX := m.canvasOffset()
+
+	x, y := m.screenToCanvas(offX+1, controlBarHeight+offY+2)
+	if x != 100 || y != 40 {
+		t.Errorf("screenToCanvas for viewport origin = (%d,%d), want (100,40)", x, y)
+	}
+}
+
+func TestMouseWheelScrolls(t *testing.T) {
+	m := newScrollModel()
+	m.handleMouse(tea.MouseMsg{X: 10, Y: 10, Type: tea.MouseWheelDown})
+	if m.scrollY != wheelScrollRows || m.scrollX != 0 {
+		t.Errorf("wheel down: scroll = %d,%d, want 0,%d", m.scrollX, m.scrollY, wheelScrollRows)
+	}
+	m.handleMouse(tea.MouseMsg{X: 10, Y: 10, Type: tea.MouseWheelDown, Shift: true})
+	if m.scrollX != wheelScrollCols || m.scrollY != wheelScrollRows {
+		t.Errorf("shift+wheel: scroll = %d,%d, want %d,%d", m.scrollX, m.scrollY, wheelScrollCols, wheelScrollRows)
+	}
+	m.handleMouse(tea.MouseMsg{X: 10, Y: 10, Type: tea.MouseWheelLeft})
+	if m.scrollX != 0 {
+		t.Errorf("wheel left: scrollX = %d, want 0", m.scrollX)
+	}
+}
+
+func TestShiftArrowsPan(t *testing.T) {
+	m := newScrollModel()
+	m.handleKey(tea.KeyMsg{Type: tea.KeyShiftDown})
+	m.handleKey(tea.KeyMsg{Type: tea.KeyShiftRight})
+	m.handleKey(tea.KeyMsg{Type: tea.KeyShiftRight})
+	if m.scrollY != 1 || m.scrollX != 2 {
+		t.Errorf("scroll = %d,%d, want 2,1", m.scrollX, m.scrollY)
+	}
+	if m.activeMenu() != -1 {
+		t.Error("panning should not open a menu")
+	}
+	m.handleKey(tea.KeyMsg{Type: tea.KeyPgDown})
+	rows, _ := m.viewportSize()
+	if m.scrollY != 1+rows {
+		t.Errorf("pgdown: scrollY = %d, want %d", m.scrollY, 1+rows)
+	}
+}
+
+func TestDrawWhileScrolled(t *testing.T) {
+	m := newScrollModel()
+	m.foregroundColor = "white"
+	m.backgroundColor = "transparent"
+	m.saveToHistory()
+	m.scrollBy(50, 120)
+	offY, offX := m.canvasOffset()
+	sx, sy := offX+1+5, controlBarHeight+offY+2+3
+
+	m.handleMouse(tea.MouseMsg{X: sx, Y: sy, Type: tea.MouseLeft})
+	m.handleMouse(tea.MouseMsg{X: sx, Y: sy, Type: tea.MouseLeft})
+	m.handleMouse(tea.MouseMsg{X: sx, Y: sy, Type: tea.MouseRelease})
+
+	if cell := m.canvas.Get(53, 125); cell.char != "●" {
+		t.Errorf("cell(53,125) = %q, want the stroke at scrolled coordinates", cell.char)
+	}
+}
+
+func TestPressOnBorderWhileScrolled(t *testing.T) {
+	m := newScrollModel()
+	m.scrollBy(50, 120)
+	offY, offX := m.canvasOffset()
+
+	// The left border maps to column 119, which exists but is off-screen
+	m.handleMouse(tea.MouseMsg{X: offX, Y: controlBarHeight + offY + 3, Type: tea.MouseLeft})
+	if m.mouseDown {
+		t.Error("pressing on the border should not start a stroke")
+	}
+}
+
+func TestDragPastEdgeAutoScrolls(t *testing.T) {
+	m := newScrollModel()
+	m.saveToHistory()
+	m.setTool("Rectangle")
+	offY, offX := m.canvasOffset()
+	_, cols := m.viewportSize()
+
+	m.handleMouse(tea.MouseMsg{X: offX + 1, Y: controlBarHeight + offY + 2, Type: tea.MouseLeft})
+	m.handleMouse(tea.MouseMsg{X: offX + cols + 1, Y: controlBarHeight + offY + 2, Type: tea.MouseMotion})
+	if m.scrollX != 1 {
+		t.Errorf("scrollX = %d, want 1 after dragging onto the right border", m.scrollX)
+	}
+}
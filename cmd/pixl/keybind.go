@@ -0,0 +1,175 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// actionClearCanvas is run by runAction itself so that its key can be pressed
+// a second time to confirm.
+const actionClearCanvas = "clear-canvas"
+
+// actionFillMode is sent on to the current tool's OnKeyPress.
+const actionFillMode = "fill-mode"
+
+// defaultBindings lists the default keys for each action. Action ids are the
+// kebab-case names of command palette items.
+var defaultBindings = []keyBinding{
+	{"save", []string{"ctrl+s"}},
+	{"open", []string{"ctrl+o"}},
+	{"undo", []string{"u"}},
+	{"redo", []string{"r"}},
+	{"history", []string{"U"}},
+	{"copy", []string{"y"}},
+	{"cut", []string{"d"}},
+	{"paste", []string{"p"}},
+	{"eyedropper", []string{"i"}},
+	{"swap-colors", []string{"x"}},
+	{actionClearCanvas, []string{"c"}},
+	{actionFillMode, []string{"F"}},
+	{"keyboard-cursor", []string{"m"}},
+	{"foreground-menu", []string{menuKeys[menuForeground]}},
+	{"background-menu", []string{menuKeys[menuBackground]}},
+	{"glyph-menu", []string{menuKeys[menuGlyph]}},
+	{"tool-menu", []string{menuKeys[menuTool]}},
+	{"layers", []string{menuKeys[menuLayer]}},
+}
+
+// reservedKeys are handled directly by handleKey and can't be rebound.
+var reservedKeys = map[string]bool{
+	"ctrl+c": true, "q": true, ":": true, "esc": true, "enter": true, "tab": true,
+	"up": true, "down": true, "left": true, "right": true,
+	"shift+up": true, "shift+down": true, "shift+left": true, "shift+right": true,
+	"pgup": true, "pgdown": true, "[": true, "]": true,
+	"1": true, "2": true, "3": true, "4": true, "5": true, "6": true, "7": true, "8": true, "9": true,
+}
+
+type keyBinding struct {
+	action string
+	keys   []string
+}
+
+// actionID converts a palette item name to its action id, e.g.
+// "Swap Colors" becomes "swap-colors".
+func actionID(name string) string {
+	return strings.ReplaceAll(strings.ToLower(name), " ", "-")
+}
+
+// actions maps each action id to its palette item. The items don't depend on
+// the model, so they're built once.
+var actions = buildActions()
+
+func buildActions() map[string]paletteItem {
+	items := (&model{}).paletteItems()
+	byID := make(map[string]paletteItem, len(items))
+	for _, item := range items {
+		byID[actionID(item.name)] = item
+	}
+	return byID
+}
+
+// findAction returns the palette item with the given id.
+func (m *model) findAction(id string) (paletteItem, bool) {
+	item, ok := actions[id]
+	return item, ok
+}
+
+func isValidAction(id string) bool {
+	_, ok := actions[id]
+	return ok
+}
+
+// parseBindingKeys splits a bind value into keys. "space" names the space
+// bar and "none" unbinds the action.
+func parseBindingKeys(val string) []string {
+	var keys []string
+	for _, k := range strings.Fields(val) {
+		switch k {
+		case "none":
+			return nil
+		case "space":
+			k = " "
+		}
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// resolveKeymap merges bindings from the config file over the defaults and
+// returns a key to action id map. A configured key takes precedence over a
+// default binding of the same key; conflicts, including with the keyboard
+// cursor's keys, are reported as warnings.
+func resolveKeymap(bindings []keyBinding) (map[string]string, []string) {
+	var warnings []string
+	keymap := map[string]string{}
+	configured := map[string]bool{}
+	for _, b := range bindings {
+		configured[b.action] = true
+	}
+
+	for _, b := range bindings {
+		for _, key := range b.keys {
+			if reservedKeys[key] {
+				warnings = append(warnings, fmt.Sprintf("key %q is reserved and can't be bound to %s", key, b.action))
+				continue
+			}
+			if other, ok := keymap[key]; ok && other != b.action {
+				warnings = append(warnings, fmt.Sprintf("key %q is bound to both %s and %s", key, other, b.action))
+				continue
+			}
+			keymap[key] = b.action
+			if isCursorKey(key) {
+				warnings = append(warnings, fmt.Sprintf("key %q moves the keyboard cursor, so %s only runs with the cursor off", key, b.action))
+			}
+		}
+	}
+
+	for _, b := range defaultBindings {
+		if configured[b.action] {
+			continue
+		}
+		for _, key := range b.keys {
+			if other, ok := keymap[key]; ok {
+				warnings = append(warnings, fmt.Sprintf("key %q for %s replaces its default binding to %s", key, other, b.action))
+				continue
+			}
+			keymap[key] = b.action
+		}
+	}
+	return keymap, warnings
+}
+
+// keyAction returns the action id bound to a key, or "" if none is.
+func (m *model) keyAction(key string) string {
+	if m.config.Keymap == nil {
+		m.config.Keymap, _ = resolveKeymap(nil)
+	}
+	return m.config.Keymap[key]
+}
+
+// runAction runs a bound action. Clearing the canvas asks for the same key
+// to be pressed twice within a second.
+func (m *model) runAction(id string) tea.Cmd {
+	if id == actionClearCanvas {
+		if !m.confirmClear {
+			m.confirmClear = true
+			return tea.Tick(time.Second, func(time.Time) tea.Msg {
+				return clearConfirmTimeout{}
+			})
+		}
+		m.confirmClear = false
+		if !m.checkLayerEditable() {
+			return nil
+		}
+		m.clearLayer()
+		m.saveToHistoryAs("Clear Canvas")
+		return nil
+	}
+	if item, ok := m.findAction(id); ok {
+		return item.run(m)
+	}
+	return nil
+}
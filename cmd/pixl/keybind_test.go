@@ -0,0 +1,120 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestActionID(t *testing.T) {
+	tests := []struct{ name, want string }{
+		{"Undo", "undo"},
+		{"Swap Colors", "swap-colors"},
+		{"Dashed Heavy Box", "dashed-heavy-box"},
+	}
+	for _, tt := range tests {
+		if got := actionID(tt.name); got != tt.want {
+			t.Errorf("actionID(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestActionIDsUnique(t *testing.T) {
+	seen := map[string]bool{}
+	for _, item := range (&model{}).paletteItems() {
+		id := actionID(item.name)
+		if seen[id] {
+			t.Errorf("duplicate action id %q", id)
+		}
+		seen[id] = true
+	}
+}
+
+func TestDefaultBindingsAreActions(t *testing.T) {
+	for _, b := range defaultBindings {
+		if !isValidAction(b.action) {
+			t.Errorf("default binding %q is not a palette action", b.action)
+		}
+	}
+	keymap, warnings := resolveKeymap(nil)
+	if len(warnings) != 0 {
+		t.Errorf("default bindings conflict: %v", warnings)
+	}
+	for i, key := range menuKeys {
+		if keymap[key] == "" {
+			t.Errorf("menu %d key %q is unbound", i, key)
+		}
+	}
+}
+
+func TestResolveKeymapConflicts(t *testing.T) {
+	keymap, warnings := resolveKeymap([]keyBinding{
+		{"undo", []string{"z"}},
+		{"redo", []string{"z", "Z"}},
+	})
+	if keymap["z"] != "undo" || keymap["Z"] != "redo" {
+		t.Errorf("keymap = %v, want first binding of z to win", keymap)
+	}
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "both undo and redo") {
+		t.Errorf("warnings = %v", warnings)
+	}
+}
+
+func TestResolveKeymapCursorKeyConflicts(t *testing.T) {
+	keymap, warnings := resolveKeymap([]keyBinding{
+		{"undo", []string{"h"}},
+		{"redo", []string{" "}},
+		{"paste", []string{"P"}},
+	})
+	if keymap["h"] != "undo" || keymap[" "] != "redo" {
+		t.Errorf("keymap = %v, want cursor keys still bound", keymap)
+	}
+	if len(warnings) != 2 || !strings.Contains(warnings[0], "keyboard cursor") || !strings.Contains(warnings[1], "redo") {
+		t.Errorf("warnings = %v, want one for each cursor key", warnings)
+	}
+}
+
+func TestDefaultBindingsAvoidCursorKeys(t *testing.T) {
+	for _, b := range defaultBindings {
+		for _, key := range b.keys {
+			if isCursorKey(key) {
+				t.Errorf("default key %q for %s is taken by cursor mode", key, b.action)
+			}
+		}
+	}
+}
+
+func TestReboundKeyRunsAction(t *testing.T) {
+	m := newLayerModel()
+	m.config.Keymap, _ = resolveKeymap([]keyBinding{
+		{"undo", []string{"z"}},
+		{"add-layer", []string{"A"}},
+	})
+
+	m.handleKey(keyMsg("A"))
+	if len(m.layers) != 2 {
+		t.Fatalf("A should add a layer, got %d layers", len(m.layers))
+	}
+	m.handleKey(keyMsg("u"))
+	if len(m.layers) != 2 {
+		t.Error("u should no longer undo once undo is rebound")
+	}
+	m.handleKey(keyMsg("z"))
+	if len(m.layers) != 1 {
+		t.Error("z should undo")
+	}
+}
+
+func TestReboundClearNeedsSameKeyTwice(t *testing.T) {
+	m := newLayerModel()
+	m.config.Keymap, _ = resolveKeymap([]keyBinding{{actionClearCanvas, []string{"X"}}})
+	m.canvas.Set(0, 0, "A", "red", "transparent")
+
+	m.handleKey(keyMsg("X"))
+	if !m.confirmClear {
+		t.Fatal("first X should ask for confirmation")
+	}
+	m.handleKey(keyMsg("X"))
+	if m.confirmClear || m.canvas.Get(0, 0).char != " " {
+		t.Error("second X should clear the canvas")
+	}
+}
@@ -0,0 +1,297 @@
+package main
+
+import "fmt"
+
+// Layer is one canvas in the layer stack. Layers are composited bottom-up
+// using the same transparency rules as paste.
+type Layer struct {
+	name    string
+	visible bool
+	locked  bool
+	canvas  Canvas
+}
+
+var (
+	// blankCell is an untouched cell on the bottom layer.
+	blankCell = Cell{char: " ", foregroundColor: "white", backgroundColor: "transparent"}
+	// transparentCell lets the layers below show through.
+	transparentCell = Cell{char: " ", foregroundColor: "transparent", backgroundColor: "transparent"}
+)
+
+// layerBlank is the cell erasing leaves behind on layer i: the bottom layer
+// erases to a blank space, upper layers to transparency.
+func layerBlank(i int) Cell {
+	if i == 0 {
+		return blankCell
+	}
+	return transparentCell
+}
+
+// newLayerCanvas creates a canvas filled with layer i's blank cell.
+func newLayerCanvas(i, width, height int) Canvas {
+	c := NewCanvas(width, height)
+	if i > 0 {
+		for row := range c.cells {
+			for col := range c.cells[row] {
+				c.cells[row][col] = transparentCell
+			}
+		}
+	}
+	return c
+}
+
+// overlayCell returns top drawn over base. A transparent foreground shows the
+// base glyph through and a transparent background shows the base background.
+func overlayCell(base, top Cell) Cell {
+	if top.foregroundColor == "transparent" && top.backgroundColor == "transparent" {
+		return base
+	}
+	result := top
+	if top.foregroundColor == "transparent" {
+		result.char = base.char
+		result.foregroundColor = base.foregroundColor
+		result.attrs = base.attrs
+	}
+	if top.backgroundColor == "transparent" {
+		result.backgroundColor = base.backgroundColor
+	}
+	return result
+}
+
+// ensureLayers wraps the canvas in a single background layer if the model
+// has no layer stack yet.
+func (m *model) ensureLayers() {
+	if len(m.layers) == 0 {
+		m.layers = []Layer{{name: "Background", visible: true, canvas: m.canvas}}
+		m.activeLayer = 0
+	}
+}
+
+// layerCanvas returns layer i's canvas. m.canvas is the working copy of the
+// active layer, so it takes precedence over the stored slot.
+func (m *model) layerCanvas(i int) *Canvas {
+	if i == m.activeLayer {
+		return &m.canvas
+	}
+	return &m.layers[i].canvas
+}
+
+// syncActiveLayer stores the working canvas back into the active slot.
+func (m *model) syncActiveLayer() {
+	m.ensureLayers()
+	m.layers[m.activeLayer].canvas = m.canvas
+}
+
+func (m *model) setActiveLayer(i int) {
+	m.syncActiveLayer()
+	if i < 0 || i >= len(m.layers) {
+		return
+	}
+	m.activeLayer = i
+	m.canvas = m.layers[i].canvas
+	m.selection.active = false
+	m.textInsertActive = false
+}
+
+func (m *model) activeLayerLocked() bool {
+	return len(m.layers) > 0 && m.layers[m.activeLayer].locked
+}
+
+// checkLayerEditable reports whether the active layer can be drawn on,
+// raising an alert if it is locked.
+func (m *model) checkLayerEditable() bool {
+	if m.activeLayerLocked() {
+		m.alertMessage = fmt.Sprintf("Layer %q is locked", m.layers[m.activeLayer].name)
+		return false
+	}
+	return true
+}
+
+// clearLayer erases every cell of the active layer.
+func (m *model) clearLayer() {
+	blank := layerBlank(m.activeLayer)
+	for row := range m.canvas.cells {
+		for col := range m.canvas.cells[row] {
+			m.canvas.cells[row][col] = blank
+		}
+	}
+}
+
+// compositeCell returns the visible cell at a position after compositing
+// every visible layer.
+func (m *model) compositeCell(row, col int) *Cell {
+	if len(m.layers) == 0 || (len(m.layers) == 1 && m.layers[0].visible) {
+		return m.canvas.Get(row, col)
+	}
+	if m.canvas.Get(row, col) == nil {
+		return nil
+	}
+	result := transparentCell
+	for i := range m.layers {
+		if !m.layers[i].visible {
+			continue
+		}
+		if c := m.layerCanvas(i).Get(row, col); c != nil {
+			result = overlayCell(result, *c)
+		}
+	}
+	return &result
+}
+
+// flattenedCanvas composites the visible layers into a single canvas.
+func (m *model) flattenedCanvas() Canvas {
+	if len(m.layers) == 0 || (len(m.layers) == 1 && m.layers[0].visible) {
+		return m.canvas
+	}
+	flat := NewCanvas(m.canvas.width, m.canvas.height)
+	for row := 0; row < flat.height; row++ {
+		for col := 0; col < flat.width; col++ {
+			flat.cells[row][col] = *m.compositeCell(row, col)
+		}
+	}
+	return flat
+}
+
+// resizeLayers resizes every layer, keeping the overlapping content.
+func (m *model) resizeLayers(width, height int) {
+	m.reframeLayers(width, height, 0, 0)
+}
+
+// nextLayerName returns the first unused "Layer N" name.
+func (m *model) nextLayerName() string {
+	for n := len(m.layers); ; n++ {
+		name := fmt.Sprintf("Layer %d", n)
+		taken := false
+		for _, l := range m.layers {
+			if l.name == name {
+				taken = true
+				break
+			}
+		}
+		if !taken {
+			return name
+		}
+	}
+}
+
+// insertLayer inserts l above the active layer and makes it active.
+func (m *model) insertLayer(l Layer, label string) {
+	m.syncActiveLayer()
+	idx := m.activeLayer + 1
+	m.layers = append(m.layers, Layer{})
+	copy(m.layers[idx+1:], m.layers[idx:])
+	m.layers[idx] = l
+	m.activeLayer = idx
+	m.canvas = l.canvas
+	m.selection.active = false
+	m.saveToHistoryAs(label)
+}
+
+func (m *model) addLayer() {
+	m.ensureLayers()
+	m.insertLayer(Layer{
+		name:    m.nextLayerName(),
+		visible: true,
+		canvas:  newLayerCanvas(1, m.canvas.width, m.canvas.height),
+	}, "Add Layer")
+}
+
+func (m *model) duplicateLayer() {
+	m.syncActiveLayer()
+	src := m.layers[m.activeLayer]
+	m.insertLayer(Layer{
+		name:    src.name + " copy",
+		visible: src.visible,
+		canvas:  src.canvas.Copy(),
+	}, "Duplicate Layer")
+}
+
+func (m *model) deleteLayer() {
+	m.syncActiveLayer()
+	if len(m.layers) <= 1 {
+		m.alertMessage = "Can't delete the only layer"
+		return
+	}
+	idx := m.activeLayer
+	m.layers = append(m.layers[:idx], m.layers[idx+1:]...)
+	if idx > 0 {
+		idx--
+	}
+	m.activeLayer = idx
+	m.canvas = m.layers[idx].canvas
+	m.selection.active = false
+	m.saveToHistoryAs("Delete Layer")
+}
+
+// mergeLayerDown composites the active layer onto the one below it.
+func (m *model) mergeLayerDown() {
+	m.syncActiveLayer()
+	idx := m.activeLayer
+	if idx == 0 {
+		m.alertMessage = "No layer below to merge into"
+		return
+	}
+	upper, lower := m.layers[idx], m.layers[idx-1]
+	if upper.locked || lower.locked {
+		m.alertMessage = "Can't merge locked layers"
+		return
+	}
+	merged := lower.canvas.Copy()
+	for row := 0; row < merged.height; row++ {
+		for col := 0; col < merged.width; col++ {
+			merged.cells[row][col] = overlayCell(merged.cells[row][col], upper.canvas.cells[row][col])
+		}
+	}
+	m.layers[idx-1].canvas = merged
+	m.layers = append(m.layers[:idx], m.layers[idx+1:]...)
+	m.activeLayer = idx - 1
+	m.canvas = merged
+	m.selection.active = false
+	m.saveToHistoryAs("Merge Layer Down")
+}
+
+// flattenLayers replaces the stack with a single layer holding the visible
+// composite. Hidden layers are discarded.
+func (m *model) flattenLayers() {
+	m.syncActiveLayer()
+	if len(m.layers) == 1 {
+		return
+	}
+	flat := m.flattenedCanvas()
+	m.layers = []Layer{{name: "Background", visible: true, canvas: flat}}
+	m.activeLayer = 0
+	m.canvas = flat
+	m.selection.active = false
+	m.saveToHistoryAs("Flatten Layers")
+}
+
+// moveLayer moves the active layer up (dir > 0) or down the stack.
+func (m *model) moveLayer(dir int) {
+	m.syncActiveLayer()
+	idx := m.activeLayer
+	target := idx + dir
+	if target < 0 || target >= len(m.layers) {
+		return
+	}
+	m.layers[idx], m.layers[target] = m.layers[target], m.layers[idx]
+	m.activeLayer = target
+	m.saveToHistoryAs("Move Layer")
+}
+
+func (m *model) toggleLayerVisible(i int) {
+	m.syncActiveLayer()
+	if i < 0 || i >= len(m.layers) {
+		return
+	}
+	m.layers[i].visible = !m.layers[i].visible
+	m.saveToHistoryAs("Toggle Layer Visibility")
+}
+
+func (m *model) toggleLayerLocked(i int) {
+	m.syncActiveLayer()
+	if i < 0 || i >= len(m.layers) {
+		return
+	}
+	m.layers[i].locked = !m.layers[i].locked
+	m.saveToHistoryAs("Toggle Layer Lock")
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Layer panel columns, relative to the start of each content line:
+// " ◉ L Name"
+const (
+	layerEyeCol  = 1
+	layerLockCol = 3
+	layerNameCol = 5
+)
+
+var layerPanelHints = []string{
+	"a add  e rename",
+	"v show  o lock",
+	"⇧↑/⇧↓ reorder",
+}
+
+// layerPanelRow maps a panel row to a layer index. The panel lists the top
+// layer first.
+func (m *model) layerPanelRow(row int) int {
+	return len(m.layers) - 1 - row
+}
+
+func (m *model) layerPanelLineWidth() int {
+	width := 0
+	for i := range m.layers {
+		name := m.layers[i].name
+		if m.layerRenaming && i == m.activeLayer {
+			name = m.layerNameInput + "_"
+		}
+		width = max(width, layerNameCol+lipgloss.Width(name)+1)
+	}
+	for _, h := range layerPanelHints {
+		width = max(width, lipgloss.Width(h)+2)
+	}
+	return width
+}
+
+// layerPanelX returns the screen column of the panel's left border, keeping
+// the panel on screen.
+func (m *model) layerPanelX() int {
+	x := m.toolbar.layerItemX - pickerContentOffset
+	width := m.layerPanelLineWidth() + pickerBorderWidth
+	if m.width > 0 && x+width > m.width {
+		x = m.width - width
+	}
+	return max(x, 0)
+}
+
+func (m *model) renderLayerPanel() string {
+	m.ensureLayers()
+	pickerStyle := lipgloss.NewStyle().
+		Border(lipgloss.NormalBorder()).
+		BorderForeground(themeColor(m.config.Theme.MenuBorder))
+	selectedStyle := lipgloss.NewStyle().
+		Background(themeColor(m.config.Theme.MenuSelectedBg)).
+		Foreground(themeColor(m.config.Theme.MenuSelectedFg))
+	hintStyle := lipgloss.NewStyle().Faint(true)
+
+	lineWidth := m.layerPanelLineWidth()
+
+	var lines []string
+	for row := range m.layers {
+		i := m.layerPanelRow(row)
+		l := m.layers[i]
+		eye := "○"
+		if l.visible {
+			eye = "◉"
+		}
+		lock := " "
+		if l.locked {
+			lock = "L"
+		}
+		name := l.name
+		if m.layerRenaming && i == m.activeLayer {
+			name = m.layerNameInput + "_"
+		}
+		line := " " + eye + " " + lock + " " + name
+		for lipgloss.Width(line) < lineWidth {
+			line += " "
+		}
+		if i == m.activeLayer {
+			line = selectedStyle.Render(line)
+		}
+		lines = append(lines, line)
+	}
+
+	lines = append(lines, strings.Repeat("─", lineWidth))
+	for _, h := range layerPanelHints {
+		line := " " + h
+		for lipgloss.Width(line) < lineWidth {
+			line += " "
+		}
+		lines = append(lines, hintStyle.Render(line))
+	}
+
+	return pickerStyle.Render(strings.Join(lines, "\n"))
+}
+
+// handleLayerPanelKey handles keys while the layer panel is open. It returns
+// false for keys that should fall through to the regular key handling.
+func (m *model) handleLayerPanelKey(msg tea.KeyMsg) bool {
+	m.ensureLayers()
+	if m.layerRenaming {
+		switch msg.Type {
+		case tea.KeyEnter:
+			if name := strings.TrimSpace(m.layerNameInput); name != "" {
+				m.syncActiveLayer()
+				m.layers[m.activeLayer].name = name
+				m.saveToHistoryAs("Rename Layer")
+			}
+			m.layerRenaming = false
+		case tea.KeyEscape:
+			m.layerRenaming = false
+		case tea.KeyBackspace:
+			if r := []rune(m.layerNameInput); len(r) > 0 {
+				m.layerNameInput = string(r[:len(r)-1])
+			}
+		case tea.KeySpace:
+			m.layerNameInput += " "
+		case tea.KeyRunes:
+			m.layerNameInput += string(msg.Runes)
+		}
+		return true
+	}
+
+	switch msg.String() {
+	case "up":
+		m.setActiveLayer(min(m.activeLayer+1, len(m.layers)-1))
+	case "down":
+		m.setActiveLayer(max(m.activeLayer-1, 0))
+	case "shift+up":
+		m.moveLayer(1)
+	case "shift+down":
+		m.moveLayer(-1)
+	case "1", "2", "3", "4", "5", "6", "7", "8", "9":
+		row := int(msg.String()[0] - '1')
+		if row < len(m.layers) {
+			m.setActiveLayer(m.layerPanelRow(row))
+		}
+	case "a":
+		m.addLayer()
+	case "e":
+		m.layerRenaming = true
+		m.layerNameInput = m.layers[m.activeLayer].name
+	case "v":
+		m.toggleLayerVisible(m.activeLayer)
+	case "o":
+		m.toggleLayerLocked(m.activeLayer)
+	case "enter":
+		m.closeMenus()
+	default:
+		return false
+	}
+	return true
+}
+
+// layerPanelClick handles a click inside the layer panel. Clicking the eye or
+// lock column toggles it; clicking elsewhere on a row selects the layer.
+func (m *model) layerPanelClick(msg tea.MouseMsg) bool {
+	m.ensureLayers()
+	left := m.layerPanelX()
+	width := m.layerPanelLineWidth() + pickerBorderWidth
+	height := len(m.layers) + 1 + len(layerPanelHints) + pickerBorderWidth
+	top := controlBarHeight
+	if msg.Y < top || msg.Y >= top+height || msg.X < left || msg.X >= left+width {
+		return false
+	}
+
+	row := msg.Y - top - 1
+	if row < 0 || row >= len(m.layers) {
+		return true
+	}
+	i := m.layerPanelRow(row)
+	switch msg.X - left - 1 {
+	case layerEyeCol:
+		m.toggleLayerVisible(i)
+	case layerLockCol:
+		m.toggleLayerLocked(i)
+	default:
+		m.setActiveLayer(i)
+	}
+	return true
+}
@@ -0,0 +1,303 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func newLayerModel() *model {
+	m := initialModel()
+	m.canvas = NewCanvas(4, 2)
+	m.width = 80
+	m.height = 20
+	m.config.Theme = defaultTheme()
+	m.saveToHistory()
+	return m
+}
+
+func TestOverlayCell(t *testing.T) {
+	base := Cell{char: "A", foregroundColor: "red", backgroundColor: "blue", attrs: attrBold}
+	tests := []struct {
+		name string
+		top  Cell
+		want Cell
+	}{
+		{"transparent", transparentCell, base},
+		{"opaque", Cell{char: "B", foregroundColor: "green", backgroundColor: "black"},
+			Cell{char: "B", foregroundColor: "green", backgroundColor: "black"}},
+		{"glyph over background", Cell{char: "B", foregroundColor: "green", backgroundColor: "transparent"},
+			Cell{char: "B", foregroundColor: "green", backgroundColor: "blue"}},
+		{"background under glyph", Cell{char: " ", foregroundColor: "transparent", backgroundColor: "yellow"},
+			Cell{char: "A", foregroundColor: "red", backgroundColor: "yellow", attrs: attrBold}},
+	}
+	for _, tt := range tests {
+		if got := overlayCell(base, tt.top); got != tt.want {
+			t.Errorf("%s: overlayCell = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestAddLayerDrawsIntoActiveLayer(t *testing.T) {
+	m := newLayerModel()
+	m.canvas.Set(0, 0, "A", "red", "transparent")
+	m.addLayer()
+
+	if len(m.layers) != 2 || m.activeLayer != 1 || m.layers[1].name != "Layer 1" {
+		t.Fatalf("layers = %d active = %d, want new active Layer 1", len(m.layers), m.activeLayer)
+	}
+	if cell := m.canvas.Get(0, 0); *cell != transparentCell {
+		t.Errorf("new layer cell = %+v, want transparent", cell)
+	}
+
+	m.canvas.Set(0, 1, "B", "green", "transparent")
+	if cell := m.layerCanvas(0).Get(0, 1); cell.char != " " {
+		t.Errorf("drawing leaked into the background layer: %+v", cell)
+	}
+	if c := m.compositeCell(0, 0); c.char != "A" {
+		t.Errorf("composite(0,0) = %+v, want background A to show through", c)
+	}
+	if c := m.compositeCell(0, 1); c.char != "B" {
+		t.Errorf("composite(0,1) = %+v, want B", c)
+	}
+}
+
+func TestHiddenLayerIsNotComposited(t *testing.T) {
+	m := newLayerModel()
+	m.addLayer()
+	m.canvas.Set(0, 0, "B", "green", "transparent")
+	m.toggleLayerVisible(1)
+
+	if c := m.compositeCell(0, 0); c.char != " " {
+		t.Errorf("composite(0,0) = %+v, hidden layer should not show", c)
+	}
+	if out := m.renderCanvasPlain(); strings.Contains(out, "B") {
+		t.Errorf("hidden layer exported: %q", out)
+	}
+}
+
+func TestRenderCanvasPlainFlattensLayers(t *testing.T) {
+	m := newLayerModel()
+	m.canvas.Set(0, 0, "A", "red", "blue")
+	m.addLayer()
+	m.canvas.Set(0, 0, "B", "white", "transparent")
+
+	want := "\x1b[44mB\x1b[0m   \n    \n"
+	if got := m.renderCanvasPlain(); got != want {
+		t.Errorf("renderCanvasPlain() = %q, want %q", got, want)
+	}
+}
+
+func TestMergeLayerDown(t *testing.T) {
+	m := newLayerModel()
+	m.canvas.Set(0, 0, "A", "red", "blue")
+	m.canvas.Set(1, 1, "C", "red", "transparent")
+	m.addLayer()
+	m.canvas.Set(0, 0, "B", "white", "transparent")
+	m.mergeLayerDown()
+
+	if len(m.layers) != 1 || m.activeLayer != 0 {
+		t.Fatalf("layers = %d active = %d, want 1 and 0", len(m.layers), m.activeLayer)
+	}
+	if cell := m.canvas.Get(0, 0); cell.char != "B" || cell.backgroundColor != "blue" {
+		t.Errorf("merged (0,0) = %+v, want B over blue", cell)
+	}
+	if cell := m.canvas.Get(1, 1); cell.char != "C" {
+		t.Errorf("merged (1,1) = %+v, want C kept", cell)
+	}
+}
+
+func TestMergeDownRefusesBottomAndLocked(t *testing.T) {
+	m := newLayerModel()
+	m.mergeLayerDown()
+	if m.alertMessage == "" {
+		t.Error("merging the bottom layer should alert")
+	}
+
+	m.alertMessage = ""
+	m.addLayer()
+	m.toggleLayerLocked(0)
+	m.mergeLayerDown()
+	if m.alertMessage == "" || len(m.layers) != 2 {
+		t.Error("merging into a locked layer should alert and keep both layers")
+	}
+}
+
+func TestDuplicateAndDeleteLayer(t *testing.T) {
+	m := newLayerModel()
+	m.canvas.Set(0, 0, "A", "red", "transparent")
+	m.duplicateLayer()
+
+	if len(m.layers) != 2 || m.layers[1].name != "Background copy" {
+		t.Fatalf("duplicate produced %d layers, top %q", len(m.layers), m.layers[len(m.layers)-1].name)
+	}
+	m.canvas.Set(0, 0, "Z", "red", "transparent")
+	if m.layerCanvas(0).Get(0, 0).char != "A" {
+		t.Error("duplicate should not share cells with its source")
+	}
+
+	m.deleteLayer()
+	if len(m.layers) != 1 || m.canvas.Get(0, 0).char != "A" {
+		t.Errorf("after delete: %d layers, cell %q", len(m.layers), m.canvas.Get(0, 0).char)
+	}
+	m.deleteLayer()
+	if len(m.layers) != 1 || m.alertMessage == "" {
+		t.Error("deleting the only layer should be refused")
+	}
+}
+
+func TestFlattenLayersDropsHidden(t *testing.T) {
+	m := newLayerModel()
+	m.canvas.Set(0, 0, "A", "red", "transparent")
+	m.addLayer()
+	m.canvas.Set(0, 1, "B", "red", "transparent")
+	m.addLayer()
+	m.canvas.Set(0, 2, "C", "red", "transparent")
+	m.toggleLayerVisible(2)
+	m.flattenLayers()
+
+	if len(m.layers) != 1 || m.layers[0].name != "Background" {
+		t.Fatalf("flatten left %d layers", len(m.layers))
+	}
+	if got := m.canvas.Get(0, 0).char + m.canvas.Get(0, 1).char + m.canvas.Get(0, 2).char; got != "AB " {
+		t.Errorf("flattened row = %q, want %q", got, "AB ")
+	}
+}
+
+func TestMoveLayer(t *testing.T) {
+	m := newLayerModel()
+	m.addLayer()
+	m.addLayer()
+	m.setActiveLayer(1)
+	m.moveLayer(1)
+
+	if m.activeLayer != 2 || m.layers[2].name != "Layer 1" || m.layers[1].name != "Layer 2" {
+		t.Errorf("after move up: active %d, order %q %q", m.activeLayer, m.layers[1].name, m.layers[2].name)
+	}
+	m.moveLayer(1)
+	if m.activeLayer != 2 {
+		t.Error("moving the top layer up should do nothing")
+	}
+}
+
+func TestUndoRestoresLayerStack(t *testing.T) {
+	m := newLayerModel()
+	m.addLayer()
+	m.canvas.Set(0, 0, "B", "red", "transparent")
+	m.saveToHistory()
+
+	m.undo()
+	if len(m.layers) != 2 || m.canvas.Get(0, 0).char != " " {
+		t.Errorf("undo stroke: %d layers, cell %q", len(m.layers), m.canvas.Get(0, 0).char)
+	}
+	m.undo()
+	if len(m.layers) != 1 || m.activeLayer != 0 {
+		t.Errorf("undo add: %d layers, active %d", len(m.layers), m.activeLayer)
+	}
+	m.redo()
+	m.redo()
+	if len(m.layers) != 2 || m.activeLayer != 1 || m.canvas.Get(0, 0).char != "B" {
+		t.Error("redo should restore the layer and its contents")
+	}
+}
+
+func TestLockedLayerBlocksDrawing(t *testing.T) {
+	m := newLayerModel()
+	m.fixedWidth, m.fixedHeight = 4, 2
+	m.toggleLayerLocked(0)
+	offY, offX := m.canvasOffset()
+
+	m.handleMouse(tea.MouseMsg{X: offX + 1, Y: offY + controlBarHeight + 2, Type: tea.MouseLeft})
+	if m.mouseDown {
+		t.Error("pressing on a locked layer should not start a stroke")
+	}
+	if m.alertMessage == "" {
+		t.Error("expected a locked-layer alert")
+	}
+}
+
+func TestEraseOnUpperLayerLeavesTransparency(t *testing.T) {
+	m := newLayerModel()
+	m.addLayer()
+	m.setTool("Text")
+	m.textInsertActive = true
+	m.canvas.Set(0, 0, "x", "red", "transparent")
+	m.textInsertCol = 1
+	m.handleTextKey(tea.KeyMsg{Type: tea.KeyBackspace})
+
+	if cell := m.canvas.Get(0, 0); *cell != transparentCell {
+		t.Errorf("erased cell = %+v, want transparent", cell)
+	}
+}
+
+func TestLayerPanelKeys(t *testing.T) {
+	m := newLayerModel()
+	m.handleKey(keyMsg("n"))
+	if !m.showLayerPicker {
+		t.Fatal("n should open the layer panel")
+	}
+
+	m.handleKey(keyMsg("a"))
+	if len(m.layers) != 2 || m.activeLayer != 1 {
+		t.Fatalf("a should add a layer, got %d", len(m.layers))
+	}
+	m.handleKey(keyMsg("down"))
+	if m.activeLayer != 0 {
+		t.Errorf("down should select the layer below, active = %d", m.activeLayer)
+	}
+	m.handleKey(keyMsg("v"))
+	if m.layers[0].visible {
+		t.Error("v should hide the active layer")
+	}
+	m.handleKey(keyMsg("o"))
+	if !m.layers[0].locked {
+		t.Error("o should lock the active layer")
+	}
+
+	m.handleKey(keyMsg("e"))
+	for range "Background" {
+		m.handleKey(keyMsg("backspace"))
+	}
+	for _, k := range []string{"S", "k", "y"} {
+		m.handleKey(keyMsg(k))
+	}
+	m.handleKey(keyMsg("enter"))
+	if m.layers[0].name != "Sky" {
+		t.Errorf("rename gave %q, want Sky", m.layers[0].name)
+	}
+	if !m.showLayerPicker {
+		t.Error("finishing a rename should keep the panel open")
+	}
+
+	m.handleKey(tea.KeyMsg{Type: tea.KeyShiftUp})
+	if m.activeLayer != 1 || m.layers[1].name != "Sky" {
+		t.Errorf("shift+up should move the layer up, got active %d", m.activeLayer)
+	}
+}
+
+func TestLayerPanelClick(t *testing.T) {
+	m := newLayerModel()
+	m.addLayer()
+	m.renderControlBar()
+	m.openMenu(menuLayer)
+	left := m.layerPanelX()
+	top := controlBarHeight + 1 // first row: top layer
+
+	// Row 1 is the background layer
+	m.handleMouse(tea.MouseMsg{X: left + 1 + layerNameCol, Y: top + 1, Type: tea.MouseLeft})
+	if m.activeLayer != 0 {
+		t.Errorf("clicking a row should select it, active = %d", m.activeLayer)
+	}
+	m.handleMouse(tea.MouseMsg{X: left + 1 + layerEyeCol, Y: top, Type: tea.MouseLeft})
+	if m.layers[1].visible {
+		t.Error("clicking the eye column should hide the layer")
+	}
+	m.handleMouse(tea.MouseMsg{X: left + 1 + layerLockCol, Y: top, Type: tea.MouseLeft})
+	if !m.layers[1].locked {
+		t.Error("clicking the lock column should lock the layer")
+	}
+	if !strings.Contains(m.renderLayerPanel(), "○ L Layer 1") {
+		t.Errorf("panel should show hidden+locked state:\n%s", m.renderLayerPanel())
+	}
+}
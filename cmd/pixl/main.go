@@ -18,10 +18,13 @@ type toolbarLayout struct {
 	backgroundX     int
 	toolX           int
 	glyphX          int
+	layerX          int
+	attrX           int
 	foregroundItemX int
 	backgroundItemX int
 	toolItemX       int
 	glyphItemX      int
+	layerItemX      int
 }
 
 type selectionState struct {
@@ -30,12 +33,14 @@ type selectionState struct {
 	startX int
 	endY   int
 	endX   int
+	mask   cellMask // selected cells, or nil for the inside of the rectangle
 }
 
 type clipboardData struct {
 	cells  [][]Cell
 	width  int
 	height int
+	mask   cellMask // copied cells relative to the top-left, nil if all
 }
 
 type model struct {
@@ -43,6 +48,7 @@ type model struct {
 	selectedChar       string
 	foregroundColor    string
 	backgroundColor    string
+	textAttrs          cellAttrs
 	mouseX             int
 	mouseY             int
 	width              int
@@ -50,15 +56,51 @@ type model struct {
 	ready              bool
 	showFgPicker          bool
 	showBgPicker          bool
+	colorPickerExtended   bool
+	colorInputFocused     bool
+	colorInput            string
+	colorInputInvalid     bool
+	colorGridRow          int
+	colorGridCol          int
+	recentColors          []string
 	showToolPicker           bool
 	toolPickerFocusLevel    int
 	showGlyphPicker         bool
 	glyphPickerFocusLevel   int
+	showLayerPicker         bool
+	layerRenaming           bool
+	layerNameInput          string
 	selectedTool            string
 	drawingTool             string
 	selectedCategory        int
-	history            []Canvas
+	layers             []Layer
+	activeLayer        int
+	history            []historyEntry
 	historyIndex       int
+	historyBase        historyState
+	showHistory        bool
+	historyCursor      int
+	savedHistoryIndex  int
+	showPathPrompt     bool
+	pathPromptMode     pathPromptMode
+	pathInput          string
+	pathInputMatches   []string
+	showSizePrompt     bool
+	sizePromptMode     sizePromptMode
+	sizeInput          string
+	sizePromptError    string
+	confirmQuit        bool
+	pendingOpen        string
+	discardOnQuit      bool
+	noSave             bool
+	backedUp           map[string]bool
+	recoveryFile       string
+	autosaveFailed     bool
+	pendingRecovery    string
+	recoveryTime       time.Time
+	confirmCrop        bool
+	cropWidth          int
+	cropHeight         int
 	mouseDown          bool
 	canvasBeforeStroke Canvas
 	startX             int
@@ -69,17 +111,40 @@ type model struct {
 	optionKeyHeld      bool
 	circleMode         bool
 	boxStyle           int
+	fillMode           fillMode
+	fillChar           string
+	fillColor          string
+	selectModeIndex    int
+	selectionOp        selectionOp
+	lassoPath          [][2]int
+	polylineModeIndex  int
+	polyline           [][2]int
+	polylineEnd        [2]int
 	previewPoints      map[[2]int]bool
+	previewFill        map[[2]int]bool
+	previewGlyphs      map[[2]int]string
+	connectorVertical  bool
+	arrowStart         bool
+	arrowEnd           bool
 	selection          selectionState
+	floating           floatingSelection
 	clipboard          clipboardData
 	hoverRow           int
 	hoverCol           int
+	hoverDotY          int
+	hoverDotX          int
+	pixelMouse         pixelMouse
 	cursorVisible      bool
+	keyboardCursor     bool
+	grid               drawGrid
 	lastMenu           int
 	config             Config
 	filePath           string
+	meta               documentMeta
 	fixedWidth         int
 	fixedHeight        int
+	scrollX            int
+	scrollY            int
 	confirmClear       bool
 	showPalette        bool
 	paletteQuery       string
@@ -105,30 +170,44 @@ func initialModel() *model {
 		selectedChar:    "●",
 		foregroundColor: "white",
 		backgroundColor: "transparent",
+		fillChar:        "░",
+		fillColor:       "white",
 		selectedTool:    "Point",
 		drawingTool:     "Point",
 		ready:           false,
-		history:         []Canvas{},
+		history:         []historyEntry{},
 		historyIndex:    -1,
 		mouseDown:       false,
 	}
 }
 
+// loadText replaces the document with the text, on a canvas just large
+// enough for it so none is clipped before the canvas size is settled.
+func (m *model) loadText(text string) {
+	width, height := textCanvasSize(text)
+	m.layers = nil
+	m.canvas = NewCanvas(max(width, 1), max(height, 1))
+	m.canvas.LoadText(text)
+}
+
 func (m *model) Init() tea.Cmd {
+	cmds := []tea.Cmd{m.autosaveCmd(), writeTerminal(queryCellSize + queryPixelMouse)}
 	if len(m.config.Warnings) > 0 {
 		m.alertMessage = "Config:\n" + strings.Join(m.config.Warnings, "\n")
-		return tea.Tick(5*time.Second, func(time.Time) tea.Msg {
+		cmds = append(cmds, tea.Tick(5*time.Second, func(time.Time) tea.Msg {
 			return alertTimeout{}
-		})
+		}))
 	}
-	return nil
+	return tea.Batch(cmds...)
 }
 
 func main() {
 	flagW := flag.Int("w", 0, "fixed canvas width")
 	flagH := flag.Int("h", 0, "fixed canvas height")
+	flagExport := flag.String("export", "", "also write the canvas as ANSI text to this file on quit")
+	flagNoSave := flag.Bool("no-save", false, "quit without writing the file or printing the canvas")
 	flag.Usage = func() {
-		fmt.Fprintf(os.Stderr, "Usage: pixl [--help] [-w width] [-h height] [file]\n\nOptions:\n")
+		fmt.Fprintf(os.Stderr, "Usage: pixl [--help] [-w width] [-h height] [-export file] [--no-save] [file]\n\nOptions:\n")
 		flag.PrintDefaults()
 	}
 	flag.Parse()
@@ -136,6 +215,7 @@ func main() {
 	m := initialModel()
 	m.config = loadConfig()
 	m.applyConfig()
+	m.noSave = *flagNoSave
 
 	opts := []tea.ProgramOption{
 		tea.WithAltScreen(),
@@ -152,8 +232,17 @@ func main() {
 		}
 		data, err := os.ReadFile(m.filePath)
 		if err == nil && len(data) > 0 {
-			fileText = string(data)
-			m.canvas.LoadText(fileText)
+			if isNativePath(m.filePath) {
+				doc, err := decodeDocument(data)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "Error reading %s: %v\n", m.filePath, err)
+					os.Exit(1)
+				}
+				m.applyDocument(doc)
+			} else {
+				fileText = string(data)
+				m.loadText(fileText)
+			}
 		}
 	}
 
@@ -162,7 +251,7 @@ func main() {
 		data, err := io.ReadAll(io.LimitReader(os.Stdin, 10<<20))
 		if err == nil && len(data) > 0 {
 			stdinText = string(data)
-			m.canvas.LoadText(stdinText)
+			m.loadText(stdinText)
 		}
 		tty, err := os.Open("/dev/tty")
 		if err != nil {
@@ -173,6 +262,8 @@ func main() {
 		opts = append(opts, tea.WithInput(tty))
 	}
 
+	m.findRecovery()
+
 	inputText := stdinText
 	if inputText == "" {
 		inputText = fileText
@@ -182,17 +273,9 @@ func main() {
 		m.fixedWidth = *flagW
 		m.fixedHeight = *flagH
 	} else if inputText != "" && *flagW == 0 && *flagH == 0 {
-		lines := strings.Split(strings.TrimRight(inputText, "\n"), "\n")
-		maxWidth := 0
-		for _, line := range lines {
-			w := visibleWidth(line)
-			if w > maxWidth {
-				maxWidth = w
-			}
-		}
-		if maxWidth > 0 && len(lines) > 0 {
-			m.fixedWidth = maxWidth
-			m.fixedHeight = len(lines)
+		if width, height := textCanvasSize(inputText); width > 0 {
+			m.fixedWidth = width
+			m.fixedHeight = height
 		}
 	}
 
@@ -204,16 +287,25 @@ func main() {
 		os.Exit(1)
 	}
 
-	if fm, ok := finalModel.(*model); ok {
-		output := fm.renderCanvas()
-		if fm.filePath != "" {
-			output = fm.renderCanvasPlain()
-			if err := saveFile(fm.filePath, output); err != nil {
+	// Named documents are saved in the session (quitting asks first if there
+	// are unsaved changes); unnamed ones are printed. Either way the session
+	// ended cleanly, so its recovery file is no longer needed.
+	fm, ok := finalModel.(*model)
+	if ok {
+		fm.removeRecovery()
+		if fm.pixelMouse.requested {
+			fmt.Print(disablePixelMouse)
+		}
+	}
+	if ok && !fm.discardOnQuit {
+		if *flagExport != "" {
+			if err := saveFile(*flagExport, fm.renderCanvasPlain()); err != nil {
 				fmt.Fprintf(os.Stderr, "Error writing file: %v\n", err)
 				os.Exit(1)
 			}
-		} else {
-			fmt.Print(output)
+		}
+		if fm.filePath == "" && !fm.noSave {
+			fmt.Print(fm.renderCanvas())
 		}
 	}
 }
@@ -5,11 +5,13 @@ const (
 	menuBackground
 	menuGlyph
 	menuTool
+	menuLayer
 	menuCount
 )
 
-// menuKeys maps each menu index to the key that toggles it.
-var menuKeys = [menuCount]string{"f", "b", "g", "t"}
+// menuKeys maps each menu index to the default key that toggles it. None of
+// them are keyboard cursor keys, so they all work in cursor mode.
+var menuKeys = [menuCount]string{"f", "b", "g", "t", "n"}
 
 func (m *model) activeMenu() int {
 	flags := [menuCount]*bool{
@@ -17,6 +19,7 @@ func (m *model) activeMenu() int {
 		&m.showBgPicker,
 		&m.showGlyphPicker,
 		&m.showToolPicker,
+		&m.showLayerPicker,
 	}
 	for i, f := range flags {
 		if *f {
@@ -31,6 +34,9 @@ func (m *model) openMenu(idx int) {
 	m.showBgPicker = idx == menuBackground
 	m.showGlyphPicker = idx == menuGlyph
 	m.showToolPicker = idx == menuTool
+	m.showLayerPicker = idx == menuLayer
+	m.layerRenaming = false
+	m.colorPickerExtended = false
 	m.toolPickerFocusLevel = 0
 	m.glyphPickerFocusLevel = 0
 	if idx == menuGlyph {
@@ -41,7 +47,17 @@ func (m *model) openMenu(idx int) {
 	}
 }
 
+// toggleMenu opens a menu, or closes it if it is already open.
+func (m *model) toggleMenu(idx int) {
+	if m.activeMenu() == idx {
+		m.closeMenus()
+	} else {
+		m.openMenu(idx)
+	}
+}
+
 func (m *model) setTool(tool string) {
+	m.commitPolyline()
 	m.selectedTool = tool
 	m.selection.active = false
 	if tool != "Text" {
@@ -57,6 +73,9 @@ func (m *model) closeMenus() {
 	m.showBgPicker = false
 	m.showGlyphPicker = false
 	m.showToolPicker = false
+	m.showLayerPicker = false
+	m.layerRenaming = false
+	m.colorPickerExtended = false
 	m.toolPickerFocusLevel = 0
 	m.glyphPickerFocusLevel = 0
 }
@@ -128,7 +147,7 @@ func (m *model) setToolPickerIndex(idx int) {
 }
 
 func (m *model) toolHasSubmenu() bool {
-	return isDrawingTool(m.selectedTool) || m.selectedTool == "Box"
+	return isDrawingTool(m.selectedTool) || m.selectedTool == "Box" || m.selectedTool == "Select"
 }
 
 func (m *model) toolSubmenuCount() int {
@@ -138,6 +157,9 @@ func (m *model) toolSubmenuCount() int {
 	if m.selectedTool == "Box" {
 		return len(boxStyles)
 	}
+	if m.selectedTool == "Select" {
+		return len(selectModes)
+	}
 	return 0
 }
 
@@ -148,6 +170,9 @@ func (m *model) toolSubmenuIndex() int {
 	if m.selectedTool == "Box" {
 		return m.boxStyle
 	}
+	if m.selectedTool == "Select" {
+		return m.selectModeIndex
+	}
 	return 0
 }
 
@@ -163,6 +188,9 @@ func (m *model) setToolSubmenuIndex(idx int) {
 	if m.selectedTool == "Box" {
 		m.boxStyle = idx
 	}
+	if m.selectedTool == "Select" {
+		m.selectModeIndex = idx
+	}
 }
 
 func (m *model) drawingToolOptionIndex() int {
@@ -1,6 +1,8 @@
 package main
 
 import (
+	"fmt"
+	"strconv"
 	"strings"
 
 	"github.com/charmbracelet/lipgloss"
@@ -95,11 +97,66 @@ func normalizeColorName(name string) string {
 	return strings.ReplaceAll(name, "-", "_")
 }
 
+// Besides the named colors, canvas colors may be a 256-color palette index
+// ("0"-"255") or a 24-bit hex value ("#rrggbb").
+
+// ansi256Index returns the palette index for a 256-color name.
+func ansi256Index(name string) (int, bool) {
+	if name == "" || len(name) > 3 {
+		return 0, false
+	}
+	n, err := strconv.Atoi(name)
+	if err != nil || n < 0 || n > 255 {
+		return 0, false
+	}
+	return n, true
+}
+
+// hexRGB returns the components of a "#rrggbb" color.
+func hexRGB(name string) (r, g, b int, ok bool) {
+	if len(name) != 7 || name[0] != '#' {
+		return 0, 0, 0, false
+	}
+	v, err := strconv.ParseUint(name[1:], 16, 32)
+	if err != nil {
+		return 0, 0, 0, false
+	}
+	return int(v >> 16 & 0xff), int(v >> 8 & 0xff), int(v & 0xff), true
+}
+
+func hexColor(r, g, b int) string {
+	return fmt.Sprintf("#%02x%02x%02x", r, g, b)
+}
+
+// canonicalColor normalizes extended colors so equal colors compare equal:
+// hex digits are lowercased and palette indexes lose leading zeros.
+func canonicalColor(name string) string {
+	if n, ok := ansi256Index(name); ok {
+		return strconv.Itoa(n)
+	}
+	if r, g, b, ok := hexRGB(name); ok {
+		return hexColor(r, g, b)
+	}
+	return name
+}
+
 func colorToANSI(name string) string {
+	if n, ok := ansi256Index(name); ok {
+		return "38;5;" + strconv.Itoa(n)
+	}
+	if r, g, b, ok := hexRGB(name); ok {
+		return fmt.Sprintf("38;2;%d;%d;%d", r, g, b)
+	}
 	return ansiColorCodes[normalizeColorName(name)]
 }
 
 func colorToANSIBg(name string) string {
+	if n, ok := ansi256Index(name); ok {
+		return "48;5;" + strconv.Itoa(n)
+	}
+	if r, g, b, ok := hexRGB(name); ok {
+		return fmt.Sprintf("48;2;%d;%d;%d", r, g, b)
+	}
 	return ansiBgColorCodes[normalizeColorName(name)]
 }
 
@@ -110,6 +167,12 @@ func colorDisplayName(name string) string {
 	if name == "transparent" {
 		return "None"
 	}
+	if n, ok := ansi256Index(name); ok {
+		return fmt.Sprintf("Color %d", n)
+	}
+	if _, _, _, ok := hexRGB(name); ok {
+		return strings.ToUpper(name)
+	}
 	display := strings.ReplaceAll(name, "_", " ")
 	return strings.ToUpper(display[:1]) + display[1:]
 }
@@ -126,14 +189,46 @@ func colorStyleByName(name string) lipgloss.Style {
 	if s, ok := colorStyleMap[normalizeColorName(name)]; ok {
 		return s
 	}
+	if isExtendedColor(name) {
+		return lipgloss.NewStyle().Foreground(lipgloss.Color(canonicalColor(name)))
+	}
 	return lipgloss.NewStyle()
 }
 
-func isValidCanvasColor(name string) bool {
-	_, ok := colorStyleMap[normalizeColorName(name)]
+// cellStyle returns the style for drawing a glyph with the given colors and
+// text attributes.
+func cellStyle(fg, bg string, attrs cellAttrs) lipgloss.Style {
+	style := colorStyleByName(fg)
+	if bg != "transparent" {
+		style = style.Background(colorStyleByName(bg).GetForeground())
+	}
+	if attrs != 0 {
+		style = style.
+			Bold(attrs&attrBold != 0).
+			Faint(attrs&attrDim != 0).
+			Italic(attrs&attrItalic != 0).
+			Underline(attrs&attrUnderline != 0).
+			Reverse(attrs&attrReverse != 0).
+			Strikethrough(attrs&attrStrikethrough != 0)
+	}
+	return style
+}
+
+func isExtendedColor(name string) bool {
+	if _, ok := ansi256Index(name); ok {
+		return true
+	}
+	_, _, _, ok := hexRGB(name)
 	return ok
 }
 
+func isValidCanvasColor(name string) bool {
+	if _, ok := colorStyleMap[normalizeColorName(name)]; ok {
+		return true
+	}
+	return isExtendedColor(name)
+}
+
 func (m *model) findSelectedCharCategory() int {
 	for i, group := range characterGroups {
 		for _, char := range group.chars {
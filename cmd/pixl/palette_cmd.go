@@ -12,6 +12,22 @@ type paletteItem struct {
 	action func(m *model)
 }
 
+// paletteCommands finish palette actions that need a command, such as
+// writing to the terminal, keyed by action id.
+var paletteCommands = map[string]func(m *model) tea.Cmd{
+	"copy": (*model).exportClipboard,
+	"cut":  (*model).exportClipboard,
+}
+
+// run runs the item's action, returning the command it needs, if any.
+func (item paletteItem) run(m *model) tea.Cmd {
+	item.action(m)
+	if command, ok := paletteCommands[actionID(item.name)]; ok {
+		return command(m)
+	}
+	return nil
+}
+
 func (m *model) paletteItems() []paletteItem {
 	items := []paletteItem{
 		{"Point", func(m *model) { m.setTool("Point") }},
@@ -20,10 +36,31 @@ func (m *model) paletteItems() []paletteItem {
 		{"Circle", func(m *model) { m.setTool("Ellipse"); m.circleMode = true }},
 		{"Line", func(m *model) { m.setTool("Line") }},
 		{"Fill", func(m *model) { m.setTool("Fill") }},
-		{"Select", func(m *model) { m.setTool("Select") }},
 		{"Text", func(m *model) { m.setTool("Text") }},
 	}
 
+	for i, mode := range selectModes {
+		idx := i
+		items = append(items, paletteItem{
+			mode.name,
+			func(m *model) { m.setTool("Select"); m.selectModeIndex = idx },
+		})
+	}
+
+	for i, mode := range polylineModes {
+		idx := i
+		items = append(items, paletteItem{
+			mode.name,
+			func(m *model) { m.setTool("Polyline"); m.polylineModeIndex = idx },
+		})
+	}
+
+	items = append(items,
+		paletteItem{"Connector", func(m *model) { m.setTool("Connector") }},
+		paletteItem{"Arrow at Start", func(m *model) { m.arrowStart = !m.arrowStart }},
+		paletteItem{"Arrow at End", func(m *model) { m.arrowEnd = !m.arrowEnd }},
+	)
+
 	for i, s := range boxStyles {
 		idx := i
 		items = append(items, paletteItem{
@@ -33,9 +70,26 @@ func (m *model) paletteItems() []paletteItem {
 	}
 
 	items = append(items,
+		paletteItem{"Save", func(m *model) { m.save() }},
+		paletteItem{"Save As", func(m *model) { m.openPathPrompt(promptSaveAs) }},
+		paletteItem{"Open", func(m *model) { m.openPathPrompt(promptOpen) }},
 		paletteItem{"Clear Canvas", func(m *model) { m.confirmClear = true }},
+		paletteItem{"Resize Canvas", func(m *model) { m.openSizePrompt(promptResizeCanvas) }},
+		paletteItem{"Expand Canvas", func(m *model) { m.openSizePrompt(promptExpandCanvas) }},
+		paletteItem{"Crop to Selection", func(m *model) { m.cropToSelection() }},
+		paletteItem{"Trim Canvas", func(m *model) { m.trimCanvas() }},
+		paletteItem{"Flip Horizontal", func(m *model) { m.transformSelection(flipHorizontal) }},
+		paletteItem{"Flip Vertical", func(m *model) { m.transformSelection(flipVertical) }},
+		paletteItem{"Rotate Clockwise", func(m *model) { m.transformSelection(rotateClockwise) }},
+		paletteItem{"Rotate Counterclockwise", func(m *model) { m.transformSelection(rotateCounterclockwise) }},
+		paletteItem{"Rotate 180", func(m *model) { m.transformSelection(rotate180) }},
 		paletteItem{"Undo", func(m *model) { m.undo() }},
 		paletteItem{"Redo", func(m *model) { m.redo() }},
+		paletteItem{"History", func(m *model) { m.toggleHistoryPanel() }},
+		paletteItem{"Older State", func(m *model) { m.stepHistoryTime(-1) }},
+		paletteItem{"Newer State", func(m *model) { m.stepHistoryTime(1) }},
+		paletteItem{"Next Branch", func(m *model) { m.switchHistoryBranch(1) }},
+		paletteItem{"Previous Branch", func(m *model) { m.switchHistoryBranch(-1) }},
 		paletteItem{"Copy", func(m *model) { m.copySelection() }},
 		paletteItem{"Cut", func(m *model) { m.cutSelection() }},
 		paletteItem{"Paste", func(m *model) { m.paste() }},
@@ -43,12 +97,54 @@ func (m *model) paletteItems() []paletteItem {
 			m.foregroundColor, m.backgroundColor = m.backgroundColor, m.foregroundColor
 		}},
 		paletteItem{"Eyedropper", func(m *model) {
-			if cell := m.canvas.Get(m.hoverRow, m.hoverCol); cell != nil {
+			if m.toolGrid() == pixelGrid {
+				if cell := m.compositeCell(m.hoverRow, m.hoverCol); cell != nil {
+					m.foregroundColor = pixelOf(*cell, m.hoverDotY)
+				}
+				return
+			}
+			if cell := m.compositeCell(m.hoverRow, m.hoverCol); cell != nil {
 				m.selectedChar = cell.char
 				m.foregroundColor = cell.foregroundColor
 				m.backgroundColor = cell.backgroundColor
+				m.textAttrs = cell.attrs
 			}
 		}},
+		paletteItem{"Fill Mode", func(m *model) { m.tool().OnKeyPress(m, actionFillMode) }},
+		paletteItem{"Set Fill Glyph", func(m *model) { m.fillChar = m.selectedChar }},
+		paletteItem{"Set Fill Color", func(m *model) { m.fillColor = m.foregroundColor }},
+		paletteItem{"Keyboard Cursor", func(m *model) { m.toggleKeyboardCursor() }},
+		paletteItem{"Pixel Mode", func(m *model) { m.toggleGrid(pixelGrid) }},
+		paletteItem{"Braille Mode", func(m *model) { m.toggleGrid(brailleGrid) }},
+	)
+
+	for _, a := range textAttributes {
+		attr := a.attr
+		items = append(items, paletteItem{
+			"Toggle " + a.name,
+			func(m *model) { m.textAttrs ^= attr },
+		})
+	}
+	items = append(items, paletteItem{"Clear Attributes", func(m *model) { m.textAttrs = 0 }})
+
+	items = append(items,
+		paletteItem{"Add Layer", func(m *model) { m.addLayer() }},
+		paletteItem{"Delete Layer", func(m *model) { m.deleteLayer() }},
+		paletteItem{"Duplicate Layer", func(m *model) { m.duplicateLayer() }},
+		paletteItem{"Merge Layer Down", func(m *model) { m.mergeLayerDown() }},
+		paletteItem{"Flatten Layers", func(m *model) { m.flattenLayers() }},
+		paletteItem{"Move Layer Up", func(m *model) { m.moveLayer(1) }},
+		paletteItem{"Move Layer Down", func(m *model) { m.moveLayer(-1) }},
+		paletteItem{"Toggle Layer Visibility", func(m *model) { m.toggleLayerVisible(m.activeLayer) }},
+		paletteItem{"Toggle Layer Lock", func(m *model) { m.toggleLayerLocked(m.activeLayer) }},
+		paletteItem{"Layers", func(m *model) { m.toggleMenu(menuLayer) }},
+	)
+
+	items = append(items,
+		paletteItem{"Foreground Menu", func(m *model) { m.toggleMenu(menuForeground) }},
+		paletteItem{"Background Menu", func(m *model) { m.toggleMenu(menuBackground) }},
+		paletteItem{"Glyph Menu", func(m *model) { m.toggleMenu(menuGlyph) }},
+		paletteItem{"Tool Menu", func(m *model) { m.toggleMenu(menuTool) }},
 	)
 
 	return items
@@ -80,11 +176,12 @@ func (m *model) handlePaletteKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		return m, nil
 	case tea.KeyEnter:
 		items := filterPalette(m.paletteItems(), m.paletteQuery)
+		var cmd tea.Cmd
 		if m.paletteIndex < len(items) {
-			items[m.paletteIndex].action(m)
+			cmd = items[m.paletteIndex].run(m)
 		}
 		m.closePalette()
-		return m, nil
+		return m, cmd
 	case tea.KeyBackspace:
 		if len(m.paletteQuery) == 0 {
 			m.closePalette()
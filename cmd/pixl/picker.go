@@ -84,6 +84,24 @@ func (m *model) renderGlyphsPicker() string {
 }
 
 func (m *model) renderDrawingToolPicker() string {
+	names := make([]string, len(drawingToolOptions))
+	for i, opt := range drawingToolOptions {
+		names[i] = opt.name
+	}
+	return m.renderOptionPicker(names)
+}
+
+func (m *model) renderSelectModePicker() string {
+	names := make([]string, len(selectModes))
+	for i, mode := range selectModes {
+		names[i] = mode.name
+	}
+	return m.renderOptionPicker(names)
+}
+
+// renderOptionPicker renders a tool submenu listing the tool's options by
+// name, with the current one highlighted.
+func (m *model) renderOptionPicker(names []string) string {
 	pickerStyle := lipgloss.NewStyle().
 		Border(lipgloss.NormalBorder()).
 		BorderForeground(themeColor(m.config.Theme.MenuBorder))
@@ -100,16 +118,16 @@ func (m *model) renderDrawingToolPicker() string {
 	highlightIdx := m.toolSubmenuIndex()
 
 	maxNameWidth := 0
-	for _, opt := range drawingToolOptions {
-		if w := lipgloss.Width(opt.name); w > maxNameWidth {
+	for _, name := range names {
+		if w := lipgloss.Width(name); w > maxNameWidth {
 			maxNameWidth = w
 		}
 	}
 	lineWidth := 1 + maxNameWidth + 1
 
 	var content strings.Builder
-	for i, opt := range drawingToolOptions {
-		line := " " + opt.name
+	for i, name := range names {
+		line := " " + name
 		for lipgloss.Width(line) < lineWidth {
 			line += " "
 		}
@@ -119,7 +137,7 @@ func (m *model) renderDrawingToolPicker() string {
 		} else {
 			content.WriteString(line)
 		}
-		if i < len(drawingToolOptions)-1 {
+		if i < len(names)-1 {
 			content.WriteString("\n")
 		}
 	}
@@ -134,6 +152,9 @@ func (m *model) renderToolSubmenuPicker() string {
 	if m.selectedTool == "Box" {
 		return m.renderBoxStylePicker()
 	}
+	if m.selectedTool == "Select" {
+		return m.renderSelectModePicker()
+	}
 	return ""
 }
 
@@ -232,6 +253,10 @@ func (m *model) renderBoxStylePicker() string {
 }
 
 func (m *model) renderColorPicker(title string) string {
+	if m.colorPickerExtended {
+		return m.renderExtendedColorPicker()
+	}
+
 	pickerStyle := lipgloss.NewStyle().
 		Border(lipgloss.NormalBorder()).
 		BorderForeground(themeColor(m.config.Theme.MenuBorder))
@@ -253,7 +278,7 @@ func (m *model) renderColorPicker(title string) string {
 	}
 
 	var content strings.Builder
-	for i, color := range colors {
+	for _, color := range colors {
 		var swatch string
 		if color.name == "transparent" {
 			swatch = "  "
@@ -271,11 +296,14 @@ func (m *model) renderColorPicker(title string) string {
 		if color.name == currentColor {
 			name = selectedStyle.Render(name)
 		}
-		content.WriteString(fmt.Sprintf(" %s %s", swatch, name))
-		if i < len(colors)-1 {
-			content.WriteString("\n")
-		}
+		content.WriteString(fmt.Sprintf(" %s %s\n", swatch, name))
+	}
+
+	more := colorPickerMore
+	for lipgloss.Width(more) < maxNameWidth {
+		more += " "
 	}
+	content.WriteString(fmt.Sprintf(" ⋯  %s ", more))
 
 	return pickerStyle.Render(content.String())
 }
@@ -0,0 +1,123 @@
+package main
+
+// Half-block glyphs. In pixel mode each cell holds two square pixels, the
+// upper and lower half, drawn with these glyphs and the cell's colors.
+const (
+	upperHalfBlock = "▀"
+	lowerHalfBlock = "▄"
+	fullBlock      = "█"
+)
+
+// cellPixels returns the colors of a cell's upper and lower pixels. Half and
+// full blocks are read from their colors; any other glyph is ignored, so
+// both pixels are its background.
+func cellPixels(c Cell) (upper, lower string) {
+	switch c.char {
+	case upperHalfBlock:
+		return c.foregroundColor, c.backgroundColor
+	case lowerHalfBlock:
+		return c.backgroundColor, c.foregroundColor
+	case fullBlock:
+		return c.foregroundColor, c.foregroundColor
+	}
+	return c.backgroundColor, c.backgroundColor
+}
+
+// pixelCell returns the cell showing two pixels. Transparent pixels use the
+// cell's background, so a lone pixel is a half block over whatever is below
+// it, and a cell with neither pixel set is blank.
+func pixelCell(upper, lower string, blank Cell) Cell {
+	switch {
+	case upper == lower && upper == "transparent":
+		return blank
+	case upper == lower:
+		return Cell{char: fullBlock, foregroundColor: upper, backgroundColor: "transparent"}
+	case upper == "transparent":
+		return Cell{char: lowerHalfBlock, foregroundColor: lower, backgroundColor: "transparent"}
+	}
+	return Cell{char: upperHalfBlock, foregroundColor: upper, backgroundColor: lower}
+}
+
+// pixelOf returns the color of the upper (half 0) or lower (half 1) pixel
+// of a cell.
+func pixelOf(c Cell, half int) string {
+	upper, lower := cellPixels(c)
+	if half == 1 {
+		return lower
+	}
+	return upper
+}
+
+// pixelAt returns the color of a pixel on the active layer. ok is false if
+// the pixel is off the canvas.
+func (m *model) pixelAt(py, px int) (color string, ok bool) {
+	if py < 0 {
+		return "", false
+	}
+	cell := m.canvas.Get(py/2, px)
+	if cell == nil {
+		return "", false
+	}
+	return pixelOf(*cell, py%2), true
+}
+
+// setPixel colors a pixel on the active layer, keeping the other pixel of
+// its cell.
+func (m *model) setPixel(py, px int, color string) {
+	if py < 0 {
+		return
+	}
+	cell := m.canvas.Get(py/2, px)
+	if cell == nil {
+		return
+	}
+	upper, lower := cellPixels(*cell)
+	if py%2 == 0 {
+		upper = color
+	} else {
+		lower = color
+	}
+	m.canvas.SetCell(py/2, px, pixelCell(upper, lower, layerBlank(m.activeLayer)))
+}
+
+// fillPixels flood fills the pixels connected to the given one that share
+// its color with the foreground color.
+func (m *model) fillPixels(py, px int) {
+	target, ok := m.pixelAt(py, px)
+	if !ok || target == m.foregroundColor {
+		return
+	}
+	same := func(y, x int) bool {
+		c, ok := m.pixelAt(y, x)
+		return ok && c == target
+	}
+	for _, p := range connectedPoints(py, px, same) {
+		m.setPixel(p[0], p[1], m.foregroundColor)
+	}
+}
+
+// renderPixels renders a cell in pixel mode with the stroke preview and the
+// cursor drawn on its pixels. ok is false if neither is on the cell.
+func (m *model) renderPixels(row, col int) (string, bool) {
+	cell := m.compositeCell(row, col)
+	if cell == nil {
+		return "", false
+	}
+	var pixels [2]string
+	pixels[0], pixels[1] = cellPixels(*cell)
+	drawn := false
+	for half := range pixels {
+		if color, ok := m.dotColor(2*row+half, col); ok {
+			pixels[half] = color
+			drawn = true
+		}
+	}
+	if !drawn {
+		return "", false
+	}
+	c := pixelCell(pixels[0], pixels[1], transparentCell)
+	if c.foregroundColor == "transparent" {
+		return " ", true
+	}
+	return cellStyle(c.foregroundColor, c.backgroundColor, 0).Render(c.char), true
+}
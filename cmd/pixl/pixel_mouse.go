@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Terminals report the mouse in whole cells, which leaves the lower pixel
+// and most braille dots out of its reach. Those supporting SGR-Pixel mouse
+// reports (mode 1016) can give the position in pixels instead. pixl asks
+// for the size of a cell and whether the mode is known, turns it on once
+// both answers are in, and converts each report back to a cell and the dot
+// within it. Terminals that don't answer keep reporting cells.
+const (
+	queryCellSize     = "\x1b[16t"     // replies CSI 6 ; height ; width t
+	queryPixelMouse   = "\x1b[?1016$p" // replies CSI ? 1016 ; state $ y
+	enablePixelMouse  = "\x1b[?1016h"
+	disablePixelMouse = "\x1b[?1016l"
+)
+
+// pixelMouse is the state of SGR-Pixel mouse reporting.
+type pixelMouse struct {
+	cellHeight, cellWidth int  // in pixels, 0 until the terminal answers
+	requested             bool // the mode has been turned on
+	on                    bool // the terminal confirmed it, so reports are in pixels
+}
+
+// writeTerminal returns a command writing a control sequence to the
+// terminal, alongside the frames tea renders there.
+func writeTerminal(seq string) tea.Cmd {
+	return func() tea.Msg {
+		_, _ = io.WriteString(os.Stdout, seq)
+		return nil
+	}
+}
+
+// csiReply returns the parameters and final byte of a control sequence the
+// terminal sent that tea doesn't recognize, such as the answer to a query.
+// tea passes these on as a message whose String() lists the bytes after
+// ESC [ in decimal.
+func csiReply(msg tea.Msg) (string, bool) {
+	s, ok := msg.(fmt.Stringer)
+	if !ok {
+		return "", false
+	}
+	list, ok := strings.CutPrefix(s.String(), "?CSI[")
+	if !ok {
+		return "", false
+	}
+	list, ok = strings.CutSuffix(list, "]?")
+	if !ok {
+		return "", false
+	}
+	var seq []byte
+	for _, f := range strings.Fields(list) {
+		b, err := strconv.Atoi(f)
+		if err != nil || b < 0 || b > 0xff {
+			return "", false
+		}
+		seq = append(seq, byte(b))
+	}
+	return string(seq), true
+}
+
+// handleTerminalReply takes in the answers to the queries above, and turns
+// pixel reports on when the terminal knows the mode and its cell size.
+func (m *model) handleTerminalReply(seq string) tea.Cmd {
+	if size, ok := strings.CutSuffix(seq, "t"); ok {
+		var height, width int
+		if n, _ := fmt.Sscanf(size, "6;%d;%d", &height, &width); n == 2 && height > 0 && width > 0 {
+			m.pixelMouse.cellHeight, m.pixelMouse.cellWidth = height, width
+		}
+		return nil
+	}
+	state, ok := strings.CutPrefix(seq, "?1016;")
+	if !ok {
+		return nil
+	}
+	switch state {
+	case "1$y":
+		m.pixelMouse.on = m.pixelMouse.cellHeight > 0
+	case "2$y":
+		// Ask again after turning it on, so that reports are only read
+		// as pixels once the terminal has switched
+		m.pixelMouse.on = false
+		if m.pixelMouse.cellHeight > 0 && !m.pixelMouse.requested {
+			m.pixelMouse.requested = true
+			return writeTerminal(enablePixelMouse + queryPixelMouse)
+		}
+	}
+	return nil
+}
+
+// toCells converts a mouse report in pixels to the cell it falls in, and
+// the dot within that cell on a grid of rows x cols dots per cell.
+func (p pixelMouse) toCells(msg tea.MouseMsg, rows, cols int) (tea.MouseMsg, int, int) {
+	y, x := max(msg.Y, 0), max(msg.X, 0)
+	msg.Y, msg.X = y/p.cellHeight, x/p.cellWidth
+	return msg, y % p.cellHeight * rows / p.cellHeight, x % p.cellWidth * cols / p.cellWidth
+}
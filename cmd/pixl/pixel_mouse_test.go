@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// csiMsg formats a control sequence the way tea reports one it doesn't
+// recognize.
+type csiMsg []byte
+
+func (c csiMsg) String() string {
+	return fmt.Sprintf("?CSI%+v?", []byte(c)[2:])
+}
+
+func TestCSIReply(t *testing.T) {
+	if seq, ok := csiReply(csiMsg("\x1b[?1016;2$y")); !ok || seq != "?1016;2$y" {
+		t.Errorf("csiReply = %q, %v, want the parameters and final byte", seq, ok)
+	}
+	if _, ok := csiReply(tea.KeyMsg{Type: tea.KeyEnter}); ok {
+		t.Error("a key isn't a reply")
+	}
+}
+
+func TestPixelMouseHandshake(t *testing.T) {
+	m := newDrawModel("Point", 4, 3)
+	if cmd := m.handleTerminalReply("?1016;2$y"); cmd != nil || m.pixelMouse.requested {
+		t.Fatal("the mode shouldn't be turned on before the cell size is known")
+	}
+	m.handleTerminalReply("6;20;10t")
+	if m.pixelMouse.cellHeight != 20 || m.pixelMouse.cellWidth != 10 {
+		t.Fatalf("cell size = %dx%d, want 10x20", m.pixelMouse.cellWidth, m.pixelMouse.cellHeight)
+	}
+	if cmd := m.handleTerminalReply("?1016;2$y"); cmd == nil || !m.pixelMouse.requested || m.pixelMouse.on {
+		t.Fatal("a known mode should be turned on, but reports read as cells until confirmed")
+	}
+	if cmd := m.handleTerminalReply("?1016;2$y"); cmd != nil {
+		t.Error("a terminal refusing the mode shouldn't be asked again")
+	}
+	m.handleTerminalReply("?1016;1$y")
+	if !m.pixelMouse.on {
+		t.Error("reports should be read as pixels once the terminal confirms the mode")
+	}
+}
+
+func TestPixelMouseReachesEveryDot(t *testing.T) {
+	// Cells are 10x20 pixels; press and release in the given pixel of a
+	// canvas cell
+	press := func(m *model, row, col, y, x int) {
+		m.pixelMouse = pixelMouse{cellHeight: 20, cellWidth: 10, requested: true, on: true}
+		y, x = (controlBarHeight+row)*20+y, col*10+x
+		m.handleMouse(tea.MouseMsg{X: x, Y: y, Type: tea.MouseLeft})
+		m.handleMouse(tea.MouseMsg{X: x, Y: y, Type: tea.MouseRelease})
+	}
+
+	m := newDrawModel("Point", 4, 3)
+	m.grid = pixelGrid
+	press(m, 1, 2, 15, 3)
+	if m.hoverRow != 1 || m.hoverCol != 2 {
+		t.Errorf("hover = (%d,%d), want the cell (1,2)", m.hoverRow, m.hoverCol)
+	}
+	checkPixels(t, m, map[[2]int]string{{3, 2}: "red", {2, 2}: "transparent"})
+
+	m = newDrawModel("Point", 4, 3)
+	m.grid = brailleGrid
+	press(m, 1, 2, 17, 7)
+	if dots := brailleDots(*m.canvas.Get(1, 2)); dots != brailleBits[3][1] {
+		t.Errorf("cell (1,2) has dots %b, want only the bottom right one", dots)
+	}
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func checkPixels(t *testing.T, m *model, want map[[2]int]string) {
+	t.Helper()
+	for p, color := range want {
+		if got, _ := m.pixelAt(p[0], p[1]); got != color {
+			t.Errorf("pixel (%d,%d) = %q, want %q", p[0], p[1], got, color)
+		}
+	}
+}
+
+func TestPixelCellRoundTrip(t *testing.T) {
+	colors := []string{"transparent", "red", "blue"}
+	for _, upper := range colors {
+		for _, lower := range colors {
+			c := pixelCell(upper, lower, blankCell)
+			if u, l := cellPixels(c); u != upper || l != lower {
+				t.Errorf("pixelCell(%s, %s) = %q reads back as (%s, %s)", upper, lower, c.char, u, l)
+			}
+		}
+	}
+	if c := pixelCell("red", "red", blankCell); c.char != fullBlock {
+		t.Errorf("two red pixels = %q, want a full block", c.char)
+	}
+	if c := pixelCell("transparent", "transparent", blankCell); c != blankCell {
+		t.Errorf("no pixels = %+v, want the blank cell", c)
+	}
+}
+
+func TestPixelLineUsesBothHalves(t *testing.T) {
+	m := newDrawModel("Line", 4, 3)
+	m.grid = pixelGrid
+	drag(m, 0, 0, 2, 0)
+
+	// Pixel rows 0 to 4 fill two cells and the top of the third
+	checkCells(t, m, 4, 3, map[[2]int]string{{0, 0}: fullBlock, {1, 0}: fullBlock, {2, 0}: upperHalfBlock})
+	checkPixels(t, m, map[[2]int]string{{4, 0}: "red", {5, 0}: "transparent"})
+}
+
+func TestPixelKeepsOtherHalf(t *testing.T) {
+	m := newDrawModel("Point", 4, 3)
+	m.grid = pixelGrid
+	m.toggleKeyboardCursor()
+	m.setPixel(0, 1, "blue")
+	pressKeys(m, " ", "j", "l", " ")
+
+	checkPixels(t, m, map[[2]int]string{{0, 0}: "red", {1, 0}: "red", {1, 1}: "red", {0, 1}: "blue"})
+	if cell := m.canvas.Get(0, 1); *cell != (Cell{char: upperHalfBlock, foregroundColor: "blue", backgroundColor: "red"}) {
+		t.Errorf("cell (0,1) = %+v, want blue over red", *cell)
+	}
+}
+
+func TestKeyboardCursorStepsByPixel(t *testing.T) {
+	m := newDrawModel("Point", 4, 3)
+	m.grid = pixelGrid
+	m.toggleKeyboardCursor()
+	pressKeys(m, "j", " ", " ")
+	if m.hoverRow != 0 || m.hoverDotY != 1 {
+		t.Errorf("cursor at row %d half %d, want row 0 half 1", m.hoverRow, m.hoverDotY)
+	}
+	checkPixels(t, m, map[[2]int]string{{0, 0}: "transparent", {1, 0}: "red"})
+
+	pressKeys(m, "j", "j", "j", "j", "j")
+	if m.hoverRow != 2 || m.hoverDotY != 1 {
+		t.Errorf("cursor at row %d half %d, want the last pixel row", m.hoverRow, m.hoverDotY)
+	}
+}
+
+func TestFillPixels(t *testing.T) {
+	m := newDrawModel("Fill", 4, 3)
+	m.grid = pixelGrid
+	m.toggleKeyboardCursor()
+	for y := 0; y < 6; y++ {
+		m.setPixel(y, 1, "blue")
+	}
+	pressKeys(m, "j", " ", " ")
+
+	checkPixels(t, m, map[[2]int]string{{0, 0}: "red", {5, 0}: "red", {3, 1}: "blue", {3, 2}: "transparent"})
+	if cell := m.canvas.Get(1, 0); cell.char != fullBlock {
+		t.Errorf("cell (1,0) = %q, want a full block", cell.char)
+	}
+}
+
+func TestPixelCircleIsRound(t *testing.T) {
+	m := newDrawModel("Ellipse", 4, 3)
+	m.grid = pixelGrid
+	m.canvas = NewCanvas(20, 20)
+	points := m.getCirclePoints(20, 10, 20, 15, true)
+	minY, minX, maxY, maxX, _ := maskBounds(cellMask(points))
+	if maxY-minY != maxX-minX {
+		t.Errorf("circle spans %d rows and %d columns, want the same", maxY-minY+1, maxX-minX+1)
+	}
+}
+
+func TestPixelEyedropperSamplesHalf(t *testing.T) {
+	m := newDrawModel("Point", 4, 3)
+	m.grid = pixelGrid
+	m.toggleKeyboardCursor()
+	m.setPixel(1, 0, "green")
+	m.runAction("eyedropper")
+	if m.foregroundColor != "transparent" {
+		t.Errorf("upper pixel sampled %q, want transparent", m.foregroundColor)
+	}
+	pressKeys(m, "j")
+	m.runAction("eyedropper")
+	if m.foregroundColor != "green" {
+		t.Errorf("lower pixel sampled %q, want green", m.foregroundColor)
+	}
+}
+
+func TestPixelPreviewAndModeToggle(t *testing.T) {
+	m := newDrawModel("Rectangle", 4, 3)
+	m.grid = pixelGrid
+	m.runAction("pixel-mode")
+	if m.grid != cellGrid {
+		t.Fatal("pixel-mode should toggle pixel mode off")
+	}
+	m.runAction("pixel-mode")
+
+	m.beginStroke(0, 0)
+	m.dragStroke(1, 2)
+	if got := m.renderCellAt(1, 1); !strings.Contains(got, upperHalfBlock) {
+		t.Errorf("preview at (1,1) = %q, want the rectangle's upper half", got)
+	}
+	if got := m.renderCellAt(0, 1); !strings.Contains(got, upperHalfBlock) {
+		t.Errorf("preview at (0,1) = %q, want the top edge on the upper half", got)
+	}
+
+	// Other tools keep drawing glyphs on cells
+	m.endStroke(1, 2)
+	m.setTool("Box")
+	if m.toolGrid() != cellGrid {
+		t.Error("the Box tool should not draw pixels")
+	}
+}
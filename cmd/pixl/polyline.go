@@ -0,0 +1,154 @@
+package main
+
+type polylineMode struct {
+	name   string
+	closed bool // joins the last vertex back to the first
+	box    bool // draws box-drawing lines along straight segments
+}
+
+// polylineModes are the Polyline tool's modes, cycled with enter when no
+// polyline is in progress.
+var polylineModes = []polylineMode{
+	{"Polyline", false, false},
+	{"Polygon", true, false},
+	{"Box Polyline", false, true},
+	{"Box Polygon", true, true},
+}
+
+func (m *model) polylineMode() polylineMode {
+	if m.polylineModeIndex >= 0 && m.polylineModeIndex < len(polylineModes) {
+		return polylineModes[m.polylineModeIndex]
+	}
+	return polylineModes[0]
+}
+
+// polylineFills reports whether the polyline draws a fill, which only
+// closed polygons have.
+func (m *model) polylineFills() bool {
+	return m.polylineMode().closed && m.fillMode != fillNone
+}
+
+// snapPolylinePoint keeps a box polyline's segments straight, moving a point
+// level with or directly above or below the last vertex, whichever is
+// nearer.
+func (m *model) snapPolylinePoint(y, x int) [2]int {
+	if !m.polylineMode().box || len(m.polyline) == 0 {
+		return [2]int{y, x}
+	}
+	last := m.polyline[len(m.polyline)-1]
+	dy, dx := y-last[0], x-last[1]
+	if dy*dy > dx*dx {
+		return [2]int{y, last[1]}
+	}
+	return [2]int{last[0], x}
+}
+
+// polylineShape returns the glyph for each cell of the outline through the
+// given vertices, and the cells its fill covers.
+func (m *model) polylineShape(vertices [][2]int) (glyphs map[[2]int]string, fill map[[2]int]bool) {
+	mode := m.polylineMode()
+	closed := mode.closed && len(vertices) > 2
+	if mode.box {
+		var path [][2]int
+		for i, v := range vertices {
+			if i == 0 {
+				path = append(path, v)
+				continue
+			}
+			path = append(path, elbowPath(vertices[i-1], v, true)[1:]...)
+		}
+		if closed {
+			path = append(path, elbowPath(vertices[len(vertices)-1], vertices[0], true)[1:]...)
+		}
+		glyphs = m.boxPathGlyphs(path)
+	} else {
+		glyphs = map[[2]int]string{}
+		ends := vertices
+		if closed {
+			ends = append(ends[:len(ends):len(ends)], vertices[0])
+		}
+		for i := range ends {
+			j := max(i-1, 0)
+			for p := range getLinePoints(ends[j][0], ends[j][1], ends[i][0], ends[i][1]) {
+				glyphs[p] = m.selectedChar
+			}
+		}
+	}
+
+	if closed && m.polylineFills() {
+		outline := make(map[[2]int]bool, len(glyphs))
+		for p := range glyphs {
+			outline[p] = true
+		}
+		area := scanlineFill(vertices)
+		for p := range outline {
+			area[p] = true
+		}
+		fill = m.fillAreaPoints(outline, area)
+	}
+	if closed && !m.drawsOutline() {
+		glyphs = nil
+	}
+	return glyphs, fill
+}
+
+// movePolylineEnd moves the loose end of the polyline in progress and
+// updates its preview.
+func (m *model) movePolylineEnd(y, x int) {
+	m.polylineEnd = m.snapPolylinePoint(m.clampToCanvas(y, x))
+	m.previewGlyphs, m.previewFill = m.polylineShape(append(m.polyline[:len(m.polyline):len(m.polyline)], m.polylineEnd))
+	m.showPreview = true
+}
+
+// trackPolyline follows the pointer with the polyline's loose end between
+// clicks.
+func (m *model) trackPolyline(y, x int) {
+	if m.selectedTool == "Polyline" && len(m.polyline) > 0 && !m.mouseDown {
+		m.movePolylineEnd(y, x)
+	}
+}
+
+// addPolylineVertex adds a vertex to the polyline in progress. Adding the
+// last vertex again, as a double-click does, finishes it.
+func (m *model) addPolylineVertex(y, x int) {
+	p := m.snapPolylinePoint(y, x)
+	if p == m.polyline[len(m.polyline)-1] {
+		if len(m.polyline) > 1 {
+			m.commitPolyline()
+			return
+		}
+	} else {
+		m.polyline = append(m.polyline, p)
+	}
+	m.movePolylineEnd(p[0], p[1])
+}
+
+// commitPolyline draws the polyline in progress through its vertices,
+// leaving out the loose end, and records it in history. A stroke finishing
+// the polyline then sees no change of its own to record.
+func (m *model) commitPolyline() {
+	if len(m.polyline) == 0 {
+		return
+	}
+	before := m.canvas.Copy()
+	glyphs, fill := m.polylineShape(m.polyline)
+	for p := range fill {
+		m.canvas.SetCell(p[0], p[1], m.fillCell())
+	}
+	for p, g := range glyphs {
+		m.canvas.SetCell(p[0], p[1], m.brushCell(g))
+	}
+	m.cancelPolyline()
+	if !m.canvas.Equals(before) {
+		m.saveToHistoryAs("Polyline")
+	}
+	m.canvasBeforeStroke = m.canvas.Copy()
+}
+
+// cancelPolyline abandons the polyline in progress.
+func (m *model) cancelPolyline() {
+	m.polyline = nil
+	m.previewGlyphs = nil
+	m.previewFill = nil
+	m.showPreview = false
+}
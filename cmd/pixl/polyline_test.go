@@ -0,0 +1,109 @@
+package main
+
+import "testing"
+
+func TestPolylineClicks(t *testing.T) {
+	m := newDrawModel("Polyline", 9, 7)
+	steps := len(m.history)
+	click(m, 0, 0)
+	click(m, 0, 4)
+	click(m, 4, 4)
+	if len(m.history) != steps {
+		t.Fatal("placing vertices shouldn't change the canvas")
+	}
+
+	// Clicking the last vertex again finishes the polyline
+	click(m, 4, 4)
+	checkCells(t, m, 9, 7, map[[2]int]string{{0, 0}: "#", {0, 4}: "#", {2, 4}: "#", {4, 4}: "#", {2, 2}: " ", {4, 0}: " "})
+	if len(m.history) != steps+1 {
+		t.Errorf("history grew by %d, want 1", len(m.history)-steps)
+	}
+	if m.polyline != nil || m.showPreview {
+		t.Error("finishing should clear the polyline and its preview")
+	}
+}
+
+func TestPolylineRubberBand(t *testing.T) {
+	m := newDrawModel("Polyline", 9, 7)
+	click(m, 0, 0)
+	m.trackPolyline(2, 2)
+	if _, ok := m.tool().RenderPreview(m, 1, 1); !ok {
+		t.Error("the preview should follow the pointer")
+	}
+	if m.canvas.Get(1, 1).char != " " {
+		t.Error("the preview shouldn't draw")
+	}
+
+	pressKeys(m, "esc")
+	if m.polyline != nil || m.showPreview {
+		t.Error("esc should cancel the polyline")
+	}
+	checkCells(t, m, 9, 7, map[[2]int]string{{0, 0}: " ", {1, 1}: " "})
+}
+
+func TestPolygonFromKeyboard(t *testing.T) {
+	m := newDrawModel("Polyline", 9, 7)
+	m.runAction("polygon")
+	m.toggleKeyboardCursor()
+	pressKeys(m, " ", "l", "l", "l", " ", "j", "j", "j", " ", "enter")
+
+	// The closing edge runs diagonally back to the first vertex
+	checkCells(t, m, 9, 7, map[[2]int]string{{0, 3}: "#", {3, 3}: "#", {1, 1}: "#", {2, 2}: "#", {1, 2}: " "})
+}
+
+func TestPolygonFill(t *testing.T) {
+	m := newDrawModel("Polyline", 9, 7)
+	m.runAction("polygon")
+	m.fillMode = fillOutlined
+
+	// An L shape, whose inside isn't convex
+	for _, p := range [][2]int{{0, 0}, {0, 2}, {4, 2}, {4, 6}, {6, 6}, {6, 0}, {6, 0}} {
+		click(m, p[0], p[1])
+	}
+	checkCells(t, m, 9, 7, map[[2]int]string{{0, 1}: "#", {3, 1}: "░", {5, 4}: "░", {2, 4}: " ", {4, 4}: "#"})
+	if m.canvas.Get(3, 1).foregroundColor != "blue" {
+		t.Error("the fill should use the fill color")
+	}
+}
+
+func TestBoxPolygon(t *testing.T) {
+	m := newDrawModel("Polyline", 9, 7)
+	m.runAction("box-polygon")
+	m.fillMode = fillOutlined
+	click(m, 0, 0)
+	click(m, 0, 4)
+
+	// Box segments snap straight to the last vertex
+	click(m, 3, 5)
+	click(m, 3, 0)
+	pressKeys(m, "enter")
+	checkCells(t, m, 9, 7, map[[2]int]string{{0, 0}: "┌", {0, 2}: "─", {0, 4}: "┐", {2, 4}: "│", {3, 4}: "┘", {3, 0}: "└", {1, 0}: "│", {2, 2}: "░", {3, 5}: " "})
+}
+
+func TestBoxPolylineMergesBorders(t *testing.T) {
+	m := newDrawModel("Polyline", 9, 7)
+	m.runAction("box-polyline")
+	m.config.MergeBoxBorders = true
+	m.canvas.Set(2, 0, "─", "red", "transparent")
+	m.canvas.Set(2, 1, "─", "red", "transparent")
+	m.canvas.Set(2, 2, "─", "red", "transparent")
+	click(m, 0, 1)
+	click(m, 2, 1)
+	click(m, 2, 1)
+	checkCells(t, m, 9, 7, map[[2]int]string{{0, 1}: "│", {1, 1}: "│", {2, 1}: "┴", {2, 2}: "─"})
+}
+
+func TestPolylineModeKey(t *testing.T) {
+	m := newDrawModel("Polyline", 9, 7)
+	pressKeys(m, "enter", "F")
+	if m.polylineMode().name != "Polygon" || m.fillMode != fillOnly {
+		t.Errorf("mode is %s %s, want Polygon Filled", m.polylineMode().name, fillModeNames[m.fillMode])
+	}
+
+	click(m, 0, 0)
+	click(m, 2, 2)
+	m.setTool("Line")
+	if m.polyline != nil || m.canvas.Get(1, 1).char != "#" {
+		t.Error("switching tools should finish the polyline")
+	}
+}
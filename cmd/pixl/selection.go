@@ -0,0 +1,258 @@
+package main
+
+// cellMask is a set of canvas cells, keyed by row and column.
+type cellMask map[[2]int]bool
+
+// selectShape is how the Select tool picks cells.
+type selectShape int
+
+const (
+	selectRect selectShape = iota
+	selectLasso
+	selectWand
+)
+
+// wandMatch is what the magic wand compares to decide which neighbouring
+// cells belong with the clicked one.
+type wandMatch int
+
+const (
+	matchBoth wandMatch = iota
+	matchGlyph
+	matchColor
+)
+
+type selectMode struct {
+	name  string
+	shape selectShape
+	match wandMatch
+}
+
+// selectModes are the Select tool's modes, cycled with enter.
+var selectModes = []selectMode{
+	{"Select", selectRect, matchBoth},
+	{"Lasso", selectLasso, matchBoth},
+	{"Magic Wand", selectWand, matchBoth},
+	{"Wand by Glyph", selectWand, matchGlyph},
+	{"Wand by Color", selectWand, matchColor},
+}
+
+// selectionOp is how a new selection combines with the current one.
+type selectionOp int
+
+const (
+	selectReplace selectionOp = iota
+	selectUnion
+	selectSubtract
+	selectIntersect
+)
+
+// selectionOpFor picks the operation from the modifiers held when a
+// selection starts: shift adds to the selection, alt subtracts from it, and
+// ctrl or shift with alt keeps only the overlap.
+func selectionOpFor(shift, alt, ctrl bool) selectionOp {
+	switch {
+	case ctrl || shift && alt:
+		return selectIntersect
+	case shift:
+		return selectUnion
+	case alt:
+		return selectSubtract
+	}
+	return selectReplace
+}
+
+func (m *model) selectMode() selectMode {
+	if m.selectModeIndex >= 0 && m.selectModeIndex < len(selectModes) {
+		return selectModes[m.selectModeIndex]
+	}
+	return selectModes[0]
+}
+
+// isSelected reports whether a canvas cell is selected. A selection without
+// a mask selects everything inside its rectangle's border.
+func (m *model) isSelected(y, x int) bool {
+	if !m.selection.active {
+		return false
+	}
+	if m.selection.mask != nil {
+		return m.selection.mask[[2]int{y, x}]
+	}
+	minY, minX, maxY, maxX, ok := m.selectionBounds()
+	return ok && y >= minY && y <= maxY && x >= minX && x <= maxX
+}
+
+// selectionMask returns the selected cells on the canvas.
+func (m *model) selectionMask() cellMask {
+	mask := cellMask{}
+	minY, minX, maxY, maxX, ok := m.selectionBounds()
+	if !ok {
+		return mask
+	}
+	for y := minY; y <= maxY; y++ {
+		for x := minX; x <= maxX; x++ {
+			if m.isSelected(y, x) {
+				mask[[2]int{y, x}] = true
+			}
+		}
+	}
+	return mask
+}
+
+// maskBounds returns the rows and columns a mask spans. ok is false for an
+// empty mask.
+func maskBounds(mask cellMask) (minY, minX, maxY, maxX int, ok bool) {
+	for p := range mask {
+		if !ok {
+			minY, minX, maxY, maxX, ok = p[0], p[1], p[0], p[1], true
+		}
+		minY, maxY = min(minY, p[0]), max(maxY, p[0])
+		minX, maxX = min(minX, p[1]), max(maxX, p[1])
+	}
+	return minY, minX, maxY, maxX, ok
+}
+
+// setSelection selects the cells of a mask, or nothing if it is empty. The
+// selection rectangle's border goes around the mask, and a mask that fills
+// its rectangle becomes a plain rectangle selection.
+func (m *model) setSelection(mask cellMask) {
+	minY, minX, maxY, maxX, ok := maskBounds(mask)
+	if !ok {
+		m.selection = selectionState{}
+		return
+	}
+	m.selection = selectionState{
+		active: true,
+		startY: minY - 1,
+		startX: minX - 1,
+		endY:   maxY + 1,
+		endX:   maxX + 1,
+	}
+	if len(mask) != (maxY-minY+1)*(maxX-minX+1) {
+		m.selection.mask = mask
+	}
+}
+
+// combineSelection combines a new selection with the current one using the
+// operation chosen when it started.
+func (m *model) combineSelection(mask cellMask) {
+	if m.selectionOp == selectReplace || !m.selection.active {
+		if m.selectionOp == selectIntersect || m.selectionOp == selectSubtract {
+			mask = cellMask{}
+		}
+		m.setSelection(mask)
+		return
+	}
+	current := m.selectionMask()
+	switch m.selectionOp {
+	case selectUnion:
+		for p := range mask {
+			current[p] = true
+		}
+	case selectSubtract:
+		for p := range mask {
+			delete(current, p)
+		}
+	case selectIntersect:
+		for p := range current {
+			if !mask[p] {
+				delete(current, p)
+			}
+		}
+	}
+	m.setSelection(current)
+}
+
+// rectMask returns the cells inside a selection rectangle's border, clipped
+// to the canvas.
+func (m *model) rectMask(y1, x1, y2, x2 int) cellMask {
+	minY, minX, maxY, maxX := normalizeRect(y1, x1, y2, x2)
+	mask := cellMask{}
+	for y := max(minY+1, 0); y <= min(maxY-1, m.canvas.height-1); y++ {
+		for x := max(minX+1, 0); x <= min(maxX-1, m.canvas.width-1); x++ {
+			mask[[2]int{y, x}] = true
+		}
+	}
+	return mask
+}
+
+// wandMask returns the cells connected to the given one that match it, the
+// same region the Fill tool would fill, compared as the mode says.
+func (m *model) wandMask(y, x int, match wandMatch) cellMask {
+	target := m.canvas.Get(y, x)
+	if target == nil {
+		return cellMask{}
+	}
+	t := *target
+	same := func(c Cell) bool {
+		glyph := c.char == t.char
+		color := c.foregroundColor == t.foregroundColor && c.backgroundColor == t.backgroundColor
+		switch match {
+		case matchGlyph:
+			return glyph
+		case matchColor:
+			return color
+		}
+		return glyph && color && c.attrs == t.attrs
+	}
+	mask := cellMask{}
+	for _, p := range m.connectedCells(y, x, same) {
+		mask[p] = true
+	}
+	return mask
+}
+
+// lassoMask returns the cells on and inside the closed path through points.
+func (m *model) lassoMask(points [][2]int) cellMask {
+	mask := cellMask{}
+	if len(points) == 0 {
+		return mask
+	}
+	for i, p := range points {
+		q := points[(i+1)%len(points)]
+		for c := range getLinePoints(p[0], p[1], q[0], q[1]) {
+			if m.canvas.Get(c[0], c[1]) != nil {
+				mask[c] = true
+			}
+		}
+	}
+	for p := range scanlineFill(points) {
+		if m.canvas.Get(p[0], p[1]) != nil {
+			mask[p] = true
+		}
+	}
+	return mask
+}
+
+// addLassoPoint extends the lasso path to a cell, recording the cells along
+// the way for the preview.
+func (m *model) addLassoPoint(y, x int) {
+	if n := len(m.lassoPath); n > 0 {
+		last := m.lassoPath[n-1]
+		if last == [2]int{y, x} {
+			return
+		}
+		for p := range getLinePoints(last[0], last[1], y, x) {
+			m.previewPoints[p] = true
+		}
+	}
+	m.lassoPath = append(m.lassoPath, [2]int{y, x})
+	m.previewPoints[[2]int{y, x}] = true
+}
+
+// onSelectionEdge reports whether a cell outlines a mask selection: it isn't
+// selected but touches a selected cell. Like the rectangle's border, the
+// outline is visual only.
+func (m *model) onSelectionEdge(y, x int) bool {
+	if m.isSelected(y, x) {
+		return false
+	}
+	for dy := -1; dy <= 1; dy++ {
+		for dx := -1; dx <= 1; dx++ {
+			if m.isSelected(y+dy, x+dx) {
+				return true
+			}
+		}
+	}
+	return false
+}
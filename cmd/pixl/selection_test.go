@@ -0,0 +1,239 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// newWandModel returns a 5x3 canvas using the Select tool:
+//
+//	AAB
+//	A B
+//	AAAC
+//
+// where every glyph is red except for the B at (1,2), which is blue.
+func newWandModel() *model {
+	m := initialModel()
+	m.canvas = NewCanvas(5, 3)
+	m.width = 80
+	m.height = 20
+	m.config.Theme = defaultTheme()
+	m.selectedTool = "Select"
+	for _, p := range [][2]int{{0, 0}, {0, 1}, {1, 0}, {2, 0}, {2, 1}, {2, 2}} {
+		m.canvas.Set(p[0], p[1], "A", "red", "transparent")
+	}
+	m.canvas.Set(0, 2, "B", "red", "transparent")
+	m.canvas.Set(1, 2, "B", "blue", "transparent")
+	m.canvas.Set(2, 3, "C", "red", "transparent")
+	m.saveToHistory()
+	return m
+}
+
+func checkSelected(t *testing.T, m *model, want ...[2]int) {
+	t.Helper()
+	got := m.selectionMask()
+	if len(got) != len(want) {
+		t.Errorf("%d cells selected, want %d", len(got), len(want))
+	}
+	for _, p := range want {
+		if !got[p] {
+			t.Errorf("cell (%d,%d) should be selected", p[0], p[1])
+		}
+	}
+}
+
+var wandA = [][2]int{{0, 0}, {0, 1}, {1, 0}, {2, 0}, {2, 1}, {2, 2}}
+
+func TestMagicWandMatchModes(t *testing.T) {
+	tests := []struct {
+		mode string
+		y, x int
+		want [][2]int
+	}{
+		{"Magic Wand", 0, 0, wandA},
+		{"Wand by Glyph", 0, 2, [][2]int{{0, 2}, {1, 2}}},
+		{"Wand by Color", 0, 0, append([][2]int{{0, 2}, {2, 3}}, wandA...)},
+		{"Magic Wand", 1, 1, [][2]int{{1, 1}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			m := newWandModel()
+			m.runAction(actionID(tt.mode))
+			click(m, tt.y, tt.x)
+			checkSelected(t, m, tt.want...)
+		})
+	}
+}
+
+func TestLassoSelectsInside(t *testing.T) {
+	m := newWandModel()
+	m.canvas = NewCanvas(9, 9)
+	m.selectModeIndex = 1
+	m.beginStroke(0, 4)
+	for _, p := range [][2]int{{4, 8}, {8, 4}, {4, 0}} {
+		m.tool().OnDrag(m, p[0], p[1])
+	}
+	m.endStroke(0, 4)
+
+	mask := m.selectionMask()
+	for _, p := range [][2]int{{0, 4}, {4, 4}, {4, 0}, {4, 8}, {2, 3}, {6, 5}} {
+		if !mask[p] {
+			t.Errorf("cell (%d,%d) should be inside the lasso", p[0], p[1])
+		}
+	}
+	for _, p := range [][2]int{{0, 0}, {1, 1}, {8, 8}, {7, 2}} {
+		if mask[p] {
+			t.Errorf("cell (%d,%d) should be outside the lasso", p[0], p[1])
+		}
+	}
+	if m.selection.mask == nil {
+		t.Error("a diamond should be a mask selection")
+	}
+}
+
+func TestLassoClickDeselects(t *testing.T) {
+	m := newWandModel()
+	m.selection = selectionState{active: true, startY: -1, startX: -1, endY: 3, endX: 3}
+	m.selectModeIndex = 1
+	click(m, 1, 4)
+	if m.selection.active {
+		t.Error("a click with the lasso should clear the selection")
+	}
+}
+
+func TestSelectionModifiersCombine(t *testing.T) {
+	m := newWandModel()
+	m.selectModeIndex = 2
+	click(m, 0, 0)
+
+	// Shift adds the blue B
+	m.selectionOp = selectionOpFor(true, false, false)
+	click(m, 1, 2)
+	checkSelected(t, m, append([][2]int{{1, 2}}, wandA...)...)
+
+	// Alt takes a rectangle away
+	m.selectModeIndex = 0
+	m.selectionOp = selectionOpFor(false, true, false)
+	drag(m, -1, -1, 2, 5)
+	checkSelected(t, m, [2]int{2, 0}, [2]int{2, 1}, [2]int{2, 2})
+
+	// Ctrl keeps the overlap with the wand's red cells
+	m.selectModeIndex = 4
+	m.selectionOp = selectionOpFor(false, false, true)
+	click(m, 2, 3)
+	checkSelected(t, m, [2]int{2, 0}, [2]int{2, 1}, [2]int{2, 2})
+	if m.selectionOp != selectReplace {
+		t.Error("the operation should reset after the stroke")
+	}
+}
+
+func TestSelectionOpFor(t *testing.T) {
+	tests := []struct {
+		shift, alt, ctrl bool
+		want             selectionOp
+	}{
+		{false, false, false, selectReplace},
+		{true, false, false, selectUnion},
+		{false, true, false, selectSubtract},
+		{false, false, true, selectIntersect},
+		{true, true, false, selectIntersect},
+	}
+	for _, tt := range tests {
+		if got := selectionOpFor(tt.shift, tt.alt, tt.ctrl); got != tt.want {
+			t.Errorf("selectionOpFor(%v, %v, %v) = %d, want %d", tt.shift, tt.alt, tt.ctrl, got, tt.want)
+		}
+	}
+}
+
+func TestFullMaskBecomesRectangle(t *testing.T) {
+	m := newWandModel()
+	m.setSelection(cellMask{{0, 3}: true, {0, 4}: true, {1, 3}: true, {1, 4}: true})
+	if m.selection.mask != nil {
+		t.Error("a mask filling its rectangle should be a plain rectangle")
+	}
+	checkSelected(t, m, [2]int{0, 3}, [2]int{0, 4}, [2]int{1, 3}, [2]int{1, 4})
+
+	m.setSelection(cellMask{})
+	if m.selection.active {
+		t.Error("an empty mask should clear the selection")
+	}
+}
+
+func TestCopyCutPasteRespectMask(t *testing.T) {
+	m := newWandModel()
+	m.selectModeIndex = 2
+	click(m, 0, 0)
+	m.cutSelection()
+
+	for _, p := range wandA {
+		if got := m.canvas.Get(p[0], p[1]).char; got != " " {
+			t.Errorf("cut left %q at (%d,%d)", got, p[0], p[1])
+		}
+	}
+	if m.canvas.Get(0, 2).char != "B" || m.canvas.Get(1, 2).char != "B" {
+		t.Error("cut should leave unselected cells inside the mask's rectangle")
+	}
+
+	// Paste over a row of Zs: only the A cells cover them
+	for x := 0; x < 5; x++ {
+		m.canvas.Set(1, x, "Z", "white", "transparent")
+	}
+	m.selection.active = false
+	m.mouseX, m.mouseY = 1, controlBarHeight
+	m.paste()
+	pressKeys(m, "enter")
+	checkCells(t, m, 5, 3, map[[2]int]string{
+		{0, 1}: "A", {0, 2}: "A", {1, 1}: "A", {1, 2}: "Z", {1, 3}: "Z", {2, 1}: "A", {2, 3}: "A",
+	})
+	checkSelected(t, m, [2]int{0, 1}, [2]int{0, 2}, [2]int{1, 1}, [2]int{2, 1}, [2]int{2, 2}, [2]int{2, 3})
+}
+
+func TestMoveMaskSelection(t *testing.T) {
+	m := newWandModel()
+	m.selectModeIndex = 3
+	click(m, 0, 2)
+	pressKeys(m, "right", "right", "enter")
+
+	checkCells(t, m, 5, 3, map[[2]int]string{{0, 2}: " ", {1, 2}: " ", {0, 4}: "B", {1, 4}: "B", {2, 2}: "A"})
+	checkSelected(t, m, [2]int{0, 4}, [2]int{1, 4})
+}
+
+func TestFlipMaskSelection(t *testing.T) {
+	m := newWandModel()
+	m.setSelection(cellMask{{0, 0}: true, {1, 0}: true, {2, 0}: true, {2, 1}: true})
+	m.transformSelection(flipHorizontal)
+
+	// The L flips within its 2x3 rectangle, leaving the B beside it alone
+	checkCells(t, m, 5, 3, map[[2]int]string{{0, 0}: " ", {1, 0}: " ", {0, 1}: "A", {1, 1}: "A", {2, 0}: "A", {2, 1}: "A", {0, 2}: "B"})
+	checkSelected(t, m, [2]int{0, 1}, [2]int{1, 1}, [2]int{2, 0}, [2]int{2, 1})
+}
+
+func TestSelectModeCycles(t *testing.T) {
+	m := newWandModel()
+	for _, want := range []string{"Lasso", "Magic Wand", "Wand by Glyph", "Wand by Color", "Select"} {
+		pressKeys(m, "enter")
+		if got := m.tool().DisplayName(m); got != want {
+			t.Errorf("mode = %q, want %q", got, want)
+		}
+	}
+}
+
+func TestMaskSelectionOutline(t *testing.T) {
+	m := newWandModel()
+	m.setSelection(cellMask{{0, 0}: true, {1, 1}: true})
+	tests := []struct {
+		y, x int
+		want bool
+	}{
+		{0, 0, false}, {1, 1, false}, {0, 1, true}, {2, 2, true}, {0, 3, false},
+	}
+	for _, tt := range tests {
+		if got := m.onSelectionEdge(tt.y, tt.x); got != tt.want {
+			t.Errorf("onSelectionEdge(%d, %d) = %v, want %v", tt.y, tt.x, got, tt.want)
+		}
+	}
+	m.ready = true
+	if view := m.View(); !strings.Contains(view, "·") {
+		t.Error("the view should outline the mask")
+	}
+}
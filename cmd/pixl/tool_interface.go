@@ -22,6 +22,8 @@ var toolRegistry = []Tool{
 	BoxTool{},
 	EllipseTool{},
 	LineTool{},
+	PolylineTool{},
+	ConnectorTool{},
 	FillTool{},
 	SelectTool{},
 	TextTool{},
@@ -44,37 +46,51 @@ func (t PointTool) DisplayName(m *model) string { return "Points" }
 func (t PointTool) CursorChar(_ *model) string    { return "" }
 func (t PointTool) ModifiesCanvas() bool         { return true }
 
-func (t PointTool) OnPress(_ *model, _, _ int)              {}
 func (t PointTool) OnKeyPress(_ *model, _ string) bool      { return false }
 func (t PointTool) RenderPreview(_ *model, _, _ int) (string, bool) { return "", false }
 
+func (t PointTool) OnPress(m *model, y, x int) {
+	m.previewEndY, m.previewEndX = y, x
+}
+
 func (t PointTool) OnDrag(m *model, y, x int) {
+	// The mouse only reaches the first dot of each cell, so dots are joined
+	// to the last one to keep strokes unbroken.
+	if m.toolGrid() != cellGrid {
+		for p := range getLinePoints(m.previewEndY, m.previewEndX, y, x) {
+			m.plot(p[0], p[1])
+		}
+		m.previewEndY, m.previewEndX = y, x
+		return
+	}
 	if y >= 0 && y < m.canvas.height && x >= 0 && x < m.canvas.width {
-		m.canvas.Set(y, x, m.selectedChar, m.foregroundColor, m.backgroundColor)
+		m.canvas.SetCell(y, x, m.brushCell(m.selectedChar))
 	}
 }
 
 func (t PointTool) OnRelease(_ *model, _, _ int) {}
 
-// RectangleTool draws rectangle outlines.
+// RectangleTool draws rectangles, outlined, filled or both.
 type RectangleTool struct{}
 
 func (t RectangleTool) Name() string                { return "Rectangle" }
 func (t RectangleTool) DisplayName(_ *model) string { return "Rectangle" }
 func (t RectangleTool) CursorChar(_ *model) string    { return "" }
 func (t RectangleTool) ModifiesCanvas() bool         { return true }
-func (t RectangleTool) OnKeyPress(_ *model, _ string) bool { return false }
+func (t RectangleTool) OnKeyPress(m *model, key string) bool { return m.shapeKeyPress(key) }
 
 func (t RectangleTool) OnPress(m *model, y, x int) {
 	m.showPreview = true
 	m.previewEndX = x
 	m.previewEndY = y
+	m.previewFill = m.fillPoints(rectOutline(m.startY, m.startX, y, x))
 }
 
 func (t RectangleTool) OnDrag(m *model, y, x int) {
-	clampedY, clampedX := m.clampToCanvas(y, x)
+	clampedY, clampedX := m.clampToGrid(y, x)
 	m.previewEndX = clampedX
 	m.previewEndY = clampedY
+	m.previewFill = m.fillPoints(rectOutline(m.startY, m.startX, clampedY, clampedX))
 }
 
 func (t RectangleTool) OnRelease(m *model, y, x int) {
@@ -82,6 +98,9 @@ func (t RectangleTool) OnRelease(m *model, y, x int) {
 }
 
 func (t RectangleTool) RenderPreview(m *model, row, col int) (string, bool) {
+	if rendered, ok := m.fillPreview(row, col); ok || !m.drawsOutline() {
+		return rendered, ok
+	}
 	minY, minX, maxY, maxX := normalizeRect(m.startY, m.startX, m.previewEndY, m.previewEndX)
 	if row >= minY && row <= maxY && col >= minX && col <= maxX {
 		if row == minY || row == maxY || col == minX || col == maxX {
@@ -189,7 +208,7 @@ func (t BoxTool) ModifiesCanvas() bool        { return true }
 
 func (t BoxTool) OnKeyPress(m *model, key string) bool {
 	if key != "enter" {
-		return false
+		return m.shapeKeyPress(key)
 	}
 	m.boxStyle = (m.boxStyle + 1) % len(boxStyles)
 	return true
@@ -199,12 +218,14 @@ func (t BoxTool) OnPress(m *model, y, x int) {
 	m.showPreview = true
 	m.previewEndX = x
 	m.previewEndY = y
+	m.previewFill = m.fillPoints(rectOutline(m.startY, m.startX, y, x))
 }
 
 func (t BoxTool) OnDrag(m *model, y, x int) {
 	clampedY, clampedX := m.clampToCanvas(y, x)
 	m.previewEndX = clampedX
 	m.previewEndY = clampedY
+	m.previewFill = m.fillPoints(rectOutline(m.startY, m.startX, clampedY, clampedX))
 }
 
 func (t BoxTool) OnRelease(m *model, y, x int) {
@@ -212,6 +233,9 @@ func (t BoxTool) OnRelease(m *model, y, x int) {
 }
 
 func (t BoxTool) RenderPreview(m *model, row, col int) (string, bool) {
+	if rendered, ok := m.fillPreview(row, col); ok || !m.drawsOutline() {
+		return rendered, ok
+	}
 	minY, minX, maxY, maxX := normalizeRect(m.startY, m.startX, m.previewEndY, m.previewEndX)
 	if row < minY || row > maxY || col < minX || col > maxX {
 		return "", false
@@ -247,11 +271,7 @@ func (t BoxTool) RenderPreview(m *model, row, col int) (string, bool) {
 		}
 	}
 
-	style := colorStyleByName(m.foregroundColor)
-	if m.backgroundColor != "transparent" {
-		style = style.Background(colorStyleByName(m.backgroundColor).GetForeground())
-	}
-	return style.Render(ch), true
+	return m.brushStyle().Render(ch), true
 }
 
 // EllipseTool draws ellipses/circles.
@@ -279,6 +299,8 @@ var drawingToolOptions = []drawingToolOption{
 	{"Ellipse", "Ellipse", false},
 	{"Circle", "Ellipse", true},
 	{"Line", "Line", false},
+	{"Polyline", "Polyline", false},
+	{"Connector", "Connector", false},
 }
 
 func isDrawingTool(name string) bool {
@@ -311,7 +333,7 @@ func (t EllipseTool) OnKeyPress(m *model, key string) bool {
 		m.circleMode = !m.circleMode
 		return true
 	}
-	return false
+	return m.shapeKeyPress(key)
 }
 
 func (t EllipseTool) OnPress(m *model, y, x int) {
@@ -319,13 +341,15 @@ func (t EllipseTool) OnPress(m *model, y, x int) {
 	m.previewEndX = x
 	m.previewEndY = y
 	m.previewPoints = m.getCirclePoints(m.startY, m.startX, m.previewEndY, m.previewEndX, m.circleMode || m.optionKeyHeld)
+	m.previewFill = m.fillPoints(m.previewPoints)
 }
 
 func (t EllipseTool) OnDrag(m *model, y, x int) {
-	clampedY, clampedX := m.clampToCanvas(y, x)
+	clampedY, clampedX := m.clampToGrid(y, x)
 	m.previewEndX = clampedX
 	m.previewEndY = clampedY
 	m.previewPoints = m.getCirclePoints(m.startY, m.startX, m.previewEndY, m.previewEndX, m.circleMode || m.optionKeyHeld)
+	m.previewFill = m.fillPoints(m.previewPoints)
 }
 
 func (t EllipseTool) OnRelease(m *model, y, x int) {
@@ -333,6 +357,9 @@ func (t EllipseTool) OnRelease(m *model, y, x int) {
 }
 
 func (t EllipseTool) RenderPreview(m *model, row, col int) (string, bool) {
+	if rendered, ok := m.fillPreview(row, col); ok || !m.drawsOutline() {
+		return rendered, ok
+	}
 	if m.previewPoints[[2]int{row, col}] {
 		return m.styledChar(), true
 	}
@@ -356,7 +383,7 @@ func (t LineTool) OnPress(m *model, y, x int) {
 }
 
 func (t LineTool) OnDrag(m *model, y, x int) {
-	clampedY, clampedX := m.clampToCanvas(y, x)
+	clampedY, clampedX := m.clampToGrid(y, x)
 	m.previewEndX = clampedX
 	m.previewEndY = clampedY
 	m.previewPoints = getLinePoints(m.startY, m.startX, m.previewEndY, m.previewEndX)
@@ -373,6 +400,117 @@ func (t LineTool) RenderPreview(m *model, row, col int) (string, bool) {
 	return "", false
 }
 
+// PolylineTool draws lines through vertices placed one click at a time,
+// optionally closed into a polygon.
+type PolylineTool struct{}
+
+func (t PolylineTool) Name() string                { return "Polyline" }
+func (t PolylineTool) DisplayName(m *model) string { return m.polylineMode().name }
+func (t PolylineTool) CursorChar(_ *model) string  { return "" }
+func (t PolylineTool) ModifiesCanvas() bool        { return true }
+
+// OnKeyPress finishes the polyline in progress with enter, or cycles the
+// mode when there is none. Only polygons can be filled.
+func (t PolylineTool) OnKeyPress(m *model, key string) bool {
+	switch {
+	case key == "enter" && len(m.polyline) > 0:
+		m.commitPolyline()
+	case key == "enter":
+		m.polylineModeIndex = (m.polylineModeIndex + 1) % len(polylineModes)
+	case m.polylineMode().closed:
+		return m.shapeKeyPress(key)
+	default:
+		return false
+	}
+	return true
+}
+
+// OnPress starts a polyline at the first click. The vertex is added on
+// release, so dragging places it where the button is let go.
+func (t PolylineTool) OnPress(m *model, y, x int) {
+	if len(m.polyline) == 0 {
+		m.polyline = [][2]int{{y, x}}
+	}
+	m.movePolylineEnd(y, x)
+}
+
+func (t PolylineTool) OnDrag(m *model, y, x int) {
+	m.movePolylineEnd(y, x)
+}
+
+func (t PolylineTool) OnRelease(m *model, y, x int) {
+	if len(m.polyline) > 0 {
+		m.addPolylineVertex(y, x)
+	}
+}
+
+func (t PolylineTool) RenderPreview(m *model, row, col int) (string, bool) {
+	if rendered, ok := m.fillPreview(row, col); ok {
+		return rendered, ok
+	}
+	if g, ok := m.previewGlyphs[[2]int{row, col}]; ok {
+		return m.brushStyle().Render(g), true
+	}
+	return "", false
+}
+
+// ConnectorTool draws box-drawing lines between two cells along a
+// horizontal and a vertical segment, for joining boxes in diagrams.
+type ConnectorTool struct{}
+
+func (t ConnectorTool) Name() string { return "Connector" }
+
+// DisplayName shows the elbow the connector turns at, between the
+// arrowheads that are turned on.
+func (t ConnectorTool) DisplayName(m *model) string {
+	name := "Connector "
+	if m.arrowStart {
+		name += "◀"
+	}
+	name += m.connectorElbow()
+	if m.arrowEnd {
+		name += "▶"
+	}
+	return name
+}
+
+func (t ConnectorTool) CursorChar(_ *model) string { return "" }
+func (t ConnectorTool) ModifiesCanvas() bool       { return true }
+
+// OnKeyPress switches between turning after the horizontal segment and
+// after the vertical one.
+func (t ConnectorTool) OnKeyPress(m *model, key string) bool {
+	if key != "enter" {
+		return false
+	}
+	m.connectorVertical = !m.connectorVertical
+	if m.mouseDown {
+		t.OnDrag(m, m.previewEndY, m.previewEndX)
+	}
+	return true
+}
+
+func (t ConnectorTool) OnPress(m *model, y, x int) {
+	m.showPreview = true
+	t.OnDrag(m, y, x)
+}
+
+func (t ConnectorTool) OnDrag(m *model, y, x int) {
+	m.previewEndY, m.previewEndX = m.clampToCanvas(y, x)
+	m.previewGlyphs = m.connectorGlyphs(m.startY, m.startX, m.previewEndY, m.previewEndX)
+}
+
+func (t ConnectorTool) OnRelease(m *model, y, x int) {
+	m.drawConnector(m.startY, m.startX, y, x)
+}
+
+func (t ConnectorTool) RenderPreview(m *model, row, col int) (string, bool) {
+	if g, ok := m.previewGlyphs[[2]int{row, col}]; ok {
+		return m.brushStyle().Render(g), true
+	}
+	return "", false
+}
+
 // FillTool performs flood fill.
 type FillTool struct{}
 
@@ -389,29 +527,80 @@ func (t FillTool) OnRelease(m *model, y, x int) {
 	m.floodFill(y, x)
 }
 
-// SelectTool creates selection rectangles.
+// SelectTool selects rectangles, freeform lasso shapes or, as a magic
+// wand, regions of matching cells.
 type SelectTool struct{}
 
 func (t SelectTool) Name() string                { return "Select" }
-func (t SelectTool) DisplayName(_ *model) string { return "Select" }
+func (t SelectTool) DisplayName(m *model) string { return m.selectMode().name }
 func (t SelectTool) CursorChar(_ *model) string    { return "┼" }
 func (t SelectTool) ModifiesCanvas() bool         { return false }
-func (t SelectTool) OnKeyPress(_ *model, _ string) bool { return false }
 
+func (t SelectTool) OnKeyPress(m *model, key string) bool {
+	if key != "enter" {
+		return false
+	}
+	m.selectModeIndex = (m.selectModeIndex + 1) % len(selectModes)
+	return true
+}
+
+// OnPress inside the selection picks up its contents to move them;
+// anywhere else it starts a new selection, or adds to the current one
+// when a modifier is held.
 func (t SelectTool) OnPress(m *model, y, x int) {
-	m.showPreview = true
-	m.previewEndX = x
-	m.previewEndY = y
-	m.selection.active = false
+	if m.selectionOp == selectReplace && m.insideSelection(y, x) && m.liftSelection() {
+		m.floating.dragging = true
+		m.floating.grabY, m.floating.grabX = y-m.floating.y, x-m.floating.x
+		return
+	}
+	if m.selectionOp == selectReplace {
+		m.selection.active = false
+	}
+	switch m.selectMode().shape {
+	case selectLasso:
+		m.showPreview = true
+		m.previewPoints = map[[2]int]bool{}
+		m.lassoPath = nil
+		m.addLassoPoint(m.clampToCanvas(y, x))
+	case selectRect:
+		m.showPreview = true
+		m.previewEndX = x
+		m.previewEndY = y
+	}
 }
 
 func (t SelectTool) OnDrag(m *model, y, x int) {
+	if m.floating.dragging {
+		m.moveFloating(y-m.floating.grabY, x-m.floating.grabX)
+		return
+	}
 	clampedY, clampedX := m.clampToCanvas(y, x)
-	m.previewEndX = clampedX
-	m.previewEndY = clampedY
+	switch m.selectMode().shape {
+	case selectLasso:
+		m.addLassoPoint(clampedY, clampedX)
+	case selectRect:
+		m.previewEndX = clampedX
+		m.previewEndY = clampedY
+	}
 }
 
 func (t SelectTool) OnRelease(m *model, y, x int) {
+	if m.floating.dragging {
+		m.floating.dragging = false
+		return
+	}
+	switch m.selectMode().shape {
+	case selectLasso:
+		m.lassoPath = append(m.lassoPath, [2]int{y, x})
+		if len(m.lassoPath) > 2 {
+			m.combineSelection(m.lassoMask(m.lassoPath))
+		}
+		m.lassoPath = nil
+		return
+	case selectWand:
+		m.combineSelection(m.wandMask(m.startY, m.startX, m.selectMode().match))
+		return
+	}
 	dy := m.startY - y
 	dx := m.startX - x
 	if dy < 0 {
@@ -421,15 +610,27 @@ func (t SelectTool) OnRelease(m *model, y, x int) {
 		dx = -dx
 	}
 	if dy > 1 && dx > 1 {
-		m.selection.active = true
-		m.selection.startY = m.startY
-		m.selection.startX = m.startX
-		m.selection.endY = y
-		m.selection.endX = x
+		if m.selectionOp != selectReplace {
+			m.combineSelection(m.rectMask(m.startY, m.startX, y, x))
+			return
+		}
+		m.selection = selectionState{
+			active: true,
+			startY: m.startY,
+			startX: m.startX,
+			endY:   y,
+			endX:   x,
+		}
 	}
 }
 
 func (t SelectTool) RenderPreview(m *model, row, col int) (string, bool) {
+	if m.selectMode().shape == selectLasso {
+		if m.previewPoints[[2]int{row, col}] {
+			return lipgloss.NewStyle().Foreground(themeColor(m.config.Theme.CursorFg)).Render("·"), true
+		}
+		return "", false
+	}
 	minY, minX, maxY, maxX := normalizeRect(m.startY, m.startX, m.previewEndY, m.previewEndX)
 	hasWidth := minX != maxX
 	hasHeight := minY != maxY
@@ -160,7 +160,7 @@ func TestBoxToolRenderPreviewNoMergeWhenDisabled(t *testing.T) {
 }
 
 func TestToolRegistryOrderAndNames(t *testing.T) {
-	expected := []string{"Point", "Rectangle", "Box", "Ellipse", "Line", "Fill", "Select", "Text"}
+	expected := []string{"Point", "Rectangle", "Box", "Ellipse", "Line", "Polyline", "Connector", "Fill", "Select", "Text"}
 
 	if len(toolRegistry) != len(expected) {
 		t.Fatalf("toolRegistry has %d tools, want %d", len(toolRegistry), len(expected))
@@ -502,10 +502,22 @@ func TestDrawingToolsSubmenuNavigation(t *testing.T) {
 		t.Errorf("expected Line, got %q", m.selectedTool)
 	}
 
+	// Down to Polyline
+	m.handleKey(tea.KeyMsg{Type: tea.KeyDown})
+	if m.selectedTool != "Polyline" {
+		t.Errorf("expected Polyline, got %q", m.selectedTool)
+	}
+
+	// Down to Connector
+	m.handleKey(tea.KeyMsg{Type: tea.KeyDown})
+	if m.selectedTool != "Connector" {
+		t.Errorf("expected Connector, got %q", m.selectedTool)
+	}
+
 	// Down at bottom stays
 	m.handleKey(tea.KeyMsg{Type: tea.KeyDown})
-	if m.selectedTool != "Line" {
-		t.Errorf("down at bottom: expected Line, got %q", m.selectedTool)
+	if m.selectedTool != "Connector" {
+		t.Errorf("down at bottom: expected Connector, got %q", m.selectedTool)
 	}
 
 	// Left exits submenu
@@ -832,6 +844,8 @@ func TestDrawingToolOptions(t *testing.T) {
 		{"Ellipse", "Ellipse"},
 		{"Circle", "Ellipse"},
 		{"Line", "Line"},
+		{"Polyline", "Polyline"},
+		{"Connector", "Connector"},
 	}
 
 	if len(drawingToolOptions) != len(expected) {
@@ -849,7 +863,7 @@ func TestDrawingToolOptions(t *testing.T) {
 }
 
 func TestIsDrawingTool(t *testing.T) {
-	for _, name := range []string{"Point", "Rectangle", "Ellipse", "Line"} {
+	for _, name := range []string{"Point", "Rectangle", "Ellipse", "Line", "Polyline", "Connector"} {
 		if !isDrawingTool(name) {
 			t.Errorf("isDrawingTool(%q) = false, want true", name)
 		}
@@ -104,6 +104,9 @@ func (m *model) renderControlBar() string {
 	if m.selectedTool == "Box" {
 		toolName = boxStyles[m.boxStyle].h + " " + boxStyles[m.boxStyle].name + " " + toolName
 	}
+	if m.toolFills() && m.fillMode != fillNone {
+		toolName += " (" + fillModeNames[m.fillMode] + " " + m.fillChar + ")"
+	}
 	toolText := fmt.Sprintf("%sT%sool: %s", underlineOn, underlineOff, toolName)
 	var toolButton string
 	if m.showToolPicker {
@@ -114,6 +117,37 @@ func (m *model) renderControlBar() string {
 	m.toolbar.toolX = currentX + toolbarButtonPadding
 	m.toolbar.toolItemX = currentX + 7
 	currentX += lipgloss.Width(toolButton)
+	currentX += 1 // separator
+
+	// Layer button
+	m.ensureLayers()
+	layerText := fmt.Sprintf("%sL%sayer: %s", underlineOn, underlineOff, m.layers[m.activeLayer].name)
+	var layerButton string
+	if m.showLayerPicker {
+		layerButton = highlightStyle.Render(layerText)
+	} else {
+		layerButton = baseStyle.Render(layerText)
+	}
+	m.toolbar.layerX = currentX + toolbarButtonPadding
+	m.toolbar.layerItemX = currentX + 8
+	currentX += lipgloss.Width(layerButton)
+	currentX += 1 // separator
+
+	// Attribute toggles, one letter per attribute in its own style
+	attrButton := baseStyle.Copy().Padding(0).Render(" ")
+	for i, a := range textAttributes {
+		style := baseStyle.Copy().Padding(0)
+		if m.textAttrs&a.attr != 0 {
+			style = highlightStyle.Copy().Padding(0)
+		}
+		attrButton += style.Inherit(cellStyle("", "transparent", a.attr)).Render(a.letter)
+		if i < len(textAttributes)-1 {
+			attrButton += baseStyle.Copy().Padding(0).Render(" ")
+		}
+	}
+	attrButton += baseStyle.Copy().Padding(0).Render(" ")
+	m.toolbar.attrX = currentX + toolbarButtonPadding
+	currentX += lipgloss.Width(attrButton)
 
 	// Mode indicator
 	modeIndicator := ""
@@ -121,12 +155,21 @@ func (m *model) renderControlBar() string {
 		modeText := fmt.Sprintf("Mode: Yank (%dx%d)", m.clipboard.width, m.clipboard.height)
 		modeIndicator = baseStyle.Render(modeText)
 	}
+	if m.grid != cellGrid {
+		modeIndicator += baseStyle.Render(drawGrids[m.grid].name)
+	}
+	if m.keyboardCursor {
+		modeIndicator += baseStyle.Render(fmt.Sprintf("Cursor: %d,%d", m.hoverCol, m.hoverRow))
+	}
 
-	barContent := fgButton + sep + bgButton + sep + glyphButton + sep + toolButton + sep + modeIndicator
+	barContent := fgButton + sep + bgButton + sep + glyphButton + sep + toolButton + sep + layerButton + sep + attrButton + sep + modeIndicator
 
 	fileIndicator := ""
 	if m.filePath != "" {
 		displayName := shortPath(m.filePath)
+		if m.isModified() {
+			displayName += " [+]"
+		}
 		contentWidth := lipgloss.Width(barContent)
 		nameWidth := lipgloss.Width(displayName)
 		gap := m.width - contentWidth - nameWidth - 1
@@ -146,6 +189,18 @@ func (m *model) renderControlBar() string {
 	return barStyle.Render(barContent + fileIndicator) + "\n"
 }
 
+// toolbarAttrAt returns the attribute whose toolbar letter is at column x.
+func (m *model) toolbarAttrAt(x int) (cellAttrs, bool) {
+	if m.toolbar.attrX <= 0 {
+		return 0, false
+	}
+	offset := x - m.toolbar.attrX
+	if offset < 0 || offset%2 != 0 || offset/2 >= len(textAttributes) {
+		return 0, false
+	}
+	return textAttributes[offset/2].attr, true
+}
+
 func shortPath(path string) string {
 	dir, file := filepath.Split(path)
 	dir = filepath.Clean(dir)
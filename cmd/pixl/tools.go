@@ -1,6 +1,9 @@
 package main
 
-import "math"
+import (
+	"math"
+	"sort"
+)
 
 func normalizeRect(y1, x1, y2, x2 int) (minY, minX, maxY, maxX int) {
 	minY, maxY = y1, y2
@@ -14,19 +17,64 @@ func normalizeRect(y1, x1, y2, x2 int) (minY, minX, maxY, maxX int) {
 	return
 }
 
-func (m *model) drawRectangle(y1, x1, y2, x2 int) {
-	minY, minX, maxY, maxX := normalizeRect(y1, x1, y2, x2)
+// selectionBounds returns the canvas cells inside the selection, or the
+// rectangle around its mask. The selection border is visual only, so the
+// cells on it are not included. ok is false if there is no selection or
+// nothing is inside it.
+func (m *model) selectionBounds() (minY, minX, maxY, maxX int, ok bool) {
+	if !m.selection.active {
+		return 0, 0, 0, 0, false
+	}
+	if m.selection.mask != nil {
+		minY, minX, maxY, maxX, ok = maskBounds(m.selection.mask)
+	} else {
+		minY, minX, maxY, maxX = normalizeRect(m.selection.startY, m.selection.startX, m.selection.endY, m.selection.endX)
+		minY, minX, maxY, maxX, ok = minY+1, minX+1, maxY-1, maxX-1, true
+	}
+	minY, minX = max(minY, 0), max(minX, 0)
+	maxY, maxX = min(maxY, m.canvas.height-1), min(maxX, m.canvas.width-1)
+	return minY, minX, maxY, maxX, ok && maxY >= minY && maxX >= minX
+}
+
+// plot draws the brush at a point on the current tool's grid: the selected
+// glyph on a cell, or a pixel or braille dot in the foreground color.
+func (m *model) plot(y, x int) {
+	switch m.toolGrid() {
+	case pixelGrid:
+		m.setPixel(y, x, m.foregroundColor)
+	case brailleGrid:
+		m.setDot(y, x, m.foregroundColor)
+	default:
+		m.canvas.SetCell(y, x, m.brushCell(m.selectedChar))
+	}
+}
 
+// rectOutline returns the points on a rectangle's border.
+func rectOutline(y1, x1, y2, x2 int) map[[2]int]bool {
+	minY, minX, maxY, maxX := normalizeRect(y1, x1, y2, x2)
+	points := make(map[[2]int]bool)
 	for y := minY; y <= maxY; y++ {
 		for x := minX; x <= maxX; x++ {
 			if y == minY || y == maxY || x == minX || x == maxX {
-				m.canvas.Set(y, x, m.selectedChar, m.foregroundColor, m.backgroundColor)
+				points[[2]int{y, x}] = true
 			}
 		}
 	}
+	return points
+}
+
+func (m *model) drawRectangle(y1, x1, y2, x2 int) {
+	m.drawShape(rectOutline(y1, x1, y2, x2))
 }
 
 func (m *model) drawBox(y1, x1, y2, x2 int) {
+	for p := range m.fillPoints(rectOutline(y1, x1, y2, x2)) {
+		m.plotFill(p[0], p[1])
+	}
+	if !m.drawsOutline() {
+		return
+	}
+
 	minY, minX, maxY, maxX := normalizeRect(y1, x1, y2, x2)
 	s := boxStyles[m.boxStyle]
 
@@ -58,7 +106,7 @@ func (m *model) drawBox(y1, x1, y2, x2 int) {
 					}
 				}
 			}
-			m.canvas.Set(y, x, ch, m.foregroundColor, m.backgroundColor)
+			m.canvas.SetCell(y, x, m.brushCell(ch))
 		}
 	}
 }
@@ -81,8 +129,13 @@ func (m *model) getCirclePoints(y1, x1, y2, x2 int, forceCircle bool) map[[2]int
 			return points
 		}
 
+		// Cells are about twice as tall as they are wide, but pixels and
+		// braille dots are square
 		rx := radius
 		ry := radius / 2
+		if m.toolGrid() != cellGrid {
+			ry = radius
+		}
 		if ry == 0 {
 			ry = 1
 		}
@@ -113,40 +166,58 @@ func (m *model) getCirclePoints(y1, x1, y2, x2 int, forceCircle bool) map[[2]int
 }
 
 func (m *model) drawCircle(y1, x1, y2, x2 int, forceCircle bool) {
-	points := m.getCirclePoints(y1, x1, y2, x2, forceCircle)
-	for point := range points {
-		m.canvas.Set(point[0], point[1], m.selectedChar, m.foregroundColor, m.backgroundColor)
-	}
+	m.drawShape(m.getCirclePoints(y1, x1, y2, x2, forceCircle))
 }
 
 func (m *model) floodFill(row, col int) {
+	if m.toolGrid() == pixelGrid {
+		m.fillPixels(row, col)
+		return
+	}
 	target := m.canvas.Get(row, col)
 	if target == nil {
 		return
 	}
 
-	targetChar := target.char
-	targetFg := target.foregroundColor
-	targetBg := target.backgroundColor
-
-	if targetChar == m.selectedChar && targetFg == m.foregroundColor && targetBg == m.backgroundColor {
+	targetCell := *target
+	fill := m.brushCell(m.selectedChar)
+	if targetCell == fill {
 		return
 	}
 
+	for _, p := range m.connectedCells(row, col, func(c Cell) bool { return c == targetCell }) {
+		m.canvas.SetCell(p[0], p[1], fill)
+	}
+}
+
+// connectedCells returns the cells reachable from row, col through
+// horizontally or vertically adjacent cells that match, starting with the
+// cell itself. Nothing is returned if the starting cell doesn't match.
+func (m *model) connectedCells(row, col int, match func(Cell) bool) [][2]int {
+	return connectedPoints(row, col, func(y, x int) bool {
+		cell := m.canvas.Get(y, x)
+		return cell != nil && match(*cell)
+	})
+}
+
+// connectedPoints returns the points reachable from row, col through
+// horizontally or vertically adjacent points that match, starting with the
+// point itself. match must be false off the grid.
+func connectedPoints(row, col int, match func(y, x int) bool) [][2]int {
 	type point struct{ r, c int }
 	queue := []point{{row, col}}
 	visited := make(map[point]bool)
 	visited[point{row, col}] = true
 
+	var points [][2]int
 	for qi := 0; qi < len(queue); qi++ {
 		p := queue[qi]
 
-		cell := m.canvas.Get(p.r, p.c)
-		if cell == nil || cell.char != targetChar || cell.foregroundColor != targetFg || cell.backgroundColor != targetBg {
+		if !match(p.r, p.c) {
 			continue
 		}
 
-		m.canvas.Set(p.r, p.c, m.selectedChar, m.foregroundColor, m.backgroundColor)
+		points = append(points, [2]int{p.r, p.c})
 
 		for _, d := range [][2]int{{-1, 0}, {1, 0}, {0, -1}, {0, 1}} {
 			np := point{p.r + d[0], p.c + d[1]}
@@ -156,6 +227,7 @@ func (m *model) floodFill(row, col int) {
 			}
 		}
 	}
+	return points
 }
 
 func getLinePoints(y1, x1, y2, x2 int) map[[2]int]bool {
@@ -201,7 +273,7 @@ func getLinePoints(y1, x1, y2, x2 int) map[[2]int]bool {
 
 func (m *model) drawLine(y1, x1, y2, x2 int) {
 	for pt := range getLinePoints(y1, x1, y2, x2) {
-		m.canvas.Set(pt[0], pt[1], m.selectedChar, m.foregroundColor, m.backgroundColor)
+		m.plot(pt[0], pt[1])
 	}
 }
 
@@ -258,3 +330,111 @@ func addEllipsePoints(points map[[2]int]bool, centerY, centerX, x, y int) {
 	points[[2]int{centerY - y, centerX + x}] = true
 	points[[2]int{centerY - y, centerX - x}] = true
 }
+
+// scanlineFill returns the points inside the polygon with the given
+// vertices, using the even-odd rule. Each row is filled between pairs of
+// the places where the polygon's edges cross it.
+func scanlineFill(points [][2]int) map[[2]int]bool {
+	inside := make(map[[2]int]bool)
+	if len(points) == 0 {
+		return inside
+	}
+	minY, maxY := points[0][0], points[0][0]
+	for _, p := range points {
+		minY, maxY = min(minY, p[0]), max(maxY, p[0])
+	}
+	for y := minY; y <= maxY; y++ {
+		var crossings []float64
+		for i, j := 0, len(points)-1; i < len(points); j, i = i, i+1 {
+			yi, xi := float64(points[i][0]), float64(points[i][1])
+			yj, xj := float64(points[j][0]), float64(points[j][1])
+			if (yi > float64(y)) != (yj > float64(y)) {
+				crossings = append(crossings, (xj-xi)*(float64(y)-yi)/(yj-yi)+xi)
+			}
+		}
+		sort.Float64s(crossings)
+		for k := 0; k+1 < len(crossings); k += 2 {
+			for x := int(math.Ceil(crossings[k])); float64(x) < crossings[k+1]; x++ {
+				inside[[2]int{y, x}] = true
+			}
+		}
+	}
+	return inside
+}
+
+// elbowPath returns the cells from one point to another along a horizontal
+// and a vertical segment, turning once. horizontalFirst picks which comes
+// first.
+func elbowPath(from, to [2]int, horizontalFirst bool) [][2]int {
+	path := [][2]int{from}
+	p := from
+	walk := func(axis int) {
+		for p[axis] != to[axis] {
+			if p[axis] < to[axis] {
+				p[axis]++
+			} else {
+				p[axis]--
+			}
+			path = append(path, p)
+		}
+	}
+	if horizontalFirst {
+		walk(1)
+		walk(0)
+	} else {
+		walk(0)
+		walk(1)
+	}
+	return path
+}
+
+// boxPathGlyphs returns the box-drawing glyph for each cell of a path of
+// adjacent cells, with arms joining it to the cells before and after it.
+// Where box borders are merged, the path also joins the box lines already
+// on the canvas with tees and crosses.
+func (m *model) boxPathGlyphs(path [][2]int) map[[2]int]string {
+	s := boxStyles[m.boxStyle]
+	arms := make(map[[2]int][4]bool)
+	for i, p := range path {
+		a := arms[p]
+		for _, j := range []int{i - 1, i + 1} {
+			if j < 0 || j >= len(path) {
+				continue
+			}
+			switch [2]int{path[j][0] - p[0], path[j][1] - p[1]} {
+			case [2]int{-1, 0}:
+				a[0] = true
+			case [2]int{1, 0}:
+				a[1] = true
+			case [2]int{0, -1}:
+				a[2] = true
+			case [2]int{0, 1}:
+				a[3] = true
+			}
+		}
+		arms[p] = a
+	}
+
+	glyphs := make(map[[2]int]string, len(arms))
+	for p, a := range arms {
+		up, down, left, right := a[0], a[1], a[2], a[3]
+		if m.config.MergeBoxBorders && s.cross != "" {
+			if existing := m.canvas.Get(p[0], p[1]); existing != nil {
+				if eu, ed, el, er, ok := s.dirs(existing.char); ok {
+					up, down, left, right = up || eu, down || ed, left || el, right || er
+				}
+			}
+		}
+		// Ends of the path have a single arm, which no glyph draws, and
+		// dashed styles have no junctions; both use a straight edge
+		g := s.fromDirs(up, down, left, right)
+		if g == "" {
+			g = s.h
+			if (up || down) && !left && !right {
+				g = s.v
+			}
+		}
+		glyphs[p] = g
+	}
+	return glyphs
+}
@@ -0,0 +1,325 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// maxReportedGlyphs limits the glyphs listed after a rotation.
+const maxReportedGlyphs = 20
+
+// transform is a flip or rotation of the selection or the whole canvas.
+type transform int
+
+const (
+	flipHorizontal transform = iota
+	flipVertical
+	rotateClockwise
+	rotateCounterclockwise
+	rotate180
+)
+
+var transformLabels = []string{
+	flipHorizontal:         "Flip Horizontal",
+	flipVertical:           "Flip Vertical",
+	rotateClockwise:        "Rotate Clockwise",
+	rotateCounterclockwise: "Rotate Counterclockwise",
+	rotate180:              "Rotate 180",
+}
+
+// quarterTurn reports whether the transform swaps rows and columns.
+func (t transform) quarterTurn() bool {
+	return t == rotateClockwise || t == rotateCounterclockwise
+}
+
+// compass transforms a compass direction, numbered clockwise from 0 for
+// north to 7 for north-west.
+func (t transform) compass(d int) int {
+	switch t {
+	case flipHorizontal:
+		return (8 - d) % 8
+	case flipVertical:
+		return (12 - d) % 8
+	case rotateClockwise:
+		return (d + 2) % 8
+	case rotateCounterclockwise:
+		return (d + 6) % 8
+	}
+	return (d + 4) % 8
+}
+
+// arms transforms the arms of a box-drawing glyph.
+func (t transform) arms(up, down, left, right bool) (bool, bool, bool, bool) {
+	switch t {
+	case flipHorizontal:
+		return up, down, right, left
+	case flipVertical:
+		return down, up, left, right
+	case rotateClockwise:
+		return left, right, down, up
+	case rotateCounterclockwise:
+		return right, left, up, down
+	}
+	return down, up, right, left
+}
+
+// position returns where the cell at row, col of a width x height region
+// ends up.
+func (t transform) position(row, col, width, height int) (int, int) {
+	switch t {
+	case flipHorizontal:
+		return row, width - 1 - col
+	case flipVertical:
+		return height - 1 - row, col
+	case rotateClockwise:
+		return col, height - 1 - row
+	case rotateCounterclockwise:
+		return width - 1 - col, row
+	}
+	return height - 1 - row, width - 1 - col
+}
+
+// compassGlyphs lists glyphs that point somewhere, indexed by compass
+// direction from north clockwise. Glyphs along a line, like ╱, appear at
+// both of its ends.
+var compassGlyphs = [][8]string{
+	{"↑", "↗", "→", "↘", "↓", "↙", "←", "↖"},
+	{"⬆", "", "➡", "", "⬇", "", "⬅", ""},
+	{"▲", "", "▶", "", "▼", "", "◀", ""},
+	{"△", "", "▷", "", "▽", "", "◁", ""},
+	{"", "◥", "", "◢", "", "◣", "", "◤"},
+	{"◓", "", "◑", "", "◒", "", "◐", ""},
+	{"", "", "◗", "", "", "", "◖", ""},
+	{"", "◔", "", "", "", "", "", ""},
+	{"", "◝", "", "◞", "", "◟", "", "◜"},
+	{"", "╱", "", "╲", "", "╱", "", "╲"},
+	{"▮", "", "▬", "", "▮", "", "▬", ""},
+	{"|", "/", "-", "\\", "|", "/", "-", "\\"},
+	{"^", "", ">", "", "v", "", "<", ""},
+	{"", "", ")", "", "", "", "(", ""},
+	{"", "", "]", "", "", "", "[", ""},
+	{"", "", "}", "", "", "", "{", ""},
+}
+
+// quadrantGlyphs maps block glyphs to the quadrants they fill: bit 0 is the
+// top-left, then top-right, bottom-left and bottom-right.
+var quadrantGlyphs = map[string]int{
+	"▘": 1, "▝": 2, "▀": 3, "▖": 4, "▌": 5, "▞": 6, "▛": 7,
+	"▗": 8, "▚": 9, "▐": 10, "▜": 11, "▄": 12, "▙": 13, "▟": 14,
+}
+
+// quadrantCompass is the compass direction of each quadrant bit.
+var quadrantCompass = []int{7, 1, 5, 3}
+
+// symmetricGlyphs look the same after any flip or rotation.
+const symmetricGlyphs = " ○◌◍◎●■□▪▫◆◇◈⬥⬦★☆✦✧✪✫✬✭✮✯✰█▓▒░•∙․⋅╳+*#.oO0x"
+
+// glyphTransforms maps each glyph to its counterpart under each transform.
+// Glyphs without a counterpart are left out.
+var glyphTransforms = buildGlyphTransforms()
+
+func buildGlyphTransforms() []map[string]string {
+	maps := make([]map[string]string, len(transformLabels))
+	for i := range maps {
+		t := transform(i)
+		m := map[string]string{}
+		for _, g := range symmetricGlyphs {
+			m[string(g)] = string(g)
+		}
+		for _, family := range compassGlyphs {
+			for d, g := range family {
+				if to := family[t.compass(d)]; g != "" && to != "" {
+					m[g] = to
+				}
+			}
+		}
+		for g, mask := range quadrantGlyphs {
+			to := 0
+			for bit, d := range quadrantCompass {
+				if mask&(1<<bit) != 0 {
+					to |= 1 << indexOf(quadrantCompass, t.compass(d))
+				}
+			}
+			for h, hm := range quadrantGlyphs {
+				if hm == to {
+					m[g] = h
+				}
+			}
+		}
+		for _, s := range boxStyles {
+			for _, g := range s.glyphs() {
+				if _, ok := m[g]; ok {
+					continue
+				}
+				up, down, left, right, _ := s.dirs(g)
+				if to := s.fromDirs(t.arms(up, down, left, right)); to != "" {
+					m[g] = to
+				}
+			}
+		}
+		maps[i] = m
+	}
+	return maps
+}
+
+func indexOf(s []int, v int) int {
+	for i, x := range s {
+		if x == v {
+			return i
+		}
+	}
+	return -1
+}
+
+// glyphs returns the style's glyphs that are defined.
+func (s *boxStyle) glyphs() []string {
+	var glyphs []string
+	for _, g := range []string{s.h, s.v, s.tl, s.tr, s.bl, s.br, s.teeRight, s.teeLeft, s.teeDown, s.teeUp, s.cross} {
+		if g != "" {
+			glyphs = append(glyphs, g)
+		}
+	}
+	return glyphs
+}
+
+// glyph returns how a glyph looks after the transform. ok is false
+// for glyphs with no known counterpart, which are returned unchanged.
+func (t transform) glyph(g string) (string, bool) {
+	if to, ok := glyphTransforms[t][g]; ok {
+		return to, true
+	}
+	return g, false
+}
+
+// transformCells returns the cells transformed, with their glyphs remapped.
+// Glyphs that can't be remapped are kept as they are and listed once each.
+func transformCells(cells [][]Cell, t transform) ([][]Cell, []string) {
+	height := len(cells)
+	width := 0
+	if height > 0 {
+		width = len(cells[0])
+	}
+	outWidth, outHeight := width, height
+	if t.quarterTurn() {
+		outWidth, outHeight = height, width
+	}
+	out := make([][]Cell, outHeight)
+	for i := range out {
+		out[i] = make([]Cell, outWidth)
+	}
+
+	var unmapped []string
+	seen := map[string]bool{}
+	for row := range cells {
+		for col, cell := range cells[row] {
+			g, ok := t.glyph(cell.char)
+			if !ok && !seen[cell.char] {
+				seen[cell.char] = true
+				unmapped = append(unmapped, cell.char)
+			}
+			cell.char = g
+			r, c := t.position(row, col, width, height)
+			out[r][c] = cell
+		}
+	}
+	return out, unmapped
+}
+
+// transformSelection flips or rotates the inside of the selection on the
+// active layer, or every layer of the whole canvas if nothing is selected.
+// A rotated selection keeps its top-left corner. Rotating by 90 degrees
+// reports the glyphs that have no rotated form.
+func (m *model) transformSelection(t transform) {
+	var unmapped []string
+	if m.selection.active {
+		var ok bool
+		if unmapped, ok = m.transformSelected(t); !ok {
+			return
+		}
+	} else {
+		unmapped = m.transformCanvas(t)
+	}
+	m.saveToHistoryAs(transformLabels[t])
+	if t.quarterTurn() && len(unmapped) > 0 {
+		if len(unmapped) > maxReportedGlyphs {
+			unmapped = append(unmapped[:maxReportedGlyphs], "…")
+		}
+		m.alertMessage = "No rotated form for: " + strings.Join(unmapped, " ")
+	}
+}
+
+func (m *model) transformSelected(t transform) ([]string, bool) {
+	minY, minX, maxY, maxX, ok := m.selectionBounds()
+	if !ok || !m.checkLayerEditable() {
+		return nil, false
+	}
+	height, width := maxY-minY+1, maxX-minX+1
+	if t.quarterTurn() && (minY+width > m.canvas.height || minX+height > m.canvas.width) {
+		m.alertMessage = fmt.Sprintf("The rotated selection (%dx%d) doesn't fit on the canvas", height, width)
+		return nil, false
+	}
+
+	cells := make([][]Cell, height)
+	blank := layerBlank(m.activeLayer)
+	for row := range cells {
+		cells[row] = make([]Cell, width)
+		for col := range cells[row] {
+			if !m.isSelected(minY+row, minX+col) {
+				cells[row][col] = transparentCell
+				continue
+			}
+			cells[row][col] = m.canvas.cells[minY+row][minX+col]
+			m.canvas.cells[minY+row][minX+col] = blank
+		}
+	}
+	out, unmapped := transformCells(cells, t)
+
+	// Only the selected cells move, so a mask is transformed with them
+	var mask cellMask
+	if m.selection.mask != nil {
+		mask = cellMask{}
+		for p := range m.selectionMask() {
+			r, c := t.position(p[0]-minY, p[1]-minX, width, height)
+			mask[[2]int{minY + r, minX + c}] = true
+		}
+	}
+	for row := range out {
+		for col, cell := range out[row] {
+			if mask == nil || mask[[2]int{minY + row, minX + col}] {
+				m.canvas.SetCell(minY+row, minX+col, cell)
+			}
+		}
+	}
+	if mask != nil {
+		m.setSelection(mask)
+	} else {
+		m.selection.startY, m.selection.startX = minY-1, minX-1
+		m.selection.endY, m.selection.endX = minY+len(out), minX+len(out[0])
+	}
+	return unmapped, true
+}
+
+// transformCanvas transforms every layer. Rotating by 90 degrees swaps the
+// canvas width and height, which then stay fixed.
+func (m *model) transformCanvas(t transform) []string {
+	m.syncActiveLayer()
+	var unmapped []string
+	seen := map[string]bool{}
+	for i := range m.layers {
+		out, missing := transformCells(m.layers[i].canvas.cells, t)
+		m.layers[i].canvas = Canvas{width: len(out[0]), height: len(out), cells: out}
+		for _, g := range missing {
+			if !seen[g] {
+				seen[g] = true
+				unmapped = append(unmapped, g)
+			}
+		}
+	}
+	m.canvas = m.layers[m.activeLayer].canvas
+	if t.quarterTurn() {
+		m.fixedWidth, m.fixedHeight = m.canvas.width, m.canvas.height
+		m.canvasInitialized = true
+	}
+	m.textInsertActive = false
+	return unmapped
+}
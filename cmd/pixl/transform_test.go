@@ -0,0 +1,138 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTransformGlyph(t *testing.T) {
+	tests := []struct {
+		t        transform
+		from, to string
+	}{
+		{flipHorizontal, "┌", "┐"},
+		{flipHorizontal, "◀", "▶"},
+		{flipHorizontal, "╱", "╲"},
+		{flipHorizontal, "▛", "▜"},
+		{flipHorizontal, "╭", "╮"},
+		{flipHorizontal, "↗", "↖"},
+		{flipHorizontal, "├", "┤"},
+		{flipHorizontal, "▲", "▲"},
+		{flipVertical, "┌", "└"},
+		{flipVertical, "▲", "▼"},
+		{flipVertical, "▛", "▙"},
+		{flipVertical, "◢", "◥"},
+		{flipVertical, "╬", "╬"},
+		{rotateClockwise, "─", "│"},
+		{rotateClockwise, "┏", "┓"},
+		{rotateClockwise, "▀", "▐"},
+		{rotateClockwise, "▖", "▘"},
+		{rotateClockwise, "◐", "◓"},
+		{rotateClockwise, "→", "↓"},
+		{rotateClockwise, "╱", "╲"},
+		{rotateClockwise, "┬", "┤"},
+		{rotateCounterclockwise, "→", "↑"},
+		{rotateCounterclockwise, "◜", "◟"},
+		{rotate180, "◤", "◢"},
+		{rotate180, "╔", "╝"},
+	}
+	for _, tt := range tests {
+		if got, ok := tt.t.glyph(tt.from); got != tt.to || !ok {
+			t.Errorf("%s of %s = %s (%v), want %s", transformLabels[tt.t], tt.from, got, ok, tt.to)
+		}
+	}
+	if got, ok := rotateClockwise.glyph("A"); ok || got != "A" {
+		t.Errorf("letters have no rotated form, got %q %v", got, ok)
+	}
+}
+
+func TestTransformGlyphsRoundTrip(t *testing.T) {
+	for _, group := range characterGroups {
+		for _, g := range group.chars {
+			for _, tr := range []transform{flipHorizontal, flipVertical, rotate180} {
+				if once, ok := tr.glyph(g); ok {
+					if twice, _ := tr.glyph(once); twice != g {
+						t.Errorf("%s twice turns %s into %s", transformLabels[tr], g, twice)
+					}
+				}
+			}
+			if cw, ok := rotateClockwise.glyph(g); ok {
+				if back, _ := rotateCounterclockwise.glyph(cw); back != g {
+					t.Errorf("rotating %s back and forth gives %s", g, back)
+				}
+			}
+		}
+	}
+}
+
+func newTransformModel() *model {
+	m := newLayerModel()
+	m.canvas = NewCanvas(6, 4)
+	m.canvas.Set(0, 0, "┌", "red", "transparent")
+	m.canvas.Set(0, 1, "→", "white", "transparent")
+	m.canvas.Set(1, 0, "A", "white", "transparent")
+	m.saveToHistory()
+	return m
+}
+
+func TestFlipSelection(t *testing.T) {
+	m := newTransformModel()
+	m.selection = selectionState{active: true, startY: -1, startX: -1, endY: 2, endX: 2}
+	before := len(m.history)
+	m.transformSelection(flipHorizontal)
+
+	checkCells(t, m, 6, 4, map[[2]int]string{{0, 0}: "←", {0, 1}: "┐", {1, 1}: "A", {1, 0}: " "})
+	if m.canvas.Get(0, 1).foregroundColor != "red" {
+		t.Error("cells should keep their colors")
+	}
+	if len(m.history) != before+1 || m.alertMessage != "" {
+		t.Errorf("flip should be one undo step without a report, alert %q", m.alertMessage)
+	}
+}
+
+func TestRotateSelection(t *testing.T) {
+	m := newTransformModel()
+	m.selection = selectionState{active: true, startY: -1, startX: -1, endY: 2, endX: 3}
+	m.transformSelection(rotateClockwise)
+
+	// The 3x2 selection becomes 2x3, keeping its top-left corner
+	checkCells(t, m, 6, 4, map[[2]int]string{
+		{0, 0}: "A", {0, 1}: "┐", {1, 1}: "↓", {0, 2}: " ",
+	})
+	minY, minX, maxY, maxX, _ := m.selectionBounds()
+	if minY != 0 || minX != 0 || maxY != 2 || maxX != 1 {
+		t.Errorf("selection = %d,%d-%d,%d, want 0,0-2,1", minY, minX, maxY, maxX)
+	}
+	if !strings.Contains(m.alertMessage, "A") {
+		t.Errorf("rotation should report glyphs it can't rotate, got %q", m.alertMessage)
+	}
+}
+
+func TestRotateSelectionMustFit(t *testing.T) {
+	m := newTransformModel()
+	m.selection = selectionState{active: true, startY: 1, startX: -1, endY: 4, endX: 6}
+	before := len(m.history)
+	m.transformSelection(rotateClockwise)
+	if len(m.history) != before || m.alertMessage == "" {
+		t.Error("a rotation that doesn't fit should alert and change nothing")
+	}
+}
+
+func TestRotateCanvas(t *testing.T) {
+	m := newTransformModel()
+	m.addLayer()
+	m.canvas.Set(3, 5, "▲", "white", "transparent")
+	m.saveToHistory()
+
+	m.transformSelection(rotateCounterclockwise)
+	checkCells(t, m, 4, 6, map[[2]int]string{{0, 3}: "◀"})
+	if bottom := m.layerCanvas(0); bottom.Get(5, 0).char != "└" || bottom.Get(4, 0).char != "↑" {
+		t.Error("every layer should be rotated")
+	}
+	if m.fixedWidth != 4 || m.fixedHeight != 6 {
+		t.Errorf("fixed size = %dx%d, want 4x6", m.fixedWidth, m.fixedHeight)
+	}
+
+	m.undo()
+	checkCells(t, m, 6, 4, map[[2]int]string{{3, 5}: "▲"})
+}
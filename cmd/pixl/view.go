@@ -70,6 +70,11 @@ func (m *model) View() string {
 			popupLines, popup2Lines = mergePopupBorders(popupLines, popup2Lines, popup2StartY-popupStartY)
 			popup2X = popupX + toolPickerWidth - 1
 		}
+	} else if m.showLayerPicker {
+		popup = m.renderLayerPanel()
+		popupLines = strings.Split(popup, "\n")
+		popupStartY = 0
+		popupX = m.layerPanelX()
 	} else if m.showGlyphPicker {
 		popup = m.renderCategoryPicker()
 		popupLines = strings.Split(popup, "\n")
@@ -93,11 +98,45 @@ func (m *model) View() string {
 		popup2X = popupX + catPickerWidth - 1
 	}
 
-	// Modal dialog overlay (confirm-clear or command palette)
+	// Modal dialog overlay (confirm-clear, command palette, history panel,
+	// path prompt, canvas size prompt, recovery prompt or crop prompt)
 	var dialogLines []string
 	var dialogX, dialogY int
-	if m.showPalette {
-		dialog := m.renderPalette()
+	if m.showPalette || m.showHistory || m.showPathPrompt || m.showSizePrompt {
+		var dialog string
+		switch {
+		case m.showPalette:
+			dialog = m.renderPalette()
+		case m.showHistory:
+			dialog = m.renderHistoryPanel()
+		case m.showSizePrompt:
+			dialog = m.renderSizePrompt()
+		default:
+			dialog = m.renderPathPrompt()
+		}
+		dialogLines = strings.Split(dialog, "\n")
+		dialogWidth := lipgloss.Width(dialogLines[0])
+		dialogX = (m.width - dialogWidth) / 2
+		dialogY = (screenRows - len(dialogLines)) / 2
+		if dialogX < 0 {
+			dialogX = 0
+		}
+		if dialogY < 0 {
+			dialogY = 0
+		}
+	} else if m.confirmQuit {
+		dialogStyle := lipgloss.NewStyle().
+			Border(lipgloss.RoundedBorder()).
+			BorderForeground(themeColor(m.config.Theme.MenuBorder)).
+			Padding(0, 1)
+		accentStyle := lipgloss.NewStyle().Foreground(themeColor(m.config.Theme.ToolbarHighlightBg))
+		name := "canvas"
+		if m.filePath != "" {
+			name = shortPath(m.filePath)
+		}
+		dialog := dialogStyle.Render("Save changes to " + name + "? " +
+			accentStyle.Render("s") + " save  " + accentStyle.Render("d") + " discard  " +
+			accentStyle.Render("esc") + " cancel")
 		dialogLines = strings.Split(dialog, "\n")
 		dialogWidth := lipgloss.Width(dialogLines[0])
 		dialogX = (m.width - dialogWidth) / 2
@@ -142,19 +181,41 @@ func (m *model) View() string {
 		if dialogY < 0 {
 			dialogY = 0
 		}
+	} else if m.pendingRecovery != "" || m.confirmCrop {
+		dialog := m.renderCropConfirm()
+		if m.pendingRecovery != "" {
+			dialog = m.renderRecoveryPrompt()
+		}
+		dialogLines = strings.Split(dialog, "\n")
+		dialogWidth := lipgloss.Width(dialogLines[0])
+		dialogX = (m.width - dialogWidth) / 2
+		dialogY = (screenRows - len(dialogLines)) / 2
+		if dialogX < 0 {
+			dialogX = 0
+		}
+		if dialogY < 0 {
+			dialogY = 0
+		}
 	}
 
 	// Render screen rows
+	m.clampScroll()
 	offY, offX := m.canvasOffset()
+	viewRows, viewCols := m.viewportSize()
 	borderStyle := lipgloss.NewStyle().Foreground(themeColor(m.config.Theme.CanvasBorder))
 	labelStyle := lipgloss.NewStyle().Foreground(themeColor(m.config.Theme.CanvasBorder))
 	label := fmt.Sprintf("%dx%d", m.canvas.width, m.canvas.height)
+	if viewRows < m.canvas.height || viewCols < m.canvas.width {
+		label += fmt.Sprintf(" @ %d,%d", m.scrollX, m.scrollY)
+	}
+	vThumbStart, vThumbLen := scrollThumb(m.scrollY, viewRows, m.canvas.height)
+	hThumbStart, hThumbLen := scrollThumb(m.scrollX, viewCols, m.canvas.width)
 
 	labelRow := offY
 	topBorderRow := offY + 1
 	canvasStartRow := offY + 2
-	canvasEndRow := offY + 1 + m.canvas.height
-	bottomBorderRow := offY + 2 + m.canvas.height
+	canvasEndRow := offY + 1 + viewRows
+	bottomBorderRow := offY + 2 + viewRows
 
 	for i := 0; i < screenRows; i++ {
 		var lineBuilder strings.Builder
@@ -219,10 +280,10 @@ func (m *model) View() string {
 					if col == offX {
 						lineBuilder.WriteString(borderStyle.Render("┌"))
 						continue
-					} else if col == offX+m.canvas.width+1 {
+					} else if col == offX+viewCols+1 {
 						lineBuilder.WriteString(borderStyle.Render("┐"))
 						continue
-					} else if col > offX && col < offX+m.canvas.width+1 {
+					} else if col > offX && col < offX+viewCols+1 {
 						lineBuilder.WriteString(borderStyle.Render("─"))
 						continue
 					}
@@ -234,10 +295,15 @@ func (m *model) View() string {
 					if col == offX {
 						lineBuilder.WriteString(borderStyle.Render("└"))
 						continue
-					} else if col == offX+m.canvas.width+1 {
+					} else if col == offX+viewCols+1 {
 						lineBuilder.WriteString(borderStyle.Render("┘"))
 						continue
-					} else if col > offX && col < offX+m.canvas.width+1 {
+					} else if col > offX && col < offX+viewCols+1 {
+						// Horizontal scrollbar thumb
+						if t := col - offX - 1; viewCols < m.canvas.width && t >= hThumbStart && t < hThumbStart+hThumbLen {
+							lineBuilder.WriteString(borderStyle.Render("━"))
+							continue
+						}
 						lineBuilder.WriteString(borderStyle.Render("─"))
 						continue
 					}
@@ -246,16 +312,21 @@ func (m *model) View() string {
 				}
 
 				if i >= canvasStartRow && i <= canvasEndRow {
+					canvasRow := i - canvasStartRow
 					if col == offX {
 						lineBuilder.WriteString(borderStyle.Render("│"))
 						continue
-					} else if col == offX+m.canvas.width+1 {
+					} else if col == offX+viewCols+1 {
+						// Vertical scrollbar thumb
+						if viewRows < m.canvas.height && canvasRow >= vThumbStart && canvasRow < vThumbStart+vThumbLen {
+							lineBuilder.WriteString(borderStyle.Render("┃"))
+							continue
+						}
 						lineBuilder.WriteString(borderStyle.Render("│"))
 						continue
-					} else if col > offX && col < offX+m.canvas.width+1 {
-						canvasRow := i - canvasStartRow
+					} else if col > offX && col < offX+viewCols+1 {
 						canvasCol := col - offX - 1
-						lineBuilder.WriteString(m.renderCellAt(canvasRow, canvasCol))
+						lineBuilder.WriteString(m.renderCellAt(canvasRow+m.scrollY, canvasCol+m.scrollX))
 						continue
 					}
 					lineBuilder.WriteString(" ")
@@ -280,13 +351,21 @@ func (m *model) View() string {
 }
 
 func (m *model) renderCellAt(row, col int) string {
-	if m.showPreview {
+	if m.toolGrid() != cellGrid {
+		if rendered, ok := m.renderGridCell(row, col); ok {
+			return rendered
+		}
+	} else if m.showPreview {
 		if rendered, ok := m.tool().RenderPreview(m, row, col); ok {
 			return rendered
 		}
 	}
 
-	if m.selection.active {
+	if m.selection.active && m.selection.mask != nil {
+		if m.onSelectionEdge(row, col) {
+			return m.selectionStyle.Render("·")
+		}
+	} else if m.selection.active {
 		minY, minX, maxY, maxX := normalizeRect(m.selection.startY, m.selection.startX, m.selection.endY, m.selection.endX)
 		hasWidth := minX != maxX
 		hasHeight := minY != maxY
@@ -330,43 +409,43 @@ func (m *model) renderCellAt(row, col int) string {
 		return m.styledChar()
 	}
 
-	cell := m.canvas.Get(row, col)
+	cell := m.compositeCell(row, col)
 	if cell == nil {
 		return " "
 	}
 	if cell.foregroundColor == "transparent" {
 		return " "
 	}
-	style := colorStyleByName(cell.foregroundColor)
-	if cell.backgroundColor != "transparent" {
-		style = style.Background(colorStyleByName(cell.backgroundColor).GetForeground())
-	}
-	return style.Render(cell.char)
+	return cellStyle(cell.foregroundColor, cell.backgroundColor, cell.attrs).Render(cell.char)
 }
 
 func (m *model) styledChar() string {
-	style := colorStyleByName(m.foregroundColor)
-	if m.backgroundColor != "transparent" {
-		style = style.Background(colorStyleByName(m.backgroundColor).GetForeground())
-	}
-	return style.Render(m.selectedChar)
+	return m.brushStyle().Render(m.selectedChar)
+}
+
+// brushStyle is the style of glyphs drawn with the current colors and
+// attributes.
+func (m *model) brushStyle() lipgloss.Style {
+	return cellStyle(m.foregroundColor, m.backgroundColor, m.textAttrs)
+}
+
+// brushCell returns the cell the drawing tools place for glyph ch.
+func (m *model) brushCell(ch string) Cell {
+	return Cell{char: ch, foregroundColor: m.foregroundColor, backgroundColor: m.backgroundColor, attrs: m.textAttrs}
 }
 
 func (m *model) renderCanvas() string {
 	var b strings.Builder
+	canvas := m.flattenedCanvas()
 
-	for row := 0; row < m.canvas.height; row++ {
-		for col := 0; col < m.canvas.width; col++ {
-			cell := m.canvas.Get(row, col)
+	for row := 0; row < canvas.height; row++ {
+		for col := 0; col < canvas.width; col++ {
+			cell := canvas.Get(row, col)
 			if cell != nil {
 				if cell.foregroundColor == "transparent" {
 					b.WriteString(" ")
 				} else {
-					style := colorStyleByName(cell.foregroundColor)
-					if cell.backgroundColor != "transparent" {
-						style = style.Background(colorStyleByName(cell.backgroundColor).GetForeground())
-					}
-					b.WriteString(style.Render(cell.char))
+					b.WriteString(cellStyle(cell.foregroundColor, cell.backgroundColor, cell.attrs).Render(cell.char))
 				}
 			}
 		}
@@ -377,52 +456,88 @@ func (m *model) renderCanvas() string {
 }
 
 func (m *model) renderCanvasPlain() string {
-	var b strings.Builder
+	return cellsText(m.flattenedCanvas().cells, true)
+}
 
-	for row := 0; row < m.canvas.height; row++ {
-		for col := 0; col < m.canvas.width; col++ {
-			cell := m.canvas.Get(row, col)
-			if cell == nil || cell.foregroundColor == "transparent" {
-				b.WriteString(" ")
-				continue
-			}
+func (m *model) hasFixedSize() bool {
+	return m.fixedWidth > 0 && m.fixedHeight > 0
+}
+
+// viewportSize returns how many canvas rows and columns fit on screen. In
+// fixed-size mode a canvas larger than the window is shown through a
+// scrollable viewport inside the border.
+func (m *model) viewportSize() (rows, cols int) {
+	rows, cols = m.canvas.height, m.canvas.width
+	if !m.hasFixedSize() || m.width <= 0 || m.height <= 0 {
+		return
+	}
+	// Label row plus top and bottom border
+	if avail := m.height - controlBarHeight - 3; avail < rows {
+		rows = max(avail, 1)
+	}
+	// Left and right border
+	if avail := m.width - 2; avail < cols {
+		cols = max(avail, 1)
+	}
+	return
+}
 
-			fg := colorToANSI(cell.foregroundColor)
-			bg := colorToANSIBg(cell.backgroundColor)
+// clampScroll keeps the scroll offsets inside the canvas.
+func (m *model) clampScroll() {
+	rows, cols := m.viewportSize()
+	m.scrollY = max(0, min(m.scrollY, m.canvas.height-rows))
+	m.scrollX = max(0, min(m.scrollX, m.canvas.width-cols))
+}
 
-			if fg == "" && bg == "" {
-				b.WriteString(cell.char)
-				continue
-			}
+// scrollBy moves the viewport by the given number of rows and columns.
+func (m *model) scrollBy(dy, dx int) {
+	m.scrollY += dy
+	m.scrollX += dx
+	m.clampScroll()
+}
 
-			b.WriteString("\x1b[")
-			if fg != "" && bg != "" {
-				b.WriteString(fg + ";" + bg)
-			} else if fg != "" {
-				b.WriteString(fg)
-			} else {
-				b.WriteString(bg)
-			}
-			b.WriteString("m")
-			b.WriteString(cell.char)
-			b.WriteString("\x1b[0m")
-		}
-		b.WriteString("\n")
+// scrollToCell scrolls just enough to bring a canvas cell into view.
+func (m *model) scrollToCell(row, col int) {
+	rows, cols := m.viewportSize()
+	if row < m.scrollY {
+		m.scrollY = row
+	} else if row >= m.scrollY+rows {
+		m.scrollY = row - rows + 1
+	}
+	if col < m.scrollX {
+		m.scrollX = col
+	} else if col >= m.scrollX+cols {
+		m.scrollX = col - cols + 1
 	}
+	m.clampScroll()
+}
 
-	return b.String()
+// viewportContains reports whether a canvas cell is currently on screen.
+func (m *model) viewportContains(row, col int) bool {
+	rows, cols := m.viewportSize()
+	return row >= m.scrollY && row < m.scrollY+rows && row < m.canvas.height &&
+		col >= m.scrollX && col < m.scrollX+cols && col < m.canvas.width &&
+		row >= 0 && col >= 0
 }
 
-func (m *model) hasFixedSize() bool {
-	return m.fixedWidth > 0 && m.fixedHeight > 0
+// scrollThumb returns the start and length of a scrollbar thumb for a track
+// of view cells showing part of total.
+func scrollThumb(scroll, view, total int) (start, length int) {
+	if total <= view {
+		return 0, view
+	}
+	length = max(1, view*view/total)
+	start = scroll * (view - length) / (total - view)
+	return
 }
 
 func (m *model) canvasOffset() (offsetY, offsetX int) {
 	if !m.hasFixedSize() {
 		return 0, 0
 	}
-	offsetY = (m.height - controlBarHeight - m.canvas.height - 3) / 2
-	offsetX = (m.width - m.canvas.width - 2) / 2
+	rows, cols := m.viewportSize()
+	offsetY = (m.height - controlBarHeight - rows - 3) / 2
+	offsetX = (m.width - cols - 2) / 2
 	if offsetY < 0 {
 		offsetY = 0
 	}
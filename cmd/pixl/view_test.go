@@ -1,8 +1,11 @@
 package main
 
 import (
+	"fmt"
 	"strings"
 	"testing"
+
+	"github.com/charmbracelet/lipgloss"
 )
 
 func TestHasFixedSize(t *testing.T) {
@@ -349,3 +352,111 @@ func TestCanvasOffsetClamps(t *testing.T) {
 		t.Errorf("offset should not be negative: (%d,%d)", offY, offX)
 	}
 }
+
+func TestRenderCanvasPlainExtendedColors(t *testing.T) {
+	m := &model{canvas: NewCanvas(2, 1)}
+	m.canvas.Set(0, 0, "X", "196", "#102030")
+
+	got := m.renderCanvasPlain()
+	want := "\x1b[38;5;196;48;2;16;32;48mX\x1b[0m \n"
+	if got != want {
+		t.Errorf("plain = %q, want %q", got, want)
+	}
+}
+
+func TestRenderCellAtExtendedColor(t *testing.T) {
+	m := &model{canvas: NewCanvas(2, 1)}
+	m.canvas.Set(0, 0, "X", "#ff0000", "transparent")
+
+	if got := m.renderCellAt(0, 0); !strings.Contains(got, "X") {
+		t.Errorf("renderCellAt = %q, want X", got)
+	}
+	if fg := colorStyleByName("#ff0000").GetForeground(); fg != lipgloss.Color("#ff0000") {
+		t.Errorf("colorStyleByName(#ff0000) foreground = %v", fg)
+	}
+}
+
+func TestRenderCanvasPlainAttributes(t *testing.T) {
+	m := &model{canvas: NewCanvas(2, 1)}
+	m.canvas.SetCell(0, 0, Cell{char: "X", foregroundColor: "red", backgroundColor: "transparent", attrs: attrBold | attrItalic})
+	m.canvas.SetCell(0, 1, Cell{char: "Y", foregroundColor: "white", backgroundColor: "transparent", attrs: attrUnderline})
+
+	want := "\x1b[31;1;3mX\x1b[0m\x1b[4mY\x1b[0m\n"
+	if got := m.renderCanvasPlain(); got != want {
+		t.Errorf("renderCanvasPlain() = %q, want %q", got, want)
+	}
+}
+
+func newScrollModel() *model {
+	m := &model{
+		canvas:       NewCanvas(300, 200),
+		fixedWidth:   300,
+		fixedHeight:  200,
+		width:        80,
+		height:       24,
+		ready:        true,
+		selectedChar: "●",
+		selectedTool: "Point",
+		drawingTool:  "Point",
+	}
+	m.config.Theme = defaultTheme()
+	return m
+}
+
+func TestViewportSize(t *testing.T) {
+	m := newScrollModel()
+	rows, cols := m.viewportSize()
+	// 24 rows minus toolbar, label and two borders; 80 columns minus two borders
+	if rows != 24-controlBarHeight-3 || cols != 78 {
+		t.Errorf("viewportSize() = %d,%d, want %d,78", rows, cols, 24-controlBarHeight-3)
+	}
+
+	small := &model{canvas: NewCanvas(10, 5), fixedWidth: 10, fixedHeight: 5, width: 80, height: 24}
+	if rows, cols := small.viewportSize(); rows != 5 || cols != 10 {
+		t.Errorf("canvas that fits should not scroll, viewport = %d,%d", rows, cols)
+	}
+}
+
+func TestScrollClampsToCanvas(t *testing.T) {
+	m := newScrollModel()
+	m.scrollBy(-5, -5)
+	if m.scrollX != 0 || m.scrollY != 0 {
+		t.Errorf("scroll = %d,%d, want 0,0", m.scrollX, m.scrollY)
+	}
+	m.scrollBy(1000, 1000)
+	rows, cols := m.viewportSize()
+	if m.scrollY != 200-rows || m.scrollX != 300-cols {
+		t.Errorf("scroll = %d,%d, want %d,%d", m.scrollX, m.scrollY, 300-cols, 200-rows)
+	}
+}
+
+func TestScrollToCell(t *testing.T) {
+	m := newScrollModel()
+	rows, cols := m.viewportSize()
+	m.scrollToCell(150, 250)
+	if !m.viewportContains(150, 250) {
+		t.Errorf("cell not visible after scrollToCell, scroll = %d,%d", m.scrollX, m.scrollY)
+	}
+	if m.scrollY != 150-rows+1 || m.scrollX != 250-cols+1 {
+		t.Errorf("scroll = %d,%d, want the minimum move", m.scrollX, m.scrollY)
+	}
+}
+
+func TestViewRendersScrolledContent(t *testing.T) {
+	m := newScrollModel()
+	m.canvas.Set(100, 200, "Q", "white", "transparent")
+	if strings.Contains(m.View(), "Q") {
+		t.Fatal("off-screen cell should not be rendered")
+	}
+	m.scrollToCell(100, 200)
+	view := m.View()
+	if !strings.Contains(view, "Q") {
+		t.Error("scrolled-to cell should be rendered")
+	}
+	if !strings.Contains(view, "┃") || !strings.Contains(view, "━") {
+		t.Error("scrollbars should be drawn in the border")
+	}
+	if !strings.Contains(view, fmt.Sprintf("300x200 @ %d,%d", m.scrollX, m.scrollY)) {
+		t.Error("label should show the scroll position")
+	}
+}